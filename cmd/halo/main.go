@@ -9,6 +9,7 @@ import (
 	"github.com/everFinance/goether"
 	"github.com/everVision/everpay-kits/sdk"
 	"github.com/permadao/permaswap/halo"
+	"github.com/permadao/permaswap/halo/hvm"
 
 	"github.com/urfave/cli/v2"
 )
@@ -22,6 +23,12 @@ func main() {
 			&cli.StringFlag{Name: "pay", Value: "https://api-dev.everpay.io", Usage: "pay url", EnvVars: []string{"PAY"}},
 			&cli.StringFlag{Name: "ecc_private", Value: "", Usage: "ecc custodian private", EnvVars: []string{"ECC_PRIVATE"}},
 			&cli.StringFlag{Name: "genesis_tx", Value: "", Usage: "genesis tx everhash", EnvVars: []string{"GENESIS_TX"}},
+			&cli.BoolFlag{Name: "from_checkpoint", Aliases: []string{"from-checkpoint"}, Value: false, Usage: "fail to run if state can not be restored from the latest checkpoint in db", EnvVars: []string{"FROM_CHECKPOINT"}},
+			&cli.StringSliceFlag{Name: "checkpoint_signers", Usage: "trusted signers of checkpoint, default the node itself", EnvVars: []string{"CHECKPOINT_SIGNERS"}},
+			&cli.StringFlag{Name: "dry_run_token", Value: "", Usage: "token required by proposal dry run api, disabled if empty", EnvVars: []string{"DRY_RUN_TOKEN"}},
+			&cli.BoolFlag{Name: "state_logs", Value: false, Usage: "save state diff and proposal executions of every tx", EnvVars: []string{"STATE_LOGS"}},
+			&cli.Int64Flag{Name: "proposal_budget_height", Value: 0, Usage: "count of executed txs from which proposal executions are metered by budget", EnvVars: []string{"PROPOSAL_BUDGET_HEIGHT"}},
+			&cli.BoolFlag{Name: "check_consistency", Value: false, Usage: "replay txs in db from genesis and compare with the state rebuilt when start", EnvVars: []string{"CHECK_CONSISTENCY"}},
 		},
 		Action: run,
 	}
@@ -45,7 +52,12 @@ func run(c *cli.Context) error {
 		panic(err)
 	}
 
+	hvm.ProposalBudgetHeight = c.Int64("proposal_budget_height")
 	h := halo.New(c.String("genesis_tx"), c.String("mysql"), everSDK)
+	h.SetCheckpoint(c.Bool("from_checkpoint"), c.StringSlice("checkpoint_signers"))
+	h.SetCheckConsistency(c.Bool("check_consistency"))
+	h.SetDryRunToken(c.String("dry_run_token"))
+	h.SetStateLogs(c.Bool("state_logs"))
 	h.Run(c.String("port"))
 
 	<-signals
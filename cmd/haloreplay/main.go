@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/permadao/permaswap/halo"
+	"github.com/permadao/permaswap/halo/hvm"
+	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/schema"
+	"github.com/permadao/permaswap/halo/token"
+
+	"github.com/urfave/cli/v2"
+)
+
+// halo txs are loaded from db in batches
+const dbBatch = 1000
+
+type replayState struct {
+	hvmSchema.State
+	Token *token.Token `json:"token"`
+}
+
+func main() {
+	app := &cli.App{
+		Name:  "haloreplay",
+		Usage: "replay halo txs offline and audit state hashes",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "genesis", Aliases: []string{"g"}, Usage: "json file of genesis tx data"},
+			&cli.Int64Flag{Name: "genesis_timestamp", Required: true, Usage: "timestamp of genesis everPay tx in milliseconds, stake time of genesis stakes"},
+			&cli.StringFlag{Name: "txs", Aliases: []string{"t"}, Usage: "json-lines file of halo txs in order"},
+			&cli.StringFlag{Name: "mysql", Usage: "mysql dsn of halo node, txs are read from db if txs is not set"},
+			&cli.Int64Flag{Name: "proposal_budget_height", Value: 0, Usage: "count of executed txs from which proposal executions are metered by budget, same as halo node"},
+			&cli.StringFlag{Name: "state_out", Value: "state.json", Usage: "file of final state"},
+			&cli.StringFlag{Name: "results_out", Value: "results.jsonl", Usage: "json-lines file of validity and state hash of every tx"},
+		},
+		Action: run,
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func loadJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func loadTxs(path string) ([]*schema.HaloTransaction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	txs := []*schema.HaloTransaction{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		tx := &schema.HaloTransaction{}
+		if err := json.Unmarshal(scanner.Bytes(), tx); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		txs = append(txs, tx)
+	}
+	return txs, scanner.Err()
+}
+
+func loadDBTxs(dsn string) ([]*schema.HaloTransaction, error) {
+	wdb := halo.NewWDB(dsn)
+	txs := []*schema.HaloTransaction{}
+	cursor := int64(0)
+	for {
+		batch, err := wdb.GetHaloTxs(cursor, dbBatch)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			return txs, nil
+		}
+		txs = append(txs, batch...)
+		cursor = batch[len(batch)-1].RawID
+	}
+}
+
+func run(c *cli.Context) error {
+	if c.String("genesis") == "" || c.Int64("genesis_timestamp") <= 0 || (c.String("txs") == "" && c.String("mysql") == "") {
+		return schema.ErrMissParams
+	}
+
+	hvm.ProposalBudgetHeight = c.Int64("proposal_budget_height")
+
+	genesis := schema.GenesisTxData{}
+	if err := loadJSON(c.String("genesis"), &genesis); err != nil {
+		return err
+	}
+	state, err := halo.GenesisState(genesis, c.Int64("genesis_timestamp"))
+	if err != nil {
+		return err
+	}
+
+	var txs []*schema.HaloTransaction
+	if c.String("txs") != "" {
+		txs, err = loadTxs(c.String("txs"))
+	} else {
+		txs, err = loadDBTxs(c.String("mysql"))
+	}
+	if err != nil {
+		return err
+	}
+
+	vm, results := halo.Replay(state, txs)
+
+	// output
+	by, err := json.MarshalIndent(replayState{State: vm.State, Token: vm.Token}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(c.String("state_out"), by, 0644); err != nil {
+		return err
+	}
+
+	file, err := os.Create(c.String("results_out"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	invalid, mismatch := 0, 0
+	for _, r := range results {
+		if !r.Validity {
+			invalid++
+		}
+		if !r.Match {
+			mismatch++
+			fmt.Println("state hash mismatch:", r.EverHash, "stateHash:", r.StateHash, "expected:", r.ExpectedStateHash)
+		}
+		by, _ := json.Marshal(r)
+		file.Write(append(by, '\n'))
+	}
+
+	fmt.Println("Txs:", len(txs), "Executed:", len(results), "Invalid:", invalid, "Mismatch:", mismatch)
+	fmt.Println("Final state hash:", vm.StateHash)
+	if mismatch > 0 {
+		return schema.ErrStateHashMismatch
+	}
+	return nil
+}
@@ -5,7 +5,9 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/everFinance/goar"
 	"github.com/everFinance/goether"
@@ -20,12 +22,24 @@ func main() {
 		Flags: []cli.Flag{
 			&cli.StringFlag{Name: "pay", Value: "https://api-dev.everpay.io", Usage: "pay url", EnvVars: []string{"PAY"}},
 			&cli.StringFlag{Name: "perma_ws", Value: "wss://swap-dev.everpay.io/wslp", Usage: "perma router ws url", EnvVars: []string{"PERMA_WS"}},
+			&cli.StringFlag{Name: "encoding", Value: "json", Usage: "encoding of msgs from routers: json or cbor for compact msgs", EnvVars: []string{"ENCODING"}},
 			&cli.StringFlag{Name: "perma_http", Value: "https://swap-dev.everpay.io", Usage: "perma router http url", EnvVars: []string{"PERMA_HTTP"}},
+			&cli.StringFlag{Name: "halo", Value: "", Usage: "halo url, discover routers from halo instead of perma_ws and perma_http", EnvVars: []string{"HALO"}},
+			&cli.StringSliceFlag{Name: "perma_pools", Usage: "pools registered with perma router, all pools if empty", EnvVars: []string{"PERMA_POOLS"}},
+			&cli.StringSliceFlag{Name: "extra_router", Usage: "another router lps are registered with: ws_url|http_url[|pool_id;pool_id]", EnvVars: []string{"EXTRA_ROUTERS"}},
 			&cli.StringFlag{Name: "lp_config", Value: "./lp/test.json", Usage: "perma lp config", EnvVars: []string{"LP_CONFIG"}},
 			&cli.Int64Flag{Name: "eth_chain_id", Value: 5, Usage: "eth chainId", EnvVars: []string{"ETH_CHAIN_ID"}},
 			&cli.StringFlag{Name: "ecc_private", Value: "", Usage: "ecc custodian private", EnvVars: []string{"ECC_PRIVATE"}},
 			&cli.StringFlag{Name: "ar_wallet", Usage: "arweave wallet json file path", EnvVars: []string{"AR_WALLET"}},
 			&cli.BoolFlag{Name: "lp_api", Value: false, Usage: "enable lp api", EnvVars: []string{"LP_API"}},
+			&cli.StringFlag{Name: "lp_api_addr", Value: ":8081", Usage: "lp api listen address", EnvVars: []string{"LP_API_ADDR"}},
+			&cli.StringFlag{Name: "lp_api_token", Value: "", Usage: "lp api bearer token, requests signed by lp account are always accepted", EnvVars: []string{"LP_API_TOKEN"}},
+			&cli.StringFlag{Name: "strategy", Value: "none", Usage: "lp rebalance strategy: none, fixed_width or ladder", EnvVars: []string{"STRATEGY"}},
+			&cli.IntFlag{Name: "ladder_rungs", Value: 5, Usage: "rungs of ladder strategy", EnvVars: []string{"LADDER_RUNGS"}},
+			&cli.StringFlag{Name: "ladder_step", Value: "1.005", Usage: "sqrt price factor of a rung in ladder strategy", EnvVars: []string{"LADDER_STEP"}},
+			&cli.StringFlag{Name: "price_feed", Value: "", Usage: "price feed file path or http url, json of token tag to usd price", EnvVars: []string{"PRICE_FEED"}},
+			&cli.Float64Flag{Name: "price_threshold", Value: 0.05, Usage: "remove lps when price diverges from feed beyond threshold", EnvVars: []string{"PRICE_THRESHOLD"}},
+			&cli.DurationFlag{Name: "price_interval", Value: 30 * time.Second, Usage: "interval of price feed", EnvVars: []string{"PRICE_INTERVAL"}},
 		},
 		Action: run,
 	}
@@ -58,9 +72,46 @@ func run(c *cli.Context) (err error) {
 
 	fmt.Println("LP address:", everSDK.AccId)
 
-	l := lp.New(c.Int64("eth_chain_id"), c.Bool("lp_api"),
-		lp.NewRSDK(c.String("perma_ws"), c.String("perma_http"), everSDK),
-	)
+	strategy, err := lp.NewStrategy(c.String("strategy"), c.Int("ladder_rungs"), c.String("ladder_step"))
+	if err != nil {
+		panic(err)
+	}
+
+	if err := lp.SetEncoding(c.String("encoding")); err != nil {
+		panic(err)
+	}
+	var rsdk *lp.RSDK
+	if c.String("halo") != "" {
+		endpoints, err := lp.DiscoverRouters(c.String("halo"))
+		if err != nil {
+			panic(err)
+		}
+		for i, e := range endpoints {
+			fmt.Println("Router", i, e.Name, e.Address, e.HttpURL, "swapFeeRatio:", e.SwapFeeRatio, "lpMinStake:", e.LpMinStake)
+		}
+		rsdk = lp.NewRSDKWithEndpoints(endpoints, everSDK)
+	} else {
+		rsdk = lp.NewRSDK(c.String("perma_ws"), c.String("perma_http"), everSDK)
+	}
+
+	l := lp.New(c.Int64("eth_chain_id"), c.Bool("lp_api"), rsdk)
+	l.SetPools(c.StringSlice("perma_pools"))
+	for _, r := range c.StringSlice("extra_router") {
+		parts := strings.Split(r, "|")
+		if len(parts) < 2 {
+			panic("invalid extra router: " + r)
+		}
+		pools := []string{}
+		if len(parts) > 2 && parts[2] != "" {
+			pools = strings.Split(parts[2], ";")
+		}
+		l.AddRouter(lp.NewRSDK(parts[0], parts[1], everSDK), pools)
+	}
+	l.SetAPI(c.String("lp_api_addr"), c.String("lp_api_token"))
+	l.SetStrategy(strategy)
+	if c.String("price_feed") != "" {
+		l.SetPriceGuard(lp.NewPriceFeed(c.String("price_feed")), c.Float64("price_threshold"), c.Duration("price_interval"))
+	}
 	l.Run(c.String("lp_config"))
 
 	<-signals
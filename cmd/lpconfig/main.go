@@ -14,6 +14,7 @@ import (
 	"github.com/everVision/everpay-kits/sdk"
 	"github.com/permadao/permaswap/core"
 	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/permadao/permaswap/lp"
 	routerSchema "github.com/permadao/permaswap/router/schema"
 
 	"github.com/urfave/cli/v2"
@@ -31,6 +32,8 @@ func main() {
 		Flags: []cli.Flag{
 			&cli.BoolFlag{Name: "info", Aliases: []string{"i"}, Value: false, Usage: "swap info"},
 			&cli.StringFlag{Name: "router", Aliases: []string{"r"}, Value: "", Usage: "perma router http url"},
+			&cli.BoolFlag{Name: "discover", Value: false, Usage: "discover routers from halo, the best router is used if router is not set"},
+			&cli.StringFlag{Name: "halo", Value: "", Usage: "halo url for discover, default halo of network"},
 			&cli.StringFlag{Name: "network", Aliases: []string{"n"}, Value: "mainnet", Usage: "nework: testnet or mainnet"},
 			&cli.StringFlag{Name: "pool_id", Aliases: []string{"p"}, Usage: "pool id"},
 			&cli.BoolFlag{Name: "full_range", Aliases: []string{"f"}, Value: false, Usage: "use full range price"},
@@ -44,7 +47,8 @@ func main() {
 
 			&cli.StringFlag{Name: "price_direction", Value: "both", Aliases: []string{"d"}, Usage: "price direction: both or up or down"},
 		},
-		Action: run,
+		Commands: []*cli.Command{planCommand},
+		Action:   run,
 	}
 
 	err := app.Run(os.Args)
@@ -174,17 +178,37 @@ func run(c *cli.Context) error {
 
 	pay := ""
 	perma := ""
+	halo := ""
 	if c.String("network") == "testnet" {
 		pay = "https://api-dev.everpay.io"
 		perma = "https://router-dev.permaswap.network"
+		halo = "https://router-dev.permaswap.network/halo"
 	} else if c.String("network") == "mainnet" {
 		pay = "https://api.everpay.io"
 		perma = "https://router.permaswap.network"
+		halo = "https://router.permaswap.network/halo"
 	} else {
 		fmt.Println("invalid network")
 		return ErrInvalidParam
 	}
 
+	if c.Bool("discover") {
+		if c.String("halo") != "" {
+			halo = c.String("halo")
+		}
+		endpoints, err := lp.DiscoverRouters(halo)
+		if err != nil {
+			return err
+		}
+		fmt.Print("Router List:", "\n\n")
+		for i, e := range endpoints {
+			fmt.Println("Rank:", i, "Name:", e.Name, "Address:", e.Address)
+			fmt.Println("URL:", e.HttpURL, "WS:", e.WsURL, "SwapFeeRatio:", e.SwapFeeRatio, "LpMinStake:", e.LpMinStake)
+			fmt.Println()
+		}
+		perma = endpoints[0].HttpURL
+	}
+
 	if c.String("router") != "" {
 		perma = c.String("router")
 	}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"fmt"
+
+	apd "github.com/cockroachdb/apd/v3"
+	"github.com/everVision/everpay-kits/sdk"
+	"github.com/permadao/permaswap/core"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/urfave/cli/v2"
+)
+
+var planCommand = &cli.Command{
+	Name:  "plan",
+	Usage: "plan lp positions for a budget and simulate the payoff against holding",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "router", Aliases: []string{"r"}, Value: "", Usage: "perma router http url, used to get tokens of pool"},
+		&cli.StringFlag{Name: "network", Aliases: []string{"n"}, Value: "mainnet", Usage: "nework: testnet or mainnet"},
+		&cli.StringFlag{Name: "pool_id", Aliases: []string{"p"}, Usage: "pool id, decimals of tokens are used if set"},
+		&cli.IntFlag{Name: "decimal_x", Value: 0, Usage: "decimals of token x if pool_id is not set"},
+		&cli.IntFlag{Name: "decimal_y", Value: 0, Usage: "decimals of token y if pool_id is not set"},
+
+		&cli.StringFlag{Name: "budget", Aliases: []string{"b"}, Usage: "capital budget in token y"},
+		&cli.StringFlag{Name: "low_price", Aliases: []string{"low"}, Usage: "lowest price"},
+		&cli.StringFlag{Name: "current_price", Aliases: []string{"current"}, Usage: "current price"},
+		&cli.StringFlag{Name: "high_price", Aliases: []string{"high"}, Usage: "highest price"},
+		&cli.IntFlag{Name: "ranges", Value: 1, Usage: "split budget equally into sub-ranges with the same price ratio"},
+
+		&cli.StringFlag{Name: "grid_low", Usage: "lowest price of payoff grid, default half of lowest price"},
+		&cli.StringFlag{Name: "grid_high", Usage: "highest price of payoff grid, default 1.5 times highest price"},
+		&cli.IntFlag{Name: "grid", Value: 10, Usage: "number of prices in payoff grid"},
+	},
+	Action: plan,
+}
+
+// position is a planned lp, prices are sqrt prices and amounts are in the min unit of tokens
+type position struct {
+	LowSqrtPrice     *apd.Decimal
+	CurrentSqrtPrice *apd.Decimal
+	HighSqrtPrice    *apd.Decimal
+	Liquidity        string
+	AmountX          string
+	AmountY          string
+}
+
+// planPosition return the position which value is budget in token y at current price
+func planPosition(low, current, high *apd.Decimal, budget string) (p position, err error) {
+	if low.Cmp(high) != -1 || current.Cmp(low) == -1 || current.Cmp(high) == 1 {
+		return p, ErrInvalidParam
+	}
+	p = position{LowSqrtPrice: low, CurrentSqrtPrice: current, HighSqrtPrice: high}
+
+	ctx := apd.BaseContext.WithPrecision(core.PRECISION)
+	price := new(apd.Decimal)
+	ctx.Mul(price, current, current)
+
+	if current.Cmp(low) == 0 {
+		// only token x in position
+		amountX := new(apd.Decimal)
+		if _, err = ctx.Quo(amountX, mustDecimal(budget), price); err != nil {
+			return
+		}
+		if p.Liquidity, err = core.LiquidityFromAmountX(low, current, high, amountX.Text('f')); err != nil {
+			return
+		}
+	} else {
+		// the value of amount y and amount x is not known before liquidity,
+		// so liquidity of budget is used to get the split and then scaled to budget
+		if p.Liquidity, err = core.LiquidityFromAmountY(low, current, high, budget); err != nil {
+			return
+		}
+		var amountX, amountY string
+		if amountX, amountY, err = core.LiquidityToAmount(p.Liquidity, low, current, high, coreSchema.PriceDirectionBoth); err != nil {
+			return
+		}
+		value, _ := positionValue(amountX, amountY, price)
+		amountY_ := new(apd.Decimal)
+		ctx.Mul(amountY_, mustDecimal(budget), mustDecimal(amountY))
+		ctx.Quo(amountY_, amountY_, value)
+		if p.Liquidity, err = core.LiquidityFromAmountY(low, current, high, amountY_.Text('f')); err != nil {
+			return
+		}
+	}
+
+	p.AmountX, p.AmountY, err = core.LiquidityToAmount(p.Liquidity, low, current, high, coreSchema.PriceDirectionBoth)
+	return
+}
+
+// amounts return amounts of position when price moves to sqrtPrice
+func (p position) amounts(sqrtPrice *apd.Decimal) (amountX, amountY string, err error) {
+	current := sqrtPrice
+	if current.Cmp(p.LowSqrtPrice) == -1 {
+		current = p.LowSqrtPrice
+	}
+	if current.Cmp(p.HighSqrtPrice) == 1 {
+		current = p.HighSqrtPrice
+	}
+	return core.LiquidityToAmount(p.Liquidity, p.LowSqrtPrice, current, p.HighSqrtPrice, coreSchema.PriceDirectionBoth)
+}
+
+// positionValue return value of amounts in token y
+func positionValue(amountX, amountY string, price *apd.Decimal) (*apd.Decimal, error) {
+	ctx := apd.BaseContext.WithPrecision(core.PRECISION)
+	value := new(apd.Decimal)
+	if _, err := ctx.Mul(value, mustDecimal(amountX), price); err != nil {
+		return nil, err
+	}
+	if _, err := ctx.Add(value, value, mustDecimal(amountY)); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// subRanges split range into n sub-ranges with the same sqrt price ratio
+func subRanges(low, high *apd.Decimal, n int) ([][2]*apd.Decimal, error) {
+	if n < 1 {
+		return nil, ErrInvalidParam
+	}
+	ctx := apd.BaseContext.WithPrecision(core.PRECISION)
+	ratio := new(apd.Decimal)
+	if _, err := ctx.Quo(ratio, high, low); err != nil {
+		return nil, err
+	}
+	root := new(apd.Decimal)
+	if _, err := ctx.Quo(root, apd.New(1, 0), apd.New(int64(n), 0)); err != nil {
+		return nil, err
+	}
+	if _, err := ctx.Pow(root, ratio, root); err != nil {
+		return nil, err
+	}
+
+	ranges := [][2]*apd.Decimal{}
+	lower := low
+	for i := 0; i < n; i++ {
+		upper := high
+		if i < n-1 {
+			upper = new(apd.Decimal)
+			ctx.Mul(upper, lower, root)
+			ctx.Reduce(upper, upper)
+		}
+		ranges = append(ranges, [2]*apd.Decimal{lower, upper})
+		lower = upper
+	}
+	return ranges, nil
+}
+
+// planPositions split budget equally into sub-ranges, current price of position is clamped into its range
+func planPositions(low, current, high *apd.Decimal, budget string, n int) ([]position, error) {
+	ranges, err := subRanges(low, high, n)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := apd.BaseContext.WithPrecision(core.PRECISION)
+	subBudget := new(apd.Decimal)
+	if _, err := ctx.Quo(subBudget, mustDecimal(budget), apd.New(int64(n), 0)); err != nil {
+		return nil, err
+	}
+
+	positions := []position{}
+	for _, r := range ranges {
+		c := current
+		if c.Cmp(r[0]) == -1 {
+			c = r[0]
+		}
+		if c.Cmp(r[1]) == 1 {
+			c = r[1]
+		}
+		p, err := planPosition(r[0], c, r[1], subBudget.Text('f'))
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
+	return positions, nil
+}
+
+// payoff return values of positions and holding the initial amounts at price
+func payoff(positions []position, sqrtPrice *apd.Decimal) (lpValue, holdValue *apd.Decimal, err error) {
+	ctx := apd.BaseContext.WithPrecision(core.PRECISION)
+	price := new(apd.Decimal)
+	ctx.Mul(price, sqrtPrice, sqrtPrice)
+
+	lpValue, holdValue = new(apd.Decimal), new(apd.Decimal)
+	for _, p := range positions {
+		amountX, amountY, err := p.amounts(sqrtPrice)
+		if err != nil {
+			return nil, nil, err
+		}
+		v, err := positionValue(amountX, amountY, price)
+		if err != nil {
+			return nil, nil, err
+		}
+		ctx.Add(lpValue, lpValue, v)
+
+		if v, err = positionValue(p.AmountX, p.AmountY, price); err != nil {
+			return nil, nil, err
+		}
+		ctx.Add(holdValue, holdValue, v)
+	}
+	return
+}
+
+func mustDecimal(s string) *apd.Decimal {
+	d, _, err := new(apd.Decimal).SetString(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// gridPrices return n prices evenly spaced from low to high
+func gridPrices(low, high string, n int) ([]string, error) {
+	low_, _, err := new(apd.Decimal).SetString(low)
+	if err != nil {
+		return nil, ErrInvalidParam
+	}
+	high_, _, err := new(apd.Decimal).SetString(high)
+	if err != nil {
+		return nil, ErrInvalidParam
+	}
+	if n < 2 || low_.Sign() != 1 || low_.Cmp(high_) != -1 {
+		return nil, ErrInvalidParam
+	}
+
+	ctx := apd.BaseContext.WithPrecision(core.PRECISION)
+	step := new(apd.Decimal)
+	ctx.Sub(step, high_, low_)
+	ctx.Quo(step, step, apd.New(int64(n-1), 0))
+
+	prices := []string{}
+	for i := 0; i < n; i++ {
+		p := new(apd.Decimal)
+		ctx.Mul(p, step, apd.New(int64(i), 0))
+		ctx.Add(p, p, low_)
+		ctx.Reduce(p, p)
+		prices = append(prices, p.Text('f'))
+	}
+	return prices, nil
+}
+
+func plan(c *cli.Context) error {
+	if c.String("budget") == "" || c.String("low_price") == "" || c.String("current_price") == "" || c.String("high_price") == "" {
+		return ErrMissParam
+	}
+
+	symbolX, symbolY := "X", "Y"
+	decimalX, decimalY := c.Int("decimal_x"), c.Int("decimal_y")
+	if c.String("pool_id") != "" {
+		pay := "https://api.everpay.io"
+		perma := "https://router.permaswap.network"
+		if c.String("network") == "testnet" {
+			pay = "https://api-dev.everpay.io"
+			perma = "https://router-dev.permaswap.network"
+		}
+		if c.String("router") != "" {
+			perma = c.String("router")
+		}
+
+		info, err := getSwapInfo(perma)
+		if err != nil {
+			return err
+		}
+		pool, ok := info.PoolList[c.String("pool_id")]
+		if !ok {
+			fmt.Println("pool not found")
+			return ErrInvalidParam
+		}
+		tokens, err := sdk.NewClient(pay).GetTokens()
+		if err != nil {
+			fmt.Println("failed to get tokens info", "err", err)
+			return err
+		}
+		symbolX, decimalX = tokens[pool.TokenXTag].Symbol, tokens[pool.TokenXTag].Decimals
+		symbolY, decimalY = tokens[pool.TokenYTag].Symbol, tokens[pool.TokenYTag].Decimals
+	}
+
+	low, err := getRevisedSqrtPrice2(c.String("low_price"), decimalX, decimalY)
+	if err != nil {
+		return err
+	}
+	current, err := getRevisedSqrtPrice2(c.String("current_price"), decimalX, decimalY)
+	if err != nil {
+		return err
+	}
+	high, err := getRevisedSqrtPrice2(c.String("high_price"), decimalX, decimalY)
+	if err != nil {
+		return err
+	}
+	budget, err := getRevisedAmount2(c.String("budget"), decimalY, true)
+	if err != nil {
+		return err
+	}
+
+	positions, err := planPositions(low, current, high, budget, c.Int("ranges"))
+	if err != nil {
+		fmt.Println("invalid price range")
+		return err
+	}
+
+	fmt.Print("Positions:", "\n\n")
+	for i, p := range positions {
+		lowPrice, _ := sqrtPriceToRevisedPrice(p.LowSqrtPrice, decimalX, decimalY)
+		highPrice, _ := sqrtPriceToRevisedPrice(p.HighSqrtPrice, decimalX, decimalY)
+		amountX, _ := getRevisedAmount2(p.AmountX, decimalX, false)
+		amountY, _ := getRevisedAmount2(p.AmountY, decimalY, false)
+		fmt.Println("Range:", i, "low:", lowPrice, "high:", highPrice)
+		fmt.Println("Liquidity:", p.Liquidity, "amountX:", roundString(amountX, 8), symbolX, "amountY:", roundString(amountY, 8), symbolY)
+		fmt.Println()
+	}
+
+	gridLow, gridHigh := c.String("grid_low"), c.String("grid_high")
+	if gridLow == "" {
+		gridLow = mulString(c.String("low_price"), "0.5")
+	}
+	if gridHigh == "" {
+		gridHigh = mulString(c.String("high_price"), "1.5")
+	}
+	prices, err := gridPrices(gridLow, gridHigh, c.Int("grid"))
+	if err != nil {
+		fmt.Println("invalid payoff grid")
+		return err
+	}
+
+	fmt.Print("Payoff in ", symbolY, " (fees not included):", "\n\n")
+	fmt.Printf("%-20s %-20s %-20s %s\n", "price", "lp", "hold", "lp vs hold")
+	for _, price := range prices {
+		sqrtPrice, err := getRevisedSqrtPrice2(price, decimalX, decimalY)
+		if err != nil {
+			return err
+		}
+		lpValue, holdValue, err := payoff(positions, sqrtPrice)
+		if err != nil {
+			return err
+		}
+		lp, _ := getRevisedAmount2(lpValue.Text('f'), decimalY, false)
+		hold, _ := getRevisedAmount2(holdValue.Text('f'), decimalY, false)
+		fmt.Printf("%-20s %-20s %-20s %s\n", price, roundString(lp, 6), roundString(hold, 6), percentDiff(lpValue, holdValue))
+	}
+	return nil
+}
+
+// sqrtPriceToRevisedPrice return price in the unit of tokens
+func sqrtPriceToRevisedPrice(sqrtPrice *apd.Decimal, decimalsX, decimalsY int) (string, error) {
+	price, err := core.SqrtPriceToPrice(*sqrtPrice)
+	if err != nil {
+		return "", err
+	}
+	price, err = getRevisedAmount2(price, decimalsY-decimalsX, false)
+	if err != nil {
+		return "", err
+	}
+	return roundString(price, 6), nil
+}
+
+func mulString(a, b string) string {
+	d, _, err := new(apd.Decimal).SetString(a)
+	if err != nil {
+		return a
+	}
+	ctx := apd.BaseContext.WithPrecision(core.PRECISION)
+	ctx.Mul(d, d, mustDecimal(b))
+	ctx.Reduce(d, d)
+	return d.Text('f')
+}
+
+func roundString(s string, places int32) string {
+	d, _, err := new(apd.Decimal).SetString(s)
+	if err != nil {
+		return s
+	}
+	ctx := apd.BaseContext.WithPrecision(core.PRECISION)
+	ctx.Quantize(d, d, -places)
+	ctx.Reduce(d, d)
+	return d.Text('f')
+}
+
+func percentDiff(a, b *apd.Decimal) string {
+	if b.IsZero() {
+		return "-"
+	}
+	ctx := apd.BaseContext.WithPrecision(core.PRECISION)
+	d := new(apd.Decimal)
+	ctx.Sub(d, a, b)
+	ctx.Quo(d, d, b)
+	ctx.Mul(d, d, apd.New(100, 0))
+	return roundString(d.Text('f'), 2) + "%"
+}
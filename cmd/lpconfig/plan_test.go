@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	apd "github.com/cockroachdb/apd/v3"
+	"github.com/permadao/permaswap/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanPosition(t *testing.T) {
+	low, _ := core.SqrtPrice("1000")
+	current, _ := core.SqrtPrice("1500")
+	high, _ := core.SqrtPrice("2000")
+
+	p, err := planPosition(low, current, high, "1000000000")
+	assert.NoError(t, err)
+	price := new(apd.Decimal)
+	apd.BaseContext.WithPrecision(core.PRECISION).Mul(price, current, current)
+	value, err := positionValue(p.AmountX, p.AmountY, price)
+	assert.NoError(t, err)
+	assert.True(t, core.QuotientGreaterThan(value, mustDecimal("1000000000"), "0.9999"))
+	assert.True(t, core.QuotientGreaterThan(mustDecimal("1000000000"), value, "0.9999"))
+
+	// lp is the same as holding at current price and less than holding when price moves
+	lpValue, holdValue, err := payoff([]position{p}, current)
+	assert.NoError(t, err)
+	assert.True(t, core.QuotientGreaterThan(lpValue, holdValue, "0.9999"))
+	moved, _ := core.SqrtPrice("1900")
+	lpValue, holdValue, err = payoff([]position{p}, moved)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, lpValue.Cmp(holdValue))
+
+	// only token x at lowest price
+	p, err = planPosition(low, low, high, "1000000000")
+	assert.NoError(t, err)
+	assert.Equal(t, "0", p.AmountY)
+
+	_, err = planPosition(current, low, high, "1000000000")
+	assert.Equal(t, ErrInvalidParam, err)
+}
+
+func TestPlanPositions(t *testing.T) {
+	low, _ := core.SqrtPrice("1000")
+	current, _ := core.SqrtPrice("1500")
+	high, _ := core.SqrtPrice("2000")
+
+	positions, err := planPositions(low, current, high, "3000000000", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(positions))
+	assert.Equal(t, 0, positions[0].LowSqrtPrice.Cmp(low))
+	assert.Equal(t, 0, positions[2].HighSqrtPrice.Cmp(high))
+	for i := 1; i < 3; i++ {
+		assert.Equal(t, 0, positions[i].LowSqrtPrice.Cmp(positions[i-1].HighSqrtPrice))
+	}
+	// ranges below current price hold token y only, above hold token x only
+	assert.Equal(t, "0", positions[0].AmountX)
+	assert.Equal(t, "0", positions[2].AmountY)
+
+	prices, err := gridPrices("500", "3000", 6)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"500", "1000", "1500", "2000", "2500", "3000"}, prices)
+}
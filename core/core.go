@@ -204,6 +204,21 @@ func (c *Core) Query(msg routerSchema.UserMsgQuery) ([]schema.Path, error) {
 		}
 	}
 
+	return c.query(addr, msg, c.AddressToLpIDs[addr])
+}
+
+// Quote is Query for pricing only, so lp address is allowed.
+// lps of the address are excluded, an account can not swap with itself.
+func (c *Core) Quote(msg routerSchema.UserMsgQuery) ([]schema.Path, error) {
+	_, addr, err := utils.IDCheck(msg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.query(addr, msg, c.AddressToLpIDs[addr])
+}
+
+func (c *Core) query(addr string, msg routerSchema.UserMsgQuery, excludedLpIDs []string) ([]schema.Path, error) {
 	amountIn, ok := new(big.Int).SetString(msg.AmountIn, 10)
 	if !ok {
 		return nil, ERR_INVALID_AMOUNT
@@ -234,7 +249,7 @@ func (c *Core) Query(msg routerSchema.UserMsgQuery) ([]schema.Path, error) {
 	sos := []schema.SwapOutput{}
 	errs := []error{}
 	for i, poolPath := range poolPaths {
-		sos_, amountOut_, err := PoolsSwap(poolPath, msg.TokenIn, msg.TokenOut, amountIn, c.AddressToLpIDs[addr])
+		sos_, amountOut_, err := PoolsSwap(poolPath, msg.TokenIn, msg.TokenOut, amountIn, excludedLpIDs)
 		if err != nil {
 			log.Debug("Failed to swap in one poolPaths", "path_index", i, "len(poolPaths)", len(poolPaths), "pooPath", poolPath,
 				"tokenIn", msg.TokenIn, "tokenOut", msg.TokenOut, "amountIn", amountIn, "err", err)
@@ -249,15 +264,18 @@ func (c *Core) Query(msg routerSchema.UserMsgQuery) ([]schema.Path, error) {
 
 	if len(sos) == 0 {
 		log.Error("Order query: Failed to find a swap path.", "lps in core", len(c.Lps))
-		isInvalidAmount := true
-		for _, e := range errs {
-			if e.Error() != ERR_INVALID_AMOUNT.Error() {
-				isInvalidAmount = false
-				break
+		// amount is invalid or out of range of lps for all pool paths
+		for _, e := range []error{ERR_INVALID_AMOUNT, ERR_OUT_OF_RANGE} {
+			isErr := true
+			for _, err := range errs {
+				if err.Error() != e.Error() {
+					isErr = false
+					break
+				}
+			}
+			if isErr {
+				return nil, e
 			}
-		}
-		if isInvalidAmount {
-			return nil, ERR_INVALID_AMOUNT
 		}
 
 		return nil, ERR_NO_PATH
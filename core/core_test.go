@@ -162,6 +162,60 @@ func TestQuery(t *testing.T) {
 	assert.Equal(t, len(paths), 0)
 }
 
+func TestQuote(t *testing.T) {
+	lpAddress := "0x911F42b0229c15bBB38D648B7Aa7CA480eD977d6"
+	pool, err := NewPool("ethereum-eth-0x0000000000000000000000000000000000000000",
+		"ethereum-usdt-0xd85476c906b5301e8e9eb58d174a6f96b9dfc5ee",
+		"0.003")
+	poolID := pool.ID()
+
+	core := New(map[string]*schema.Pool{poolID: pool}, "", "")
+
+	addMsg := routerSchema.LpMsgAdd{
+		TokenX:           "ethereum-eth-0x0000000000000000000000000000000000000000",
+		TokenY:           "ethereum-usdt-0xd85476c906b5301e8e9eb58d174a6f96b9dfc5ee",
+		FeeRatio:         testStringToDecimal("0.003"),
+		LowSqrtPrice:     testStringToDecimal("0.000044721359549995793928183473374626"),
+		CurrentSqrtPrice: testStringToDecimal("0.000054792195750516611345696978280080"),
+		HighSqrtPrice:    testStringToDecimal("0.000063245553203367586639977870888654"),
+		Liquidity:        "50000000000000000",
+		PriceDirection:   "both",
+	}
+	err = core.AddLiquidity(lpAddress, addMsg)
+	assert.NoError(t, err)
+	lpAddress2 := "0x61EbF673c200646236B2c53465bcA0699455d5FA"
+	err = core.AddLiquidity(lpAddress2, addMsg)
+	assert.NoError(t, err)
+
+	msg := routerSchema.UserMsgQuery{
+		Address:  lpAddress,
+		TokenIn:  "ethereum-usdt-0xd85476c906b5301e8e9eb58d174a6f96b9dfc5ee",
+		TokenOut: "ethereum-eth-0x0000000000000000000000000000000000000000",
+		AmountIn: "1000000",
+	}
+	_, err = core.Query(msg)
+	assert.Equal(t, ERR_INVALID_SWAP_USER, err)
+
+	// quote is allowed for lp address, only swap with other lps and does not change lp
+	lpID2 := core.AddressToLpIDs[lpAddress2][0]
+	currentSqrtPrice := core.Lps[lpID2].CurrentSqrtPrice.String()
+	paths, err := core.Quote(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(paths))
+	for _, path := range paths {
+		assert.Equal(t, lpID2, path.LpID)
+	}
+	assert.Equal(t, currentSqrtPrice, core.Lps[lpID2].CurrentSqrtPrice.String())
+
+	msg.AmountIn = "0"
+	_, err = core.Quote(msg)
+	assert.Equal(t, ERR_INVALID_AMOUNT, err)
+
+	msg.Address = ""
+	_, err = core.Quote(msg)
+	assert.Error(t, err)
+}
+
 func TestQuery2(t *testing.T) {
 	user := "0x911F42b0229c15bBB38D648B7Aa7CA480eD977d6"
 	pool, err := NewPool("ethereum-eth-0x0000000000000000000000000000000000000000",
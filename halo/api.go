@@ -1,6 +1,7 @@
 package halo
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"math/big"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/permadao/permaswap/halo/account"
+	"github.com/permadao/permaswap/halo/hvm"
 	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
 	tokSchema "github.com/permadao/permaswap/halo/token/schema"
 
@@ -36,10 +38,14 @@ func (h *Halo) RegisterRouter(router *gin.Engine, group string) {
 	g.GET("/info", h.info)
 	g.GET("/txs", h.txs)
 	g.GET("/tx/:hash", h.getTx)
+	g.GET("/proposals", h.getProposals)
 	g.GET("/proposal/:id", h.getProposal)
+	g.GET("/proposal/:id/executions", h.getProposalExecutions)
+	g.POST("/proposal/dryrun", h.proposalDryRun)
 	g.GET("/balance/:accid", h.getBalance)
 	g.GET("/token", h.tokenInfo)
 	g.POST("/submit", h.submit)
+	g.GET("/spec", h.getSpec)
 }
 
 func (h *Halo) info(c *gin.Context) {
@@ -106,6 +112,142 @@ func (h *Halo) getProposal(c *gin.Context) {
 			return
 		}
 	}
+
+	// ended proposal
+	ended, err := h.wdb.GetEndedProposal(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, hvmSchema.ErrNoProposalFound.Error())
+		return
+	}
+	p := &hvmSchema.Proposal{}
+	if err := json.Unmarshal([]byte(ended.Data), p); err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !detail {
+		p.ExecutedTxs = map[string]string{}
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// pageQuery parse page and count of query, page starts from 1
+func pageQuery(c *gin.Context) (page, count int, err error) {
+	page, err = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return 0, 0, schema.ErrInvalidPage
+	}
+	count, err = strconv.Atoi(c.DefaultQuery("count", "10"))
+	if err != nil || count < 1 || count > maxPageCount {
+		return 0, 0, schema.ErrInvalidCount
+	}
+	return page, count, nil
+}
+
+func (h *Halo) getProposals(c *gin.Context) {
+	page, count, err := pageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res := &schema.ProposalsRes{}
+	switch c.DefaultQuery("status", schema.ProposalStatusActive) {
+	case schema.ProposalStatusActive:
+		h.stateChan <- struct{}{}
+		stateRes := <-h.stateResChan
+
+		state := hvmSchema.State{}
+		if stateRes != "" {
+			if err := json.Unmarshal([]byte(stateRes), &state); err != nil {
+				log.Error("unmarshal state failed", "err", err)
+				c.JSON(http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		res.Total = int64(len(state.Proposals))
+		res.Proposals = pageProposals(state.Proposals, page, count)
+
+	case schema.ProposalStatusEnded:
+		ended, total, err := h.wdb.GetEndedProposals(page, count)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		res.Total = total
+		res.Proposals = []*hvmSchema.Proposal{}
+		for _, e := range ended {
+			p := &hvmSchema.Proposal{}
+			if err := json.Unmarshal([]byte(e.Data), p); err != nil {
+				log.Error("unmarshal ended proposal failed", "ID", e.ProposalID, "err", err)
+				continue
+			}
+			res.Proposals = append(res.Proposals, p)
+		}
+
+	default:
+		c.JSON(http.StatusBadRequest, schema.ErrInvalidProposalStatus.Error())
+		return
+	}
+
+	for _, p := range res.Proposals {
+		p.ExecutedTxs = map[string]string{}
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Halo) getProposalExecutions(c *gin.Context) {
+	if c.Param("id") == "" {
+		c.JSON(http.StatusBadRequest, schema.ErrMissParams.Error())
+		return
+	}
+	page, count, err := pageQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	executions, err := h.wdb.GetProposalExecutions(c.Param("id"), page, count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, executions)
+}
+
+func (h *Halo) proposalDryRun(c *gin.Context) {
+	if h.dryRunToken == "" {
+		c.JSON(http.StatusForbidden, schema.ErrDryRunDisabled.Error())
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+h.dryRunToken)) != 1 {
+		c.JSON(http.StatusUnauthorized, schema.ErrInvalidDryRunToken.Error())
+		return
+	}
+	select {
+	case h.dryRunLimit <- struct{}{}:
+		defer func() { <-h.dryRunLimit }()
+	default:
+		c.JSON(http.StatusTooManyRequests, schema.ErrTooManyDryRuns.Error())
+		return
+	}
+
+	req := &schema.ProposalDryRunReq{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, schema.ErrMissParams.Error())
+		return
+	}
+
+	// compile out of process
+	dryRun, err := newProposalDryRun(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// execute on a copy of state, process of hvm is not blocked
+	h.dryRunChan <- struct{}{}
+	s := <-h.dryRunResChan
+	c.JSON(http.StatusOK, hvm.ProposalDryRun(dryRun.proposal, &dryRun.tx, s.state, s.oracle))
 }
 
 func (h *Halo) getTx(c *gin.Context) {
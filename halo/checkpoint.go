@@ -0,0 +1,150 @@
+package halo
+
+import (
+	"encoding/json"
+
+	everSchema "github.com/everVision/everpay-kits/schema"
+	"github.com/permadao/permaswap/halo/account"
+	"github.com/permadao/permaswap/halo/hvm"
+	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/schema"
+	"github.com/permadao/permaswap/halo/token"
+)
+
+// checkpoint is saved every checkpointInterval txs
+const checkpointInterval = 1000
+
+// SetCheckpoint set signers of checkpoint, state is restored from the latest checkpoint signed by signers when run,
+// instead of replaying from genesis tx. checkpoints signed by the node itself are trusted if signers is empty.
+// node fails to run without a valid checkpoint if fromCheckpoint is set.
+func (h *Halo) SetCheckpoint(fromCheckpoint bool, signers []string) {
+	h.fromCheckpoint = fromCheckpoint
+	h.checkpointSigners = signers
+}
+
+// NewCheckpoint return checkpoint of state after the tx of everHash and rawID, signed by sign
+func NewCheckpoint(state *hvmSchema.State, rawID int64, everHash, signer string, sign func(string) (string, error)) (*schema.Checkpoint, error) {
+	stateJs, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	tokenJs, err := json.Marshal(state.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &schema.Checkpoint{
+		RawID:     rawID,
+		EverHash:  everHash,
+		StateHash: state.StateHash,
+		State:     string(stateJs),
+		Token:     string(tokenJs),
+		Signer:    signer,
+	}
+	if cp.Sig, err = sign(cp.String()); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// CheckpointVerify verify signature of checkpoint and return the state restored from it
+func CheckpointVerify(cp *schema.Checkpoint, signers []string) (*hvmSchema.State, error) {
+	if !hvm.InSlice(signers, cp.Signer) {
+		return nil, schema.ErrInvalidCheckpointSigner
+	}
+	acc, err := account.New(cp.Signer)
+	if err != nil {
+		return nil, err
+	}
+	hash := cp.Hash()
+	if acc.Type == everSchema.AccountTypeAR {
+		hash = cp.ArHash()
+	}
+	if err := acc.VerifySig(account.Transaction{Hash: hash, Sig: cp.Sig}); err != nil {
+		log.Error("invalid checkpoint signature", "err", err)
+		return nil, schema.ErrInvalidCheckpointSig
+	}
+
+	state := &hvmSchema.State{}
+	if err := json.Unmarshal([]byte(cp.State), state); err != nil {
+		return nil, err
+	}
+	state.Token = &token.Token{}
+	if err := json.Unmarshal([]byte(cp.Token), state.Token); err != nil {
+		return nil, err
+	}
+
+	// executor is not serialized, compile it from source again
+	proposals := append([]*hvmSchema.Proposal{}, state.Proposals...)
+	for _, v := range state.Votings {
+		if v.Proposal != nil {
+			proposals = append(proposals, v.Proposal)
+		}
+	}
+	for _, p := range proposals {
+		executor, err := hvm.NewExecutor(p.Source)
+		if err != nil {
+			log.Error("failed to restore proposal executor", "ID", p.ID, "err", err)
+			return nil, err
+		}
+		if p.Executor != nil {
+			executor.LocalState = p.Executor.LocalState
+			executor.LocalStateHash = p.Executor.LocalStateHash
+			executor.RunnedTimes = p.Executor.RunnedTimes
+		}
+		p.Executor = executor
+	}
+
+	if hash := state.Hash(); hash != cp.StateHash || hash != state.StateHash {
+		log.Error("checkpoint state hash not match", "hash", hash, "checkpoint", cp.StateHash)
+		return nil, schema.ErrInvalidCheckpointHash
+	}
+	return state, nil
+}
+
+// restoreCheckpoint restore hvm from the latest checkpoint in db and return its everPay cursor
+func (h *Halo) restoreCheckpoint() (int64, error) {
+	cp, err := h.wdb.GetLatestCheckpoint()
+	if err != nil {
+		log.Error("failed to get checkpoint", "err", err)
+		return 0, schema.ErrNoCheckpoint
+	}
+
+	signers := h.checkpointSigners
+	if len(signers) == 0 {
+		signers = []string{h.everSDK.AccId}
+	}
+	state, err := CheckpointVerify(cp, signers)
+	if err != nil {
+		return 0, err
+	}
+
+	h.hvm = hvm.New(*state)
+	h.txsSinceCheckpoint = 0
+	log.Info("restored from checkpoint", "rawId", cp.RawID, "everHash", cp.EverHash, "stateHash", cp.StateHash)
+	return cp.RawID, nil
+}
+
+// checkpoint save checkpoint of current state every checkpointInterval txs
+func (h *Halo) checkpoint(rawID int64, everHash string) {
+	h.txsSinceCheckpoint++
+	if h.txsSinceCheckpoint < checkpointInterval {
+		return
+	}
+
+	cp, err := NewCheckpoint(&h.hvm.State, rawID, everHash, h.everSDK.AccId, h.everSDK.Sign)
+	if err != nil {
+		log.Error("failed to create checkpoint", "everHash", everHash, "err", err)
+		return
+	}
+	h.txsSinceCheckpoint = 0
+	h.checkpointSave <- cp
+}
+
+func (h *Halo) checkpointSaveProcess() {
+	for cp := range h.checkpointSave {
+		if err := h.wdb.CreateCheckpoint(cp, nil); err != nil {
+			log.Error("create checkpoint failed", "rawId", cp.RawID, "err", err)
+		}
+	}
+}
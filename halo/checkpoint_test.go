@@ -0,0 +1,77 @@
+package halo
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/everFinance/goether"
+	"github.com/permadao/permaswap/halo/account"
+	"github.com/permadao/permaswap/halo/hvm"
+	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/schema"
+	"github.com/permadao/permaswap/halo/token"
+	"github.com/stretchr/testify/assert"
+)
+
+const checkpointSource = `package proposal
+
+import (
+	"github.com/permadao/permaswap/halo/hvm/schema"
+)
+
+func Execute(tx *schema.Transaction, state *schema.StateForProposal, oracle *schema.Oracle, localState string, initData string) (*schema.StateForProposal, string, string, error) {
+	return state, localState + "1", "", nil
+}
+`
+
+func TestCheckpoint(t *testing.T) {
+	signer, err := goether.NewSigner("4c0a4ac0d5d5b5ae2c4a12a1b8b8ec1d2e5f6d4e3f9c8a7b6c5d4e3f2a1b0c9d")
+	assert.NoError(t, err)
+	sign := func(msg string) (string, error) {
+		sig, err := signer.SignMsg([]byte(msg))
+		return hexutil.Encode(sig), err
+	}
+
+	executor, err := hvm.NewExecutor(checkpointSource)
+	assert.NoError(t, err)
+	executor.LocalState = "11"
+	executor.RunnedTimes = 2
+	proposal := hvm.NewProposal("test", 0, 0, 0, checkpointSource, "", nil, executor)
+
+	h := hvm.New(hvmSchema.State{
+		Dapp:    "halo",
+		ChainID: "1",
+		Token:   token.New("HALO", 18, big.NewInt(100), map[string]*big.Int{signer.Address.String(): big.NewInt(100)}, nil),
+		Accounts: map[string]*account.Account{
+			signer.Address.String(): {ID: signer.Address.String(), Type: account.AccountTypeEVM, Nonce: 10},
+		},
+		Proposals: []*hvmSchema.Proposal{proposal},
+	})
+	h.StateHash = h.Hash()
+
+	cp, err := NewCheckpoint(&h.State, 100, "0x01", signer.Address.String(), sign)
+	assert.NoError(t, err)
+
+	state, err := CheckpointVerify(cp, []string{signer.Address.String()})
+	assert.NoError(t, err)
+	assert.Equal(t, h.StateHash, state.Hash())
+	assert.Equal(t, "100", state.Token.Balances[signer.Address.String()].String())
+	assert.Equal(t, "11", state.Proposals[0].Executor.LocalState)
+	assert.NotNil(t, state.Proposals[0].Executor.Execute)
+
+	_, err = CheckpointVerify(cp, []string{"0x7759cb78EaF06c470165F0B57af7Ffd737407D56"})
+	assert.Equal(t, schema.ErrInvalidCheckpointSigner, err)
+
+	// cursor is signed
+	cp2 := *cp
+	cp2.RawID = 101
+	_, err = CheckpointVerify(&cp2, []string{signer.Address.String()})
+	assert.Equal(t, schema.ErrInvalidCheckpointSig, err)
+
+	// state is verified by state hash
+	cp2 = *cp
+	cp2.Token = `{"symbol":"HALO","decimals":18,"totalSupply":100,"balances":{"` + signer.Address.String() + `":101}}`
+	_, err = CheckpointVerify(&cp2, []string{signer.Address.String()})
+	assert.Equal(t, schema.ErrInvalidCheckpointHash, err)
+}
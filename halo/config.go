@@ -4,4 +4,6 @@ type Config struct {
 	Genesis           string
 	UrlPrefix         string `toml:"url_prefix"`
 	DefaulHaloNodeUrl string `toml:"default_halo_node_url"`
+	DryRunToken       string `toml:"dry_run_token"`
+	StateLogs         bool   `toml:"state_logs"`
 }
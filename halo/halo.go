@@ -1,6 +1,7 @@
 package halo
 
 import (
+	everSchema "github.com/everVision/everpay-kits/schema"
 	"github.com/everVision/everpay-kits/sdk"
 	"github.com/gin-gonic/gin"
 	"github.com/permadao/permaswap/halo/hvm"
@@ -23,6 +24,17 @@ type Halo struct {
 	engine  *gin.Engine
 	wdb     *WDB
 
+	genesisTx everSchema.TxResponse
+
+	fromCheckpoint     bool
+	checkpointSigners  []string
+	txsSinceCheckpoint int
+	checkConsistency   bool
+	stateLogs          bool          // save state diff and proposal executions of every tx
+	lastState          string        // serialization of state after the last tx
+	dryRunToken        string        // token required by dry run api, no auth if empty
+	dryRunLimit        chan struct{} // limit concurrent dry runs
+
 	// channels
 	close          chan struct{}
 	closed         chan struct{}
@@ -32,7 +44,10 @@ type Halo struct {
 	stateResChan   chan string
 	tokenChan      chan struct{}
 	tokenResChan   chan *tokSchema.TokenInfo
-	txSave         chan *schema.HaloTransaction
+	dryRunChan     chan struct{}
+	dryRunResChan  chan *dryRunState
+	txSave         chan *txToSave
+	checkpointSave chan *schema.Checkpoint
 }
 
 func New(genesisTx, dsn string, everSDK *sdk.SDK) (h *Halo) {
@@ -53,6 +68,7 @@ func New(genesisTx, dsn string, everSDK *sdk.SDK) (h *Halo) {
 		everSDK:           everSDK,
 		GenesisTxEverHash: genesisTx,
 		GenesisTxRawID:    txResp.RawId,
+		genesisTx:         txResp,
 		HaloAddr:          txResp.To,
 		engine:            gin.Default(),
 		wdb:               NewWDB(dsn),
@@ -64,15 +80,48 @@ func New(genesisTx, dsn string, everSDK *sdk.SDK) (h *Halo) {
 		stateResChan:      make(chan string),
 		tokenChan:         make(chan struct{}),
 		tokenResChan:      make(chan *tokSchema.TokenInfo),
-		txSave:            make(chan *schema.HaloTransaction),
+		dryRunLimit:       make(chan struct{}, maxConcurrentDryRuns),
+		dryRunChan:        make(chan struct{}),
+		dryRunResChan:     make(chan *dryRunState),
+		txSave:            make(chan *txToSave),
+		checkpointSave:    make(chan *schema.Checkpoint),
 	}
 }
 
 func (h *Halo) Run(port string) {
 	h.wdb.Migrate()
-	h.track(h.GenesisTxRawID)
+	if err := h.backfillRawID(); err != nil {
+		log.Error("backfill raw id failed", "err", err)
+		panic(err)
+	}
+	cursor := h.GenesisTxRawID
+	var err error
+	// state is restored from the latest checkpoint, txs after it are replayed
+	if cp, err := h.restoreCheckpoint(); err == nil {
+		cursor = cp
+	} else if h.fromCheckpoint {
+		log.Error("restore from checkpoint failed", "err", err)
+		panic(err)
+	} else {
+		log.Warn("no checkpoint restored, rebuild from genesis", "err", err)
+	}
+	if cursor, err = h.rebuild(cursor); err != nil {
+		log.Error("rebuild from db failed", "err", err)
+		panic(err)
+	}
+	if h.checkConsistency {
+		if err := h.CheckConsistency(); err != nil {
+			panic(err)
+		}
+	}
+	if h.stateLogs {
+		h.lastState = h.hvm.StateString()
+		h.hvm.LogProposals = true
+	}
+	h.track(cursor)
 	go h.runProcess()
 	go h.txSaveProcess()
+	go h.checkpointSaveProcess()
 	if port != "" {
 		go h.runAPI(port)
 	}
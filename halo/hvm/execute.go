@@ -9,7 +9,7 @@ import (
 	"github.com/permadao/permaswap/halo/hvm/schema"
 )
 
-func (h *HVM) VerifyTx(tx schema.Transaction, oracle *schema.Oracle) (err error) {
+func (h *HVM) VerifyTx(tx schema.Transaction) (err error) {
 	_, nonce, fee, err := h.TxVerify(tx, true)
 	if err != nil {
 		return err
@@ -117,13 +117,42 @@ func (h *HVM) VerifyTx(tx schema.Transaction, oracle *schema.Oracle) (err error)
 			return schema.ErrNoProposalFound
 		}
 
+	case schema.TxActionTick:
+		if !InSlice(h.Routers, tx.From) {
+			return schema.ErrNotARouter
+		}
+		if _, err := h.tickPricesVerify(tx.Params); err != nil {
+			return err
+		}
+
+	case schema.TxActionUpgrade:
+		if _, err := h.UpgradeVerify(tx); err != nil {
+			return err
+		}
+
+	case schema.TxActionVote:
+		if err := h.vote(tx, h.txClock(tx, nonce), true); err != nil {
+			return err
+		}
+
+	case schema.TxActionVotingParams:
+		if err := h.verifyProposer(tx.From); err != nil {
+			return err
+		}
+		if _, err := TxVotingParamsVerify(tx.Params); err != nil {
+			return err
+		}
+
 	default:
 		return schema.ErrInvalidTxAction
 	}
 	return nil
 }
 
-func (h *HVM) ExecuteTx(tx schema.Transaction, oracle *schema.Oracle) (err error) {
+// ExecuteTx execute tx on state, oracle of tx is derived from state
+func (h *HVM) ExecuteTx(tx schema.Transaction) (err error) {
+	h.ProposalLogs = []*schema.ProposalLog{}
+	h.logPrev = ""
 	defer func() {
 		if err != schema.ErrTxExecuted {
 			if err != nil {
@@ -132,6 +161,9 @@ func (h *HVM) ExecuteTx(tx schema.Transaction, oracle *schema.Oracle) (err error
 				h.Validity[tx.EverHash] = true
 			}
 			h.Executed = append(h.Executed, tx.EverHash)
+			// state may be changed by failed tx, e.g. proposal executed times
+			h.stateString = h.State.String()
+			h.StateHash = schema.StateStringHash(h.stateString)
 		}
 	}()
 
@@ -156,6 +188,10 @@ func (h *HVM) ExecuteTx(tx schema.Transaction, oracle *schema.Oracle) (err error
 		}
 		nonce, _ = strconv.ParseInt(tx.Nonce, 10, 64)
 	}
+	now := h.txClock(tx, nonce)
+	if tx.Timestamp != 0 {
+		h.Clock = now
+	}
 
 	switch tx.Action {
 	case schema.TxActionTransfer:
@@ -173,7 +209,13 @@ func (h *HVM) ExecuteTx(tx schema.Transaction, oracle *schema.Oracle) (err error
 			return err
 		}
 
-		h.Proposals = append(h.Proposals, proposal)
+		// proposal is activated after voting passed
+		if h.votingEnabled() {
+			h.Votings = append(h.Votings, h.newVoting(tx, proposal.ID, proposal, now))
+		} else {
+			activateSchedule(proposal, now)
+			h.Proposals = append(h.Proposals, proposal)
+		}
 
 	case schema.TxActionStake:
 		stakePool, amount, err := TxStakeParamsVerify(tx.Params)
@@ -255,6 +297,7 @@ func (h *HVM) ExecuteTx(tx schema.Transaction, oracle *schema.Oracle) (err error
 		log.Info("Remove router from routers", "router", tx.From)
 		h.Routers = RemoveFromSlice(h.Routers, tx.From)
 		delete(h.RouterStates, tx.From)
+		h.removePrices(tx.From)
 
 	case schema.TxActionCall:
 		proposalID, _, _, err := TxCallParamsVerify(tx.Params)
@@ -279,14 +322,53 @@ func (h *HVM) ExecuteTx(tx schema.Transaction, oracle *schema.Oracle) (err error
 		if proposalToTerminate == nil {
 			return schema.ErrNoProposalFound
 		}
-		proposals := []*schema.Proposal{}
-		for _, proposal := range h.Proposals {
-			if proposal.ID == proposalID {
-				continue
-			}
-			proposals = append(proposals, proposal)
+		if h.votingEnabled() {
+			h.Votings = append(h.Votings, h.newVoting(tx, proposalID, nil, now))
+		} else {
+			h.removeProposal(proposalID)
+		}
+
+	case schema.TxActionTick:
+		if !InSlice(h.Routers, tx.From) {
+			return schema.ErrNotARouter
+		}
+		prices, err := h.tickPricesVerify(tx.Params)
+		if err != nil {
+			return err
+		}
+		h.updatePrices(tx.From, prices)
+
+	case schema.TxActionUpgrade:
+		upgraded, err := h.UpgradeVerify(tx)
+		if err != nil {
+			return err
+		}
+		if h.votingEnabled() {
+			h.Votings = append(h.Votings, h.newVoting(tx, upgraded.ID, upgraded, now))
+		} else if err := upgradeProposal(FindProposal(h.Proposals, upgraded.ID), upgraded); err != nil {
+			return err
+		}
+
+	case schema.TxActionVote:
+		if err := h.vote(tx, now, false); err != nil {
+			return err
+		}
+
+	case schema.TxActionVotingParams:
+		if err := h.verifyProposer(tx.From); err != nil {
+			return err
+		}
+		params, err := TxVotingParamsVerify(tx.Params)
+		if err != nil {
+			return err
+		}
+		if h.votingEnabled() {
+			voting := h.newVoting(tx, "", nil, now)
+			voting.Params = params
+			h.Votings = append(h.Votings, voting)
+		} else {
+			h.setVotingParams(params)
 		}
-		h.Proposals = proposals
 
 	case schema.TxActionSwap:
 		routerState, ok := h.RouterStates[tx.Router]
@@ -304,7 +386,7 @@ func (h *HVM) ExecuteTx(tx schema.Transaction, oracle *schema.Oracle) (err error
 		if routerState.SwapFeeRecipient != "" && routerState.SwapFeeRatio != "0" {
 			routerFee = true
 		}
-		order, err := TxSwapParamsVerify(tx.Params, tx.Nonce, routerFee, pools, oracle.EverTokens)
+		order, err := TxSwapParamsVerify(tx.Params, tx.Nonce, routerFee, pools, h.everTokens())
 		if err != nil {
 			log.Error("swap params verify failed", "err", err)
 			return err
@@ -325,19 +407,26 @@ func (h *HVM) ExecuteTx(tx schema.Transaction, oracle *schema.Oracle) (err error
 	h.LatestTxHash = tx.HexHash()
 	h.LatestTxEverHash = tx.EverHash
 
+	// scheduled proposals are due by time of tx
+	oracle := h.Oracle()
+	h.runSchedules(tx, now, oracle)
+
 	if ProposalCalled != nil {
 		// run proposal executor
 		log.Debug("proposal called", "ID", ProposalCalled.ID, "name", ProposalCalled.Name, "tx", tx.HexHash())
 
-		ns, err := ProposalExecute(ProposalCalled, &tx, h.GetStateForProposal(), oracle)
+		prev := h.proposalLogPrev()
+		ns, err := h.proposalExecute(ProposalCalled, &tx, oracle)
 		if err != nil {
 			log.Error("execute proposal failed", "ID", ProposalCalled.ID, "name", ProposalCalled.Name, "tx", tx.HexHash(), "err", err)
 			ProposalCalled.ExecutedTxs[tx.EverHash] = err.Error()
+			h.logProposal(ProposalCalled, tx.EverHash, prev, err)
 			return err
 		}
 		ProposalCalled.ExecutedTxs[tx.EverHash] = ""
 		h.UpdateState(ns)
-	} else {
+		h.logProposal(ProposalCalled, tx.EverHash, prev, nil)
+	} else if tx.Action != schema.TxActionTick {
 		// run every proposal executor
 		// todo check if proposal is unstarted
 		for _, proposal := range h.Proposals {
@@ -353,17 +442,22 @@ func (h *HVM) ExecuteTx(tx schema.Transaction, oracle *schema.Oracle) (err error
 			}
 
 			log.Debug("execute proposal", "ID", proposal.ID, "name", proposal.Name, "tx", tx.HexHash())
-			ns, err := ProposalExecute(proposal, &tx, h.GetStateForProposal(), oracle)
+			prev := h.proposalLogPrev()
+			ns, err := h.proposalExecute(proposal, &tx, oracle)
 			if err != nil {
 				log.Error("execute proposal failed", "ID", proposal.ID, "name", proposal.Name, "tx", tx.HexHash(), "err", err)
 				proposal.ExecutedTxs[tx.EverHash] = err.Error()
+				h.logProposal(proposal, tx.EverHash, prev, err)
 				continue
 			}
 			proposal.ExecutedTxs[tx.EverHash] = ""
 			h.UpdateState(ns)
+			h.logProposal(proposal, tx.EverHash, prev, nil)
 		}
 	}
 
+	h.settleVotings(now)
+
 	// remove expired/finished proposal
 	proposals := []*schema.Proposal{}
 	for _, proposal := range h.Proposals {
@@ -379,7 +473,5 @@ func (h *HVM) ExecuteTx(tx schema.Transaction, oracle *schema.Oracle) (err error
 		proposals = append(proposals, proposal)
 	}
 	h.Proposals = proposals
-
-	h.StateHash = h.Hash()
 	return nil
 }
@@ -0,0 +1,212 @@
+package hvm
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/token"
+)
+
+// VotingParamsVerify verify quorum and threshold are ratios in [0, 1] if voting is enabled,
+// proposer min stake is an non-negative integer if set
+func VotingParamsVerify(quorum, threshold string, period int64, proposerMinStake string) error {
+	if period < 0 {
+		return schema.ErrInvalidVotingParams
+	}
+	if proposerMinStake != "" {
+		minStake, ok := new(big.Int).SetString(proposerMinStake, 10)
+		if !ok || minStake.Sign() < 0 {
+			return schema.ErrInvalidVotingParams
+		}
+	}
+	if period == 0 {
+		return nil
+	}
+	for _, r := range []string{quorum, threshold} {
+		ratio, ok := new(big.Rat).SetString(r)
+		if !ok || ratio.Sign() < 0 || ratio.Cmp(big.NewRat(1, 1)) > 0 {
+			return schema.ErrInvalidVotingParams
+		}
+	}
+	return nil
+}
+
+func (h *HVM) votingEnabled() bool {
+	return h.VotingPeriod > 0
+}
+
+func TxVotingParamsVerify(txParams string) (*schema.VotingParams, error) {
+	params := &schema.VotingParams{}
+	if err := json.Unmarshal([]byte(txParams), params); err != nil {
+		log.Error("invalid params of votingParams tx to unmarshal", "params", txParams, "err", err)
+		return nil, schema.ErrInvalidTxParams
+	}
+	if err := VotingParamsVerify(params.VoteQuorum, params.VoteThreshold, params.VotingPeriod, params.ProposerMinStake); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+func (h *HVM) setVotingParams(params *schema.VotingParams) {
+	h.VoteQuorum = params.VoteQuorum
+	h.VoteThreshold = params.VoteThreshold
+	h.VotingPeriod = params.VotingPeriod
+	h.ProposerMinStake = params.ProposerMinStake
+}
+
+func TxVoteParamsVerify(txParams string) (votingID, option string, err error) {
+	params := schema.TxVoteParams{}
+	if err := json.Unmarshal([]byte(txParams), &params); err != nil {
+		log.Error("invalid params of vote tx to unmarshal", "params", txParams, "err", err)
+		return "", "", schema.ErrInvalidTxParams
+	}
+	if params.VotingID == "" {
+		log.Error("no voting id of vote tx ")
+		return "", "", schema.ErrInvalidTxParams
+	}
+	if params.Option != schema.VoteOptionYes && params.Option != schema.VoteOptionNo {
+		return "", "", schema.ErrInvalidVoteOption
+	}
+	return params.VotingID, params.Option, nil
+}
+
+func FindVoting(votings []*schema.Voting, votingID string) *schema.Voting {
+	for _, v := range votings {
+		if v.ID == votingID {
+			return v
+		}
+	}
+	return nil
+}
+
+// newVoting start voting of propose, terminate, upgrade or votingParams tx at now
+func (h *HVM) newVoting(tx schema.Transaction, proposalID string, proposal *schema.Proposal, now int64) *schema.Voting {
+	return &schema.Voting{
+		ID:         tx.HexHash(),
+		Action:     tx.Action,
+		Proposer:   tx.From,
+		ProposalID: proposalID,
+		Proposal:   proposal,
+		Start:      now,
+		End:        now + h.VotingPeriod,
+		Votes:      map[string]string{},
+	}
+}
+
+// vote record option of voter, vote again to change option
+func (h *HVM) vote(tx schema.Transaction, now int64, dryRun bool) error {
+	votingID, option, err := TxVoteParamsVerify(tx.Params)
+	if err != nil {
+		return err
+	}
+	voting := FindVoting(h.Votings, votingID)
+	if voting == nil {
+		return schema.ErrNoVotingFound
+	}
+	if now >= voting.End {
+		return schema.ErrVotingClosed
+	}
+	if staked, _ := new(big.Int).SetString(h.Token.TotalStaked(tx.From, ""), 10); staked.Sign() != 1 {
+		return schema.ErrNoVotingPower
+	}
+	if !dryRun {
+		voting.Votes[tx.From] = option
+	}
+	return nil
+}
+
+// tallyVoting return weights of yes and no, votes are weighted by stakes when tallied,
+// so stake moved to another account after voting is not counted twice
+func tallyVoting(voting *schema.Voting, tok *token.Token) (yes, no *big.Int) {
+	yes, no = big.NewInt(0), big.NewInt(0)
+	for voter, option := range voting.Votes {
+		weight, _ := new(big.Int).SetString(tok.TotalStaked(voter, ""), 10)
+		if option == schema.VoteOptionYes {
+			yes.Add(yes, weight)
+		} else {
+			no.Add(no, weight)
+		}
+	}
+	return
+}
+
+func totalStaked(tok *token.Token) *big.Int {
+	total := big.NewInt(0)
+	for _, pools := range tok.Stakes {
+		for _, stakes := range pools {
+			for _, stake := range stakes {
+				total.Add(total, stake.Amount)
+			}
+		}
+	}
+	return total
+}
+
+// votingPassed return true if votes reach quorum of total staked and ratio of yes is greater than threshold
+func (h *HVM) votingPassed(voting *schema.Voting) bool {
+	quorum, ok := new(big.Rat).SetString(h.VoteQuorum)
+	if !ok {
+		return false
+	}
+	threshold, ok := new(big.Rat).SetString(h.VoteThreshold)
+	if !ok {
+		return false
+	}
+
+	yes, no := tallyVoting(voting, h.Token)
+	votes := new(big.Int).Add(yes, no)
+	if votes.Sign() == 0 {
+		return false
+	}
+	total := totalStaked(h.Token)
+	if new(big.Rat).SetFrac(votes, total).Cmp(quorum) < 0 {
+		return false
+	}
+	return new(big.Rat).SetFrac(yes, votes).Cmp(threshold) > 0
+}
+
+// settleVotings close votings ended at now, proposal is activated or terminated, or voting params are changed if voting passed
+func (h *HVM) settleVotings(now int64) {
+	votings := []*schema.Voting{}
+	for _, voting := range h.Votings {
+		if now < voting.End {
+			votings = append(votings, voting)
+			continue
+		}
+
+		passed := h.votingPassed(voting)
+		log.Info("voting ended", "ID", voting.ID, "action", voting.Action, "proposalID", voting.ProposalID, "passed", passed)
+		if !passed {
+			continue
+		}
+		switch voting.Action {
+		case schema.TxActionPropose:
+			if FindProposal(h.Proposals, voting.ProposalID) == nil {
+				activateSchedule(voting.Proposal, now)
+				h.Proposals = append(h.Proposals, voting.Proposal)
+			}
+		case schema.TxActionTerminate:
+			h.removeProposal(voting.ProposalID)
+		case schema.TxActionUpgrade:
+			// proposal may be ended during voting
+			if proposal := FindProposal(h.Proposals, voting.ProposalID); proposal != nil {
+				upgradeProposal(proposal, voting.Proposal)
+			}
+		case schema.TxActionVotingParams:
+			h.setVotingParams(voting.Params)
+		}
+	}
+	h.Votings = votings
+}
+
+func (h *HVM) removeProposal(proposalID string) {
+	proposals := []*schema.Proposal{}
+	for _, proposal := range h.Proposals {
+		if proposal.ID == proposalID {
+			continue
+		}
+		proposals = append(proposals, proposal)
+	}
+	h.Proposals = proposals
+}
@@ -0,0 +1,255 @@
+package hvm
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/everFinance/goether"
+	"github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/token"
+	tokSchema "github.com/permadao/permaswap/halo/token/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func newGovernHVM() *HVM {
+	stakes := map[string]map[string][]tokSchema.Stake{
+		"0xa": {"basic": {{StakedAt: 1, Amount: big.NewInt(60)}}},
+		"0xb": {"basic": {{StakedAt: 1, Amount: big.NewInt(30)}}},
+		"0xc": {"basic": {{StakedAt: 1, Amount: big.NewInt(10)}}},
+	}
+	return New(schema.State{
+		VoteQuorum:    "0.5",
+		VoteThreshold: "0.5",
+		VotingPeriod:  100,
+		Token:         token.New("HALO", 18, big.NewInt(1000), nil, stakes),
+	})
+}
+
+func voteTx(from, votingID, option string) schema.Transaction {
+	return schema.Transaction{
+		Action: schema.TxActionVote,
+		From:   from,
+		Params: `{"votingID":"` + votingID + `","option":"` + option + `"}`,
+	}
+}
+
+func TestVotingPropose(t *testing.T) {
+	h := newGovernHVM()
+	proposal := NewProposal("test", 0, 0, 1, source, "", nil, nil)
+	tx := schema.Transaction{Action: schema.TxActionPropose, From: "0xc", Nonce: "1000"}
+	h.Votings = append(h.Votings, h.newVoting(tx, proposal.ID, proposal, 1))
+	votingID := tx.HexHash()
+	assert.Equal(t, int64(101), h.Votings[0].End)
+
+	assert.Equal(t, schema.ErrNoVotingPower, h.vote(voteTx("0xd", votingID, schema.VoteOptionYes), 50, false))
+	assert.Equal(t, schema.ErrNoVotingFound, h.vote(voteTx("0xa", "0x01", schema.VoteOptionYes), 50, false))
+	assert.Equal(t, schema.ErrInvalidVoteOption, h.vote(voteTx("0xa", votingID, "maybe"), 50, false))
+	assert.NoError(t, h.vote(voteTx("0xa", votingID, schema.VoteOptionNo), 50, false))
+	// change vote
+	assert.NoError(t, h.vote(voteTx("0xa", votingID, schema.VoteOptionYes), 60, false))
+	assert.NoError(t, h.vote(voteTx("0xb", votingID, schema.VoteOptionNo), 60, false))
+	assert.Equal(t, schema.ErrVotingClosed, h.vote(voteTx("0xc", votingID, schema.VoteOptionNo), 101, false))
+
+	// not activated in voting window
+	h.settleVotings(100)
+	assert.Equal(t, 1, len(h.Votings))
+	assert.Equal(t, 0, len(h.Proposals))
+
+	// yes 60 > 90 * 0.5
+	h.settleVotings(101)
+	assert.Equal(t, 0, len(h.Votings))
+	assert.Equal(t, 1, len(h.Proposals))
+	assert.Equal(t, proposal.ID, h.Proposals[0].ID)
+
+	// terminate
+	tx = schema.Transaction{Action: schema.TxActionTerminate, From: "0xb", Nonce: "2000"}
+	h.Votings = append(h.Votings, h.newVoting(tx, proposal.ID, nil, 200))
+	assert.NoError(t, h.vote(voteTx("0xa", tx.HexHash(), schema.VoteOptionYes), 250, false))
+	assert.NoError(t, h.vote(voteTx("0xc", tx.HexHash(), schema.VoteOptionNo), 250, false))
+	h.settleVotings(300)
+	assert.Equal(t, 0, len(h.Proposals))
+}
+
+func TestVotingFailed(t *testing.T) {
+	h := newGovernHVM()
+	proposal := NewProposal("test", 0, 0, 1, source, "", nil, nil)
+	tx := schema.Transaction{Action: schema.TxActionPropose, From: "0xc", Nonce: "1000"}
+	h.Votings = append(h.Votings, h.newVoting(tx, proposal.ID, proposal, 1))
+
+	// 40 of 100 voted, quorum not reached
+	assert.NoError(t, h.vote(voteTx("0xb", tx.HexHash(), schema.VoteOptionYes), 50, false))
+	assert.NoError(t, h.vote(voteTx("0xc", tx.HexHash(), schema.VoteOptionYes), 50, false))
+	assert.False(t, h.votingPassed(h.Votings[0]))
+
+	// yes 50 is not greater than 100 * 0.5
+	assert.NoError(t, h.vote(voteTx("0xa", tx.HexHash(), schema.VoteOptionNo), 50, false))
+	h.Token.Stakes["0xa"]["basic"][0].Amount = big.NewInt(50)
+	assert.False(t, h.votingPassed(h.Votings[0]))
+
+	// votes are weighted by stake when tallied
+	h.Token.Stakes["0xa"]["basic"][0].Amount = big.NewInt(30)
+	assert.True(t, h.votingPassed(h.Votings[0]))
+	h.Token.Stakes["0xa"]["basic"][0].Amount = big.NewInt(50)
+
+	h.settleVotings(101)
+	assert.Equal(t, 0, len(h.Votings))
+	assert.Equal(t, 0, len(h.Proposals))
+}
+
+func TestVotingParamsVerify(t *testing.T) {
+	assert.NoError(t, VotingParamsVerify("", "", 0, ""))
+	assert.NoError(t, VotingParamsVerify("0.1", "0.5", 100, ""))
+	assert.Equal(t, schema.ErrInvalidVotingParams, VotingParamsVerify("1.1", "0.5", 100, ""))
+	assert.Equal(t, schema.ErrInvalidVotingParams, VotingParamsVerify("0.1", "", 100, ""))
+	assert.Equal(t, schema.ErrInvalidVotingParams, VotingParamsVerify("0.1", "0.5", -1, ""))
+}
+
+func TestVotingParamsVerifyMinStake(t *testing.T) {
+	assert.NoError(t, VotingParamsVerify("0.1", "0.5", 100, "10"))
+	assert.NoError(t, VotingParamsVerify("", "", 0, "0"))
+	assert.Equal(t, schema.ErrInvalidVotingParams, VotingParamsVerify("0.1", "0.5", 100, "-1"))
+	assert.Equal(t, schema.ErrInvalidVotingParams, VotingParamsVerify("0.1", "0.5", 100, "1.5"))
+}
+
+// proposal tries to change voting params without voting
+const invalidParamsSource = `package proposal
+
+import (
+	"github.com/permadao/permaswap/halo/hvm/schema"
+)
+
+func Execute(tx *schema.Transaction, state *schema.StateForProposal, oracle *schema.Oracle, localState string, initData string) (*schema.StateForProposal, string, string, error) {
+	state.VoteQuorum = "2"
+	return state, localState, "", nil
+}
+`
+
+func TestVotingExecuteTx(t *testing.T) {
+	signers := []*goether.Signer{}
+	for _, key := range []string{
+		"4c0a4ac0d5d5b5ae2c4a12a1b8b8ec1d2e5f6d4e3f9c8a7b6c5d4e3f2a1b0c9d",
+		"5d1b5bd1e6e6c6bf3d5b23b2c9c9fd2e3f6a7e5f4a0d9b8c7d6e5f4a3b2c1d0e",
+		"6e2c6ce2f7f7d7c04e6c34c3dadb0e3f4a7b8f6a5b1eac9d8e7f6a5b4c3d2e1f",
+	} {
+		signer, err := goether.NewSigner(key)
+		assert.NoError(t, err)
+		signers = append(signers, signer)
+	}
+	a, b, c := signers[0], signers[1], signers[2]
+	addrA, addrB, addrC := a.Address.String(), b.Address.String(), c.Address.String()
+
+	stakes := map[string]map[string][]tokSchema.Stake{
+		addrA: {"basic": {{StakedAt: 1, Amount: big.NewInt(60)}}},
+		addrB: {"basic": {{StakedAt: 1, Amount: big.NewInt(30)}}},
+		addrC: {"basic": {{StakedAt: 1, Amount: big.NewInt(1)}}},
+	}
+	balances := map[string]*big.Int{addrB: big.NewInt(10)}
+	h := New(schema.State{
+		Dapp:             "halo",
+		ChainID:          "1",
+		FeeRecipient:     "0xfee",
+		VoteQuorum:       "0.5",
+		VoteThreshold:    "0.5",
+		VotingPeriod:     100,
+		ProposerMinStake: "10",
+		Routers:          []string{"0xrouter"},
+		Token:            token.New("HALO", 18, big.NewInt(1000), balances, stakes),
+	})
+
+	executeAt := func(signer *goether.Signer, action, params string, nonce, timestamp int64) (schema.Transaction, error) {
+		tx := schema.Transaction{
+			Dapp:         "halo",
+			ChainID:      "1",
+			Router:       "0xrouter",
+			Action:       action,
+			From:         signer.Address.String(),
+			Fee:          "0",
+			FeeRecipient: "0xfee",
+			Nonce:        strconv.FormatInt(nonce, 10),
+			Version:      schema.TxVersionV1,
+			Params:       params,
+		}
+		sig, err := signer.SignMsg([]byte(tx.String()))
+		assert.NoError(t, err)
+		tx.Sig = hexutil.Encode(sig)
+		tx.EverHash = tx.HexHash()
+		tx.Timestamp = timestamp
+		return tx, h.ExecuteTx(tx)
+	}
+	execute := func(signer *goether.Signer, action, params string, nonce int64) (schema.Transaction, error) {
+		return executeAt(signer, action, params, nonce, 0)
+	}
+	vote := func(signer *goether.Signer, votingID string, nonce int64) {
+		_, err := execute(signer, schema.TxActionVote, `{"votingID":"`+votingID+`","option":"yes"}`, nonce)
+		assert.NoError(t, err)
+	}
+
+	by, _ := json.Marshal(schema.TxProposeParams{Name: "params", Source: invalidParamsSource, RunTimes: 100})
+	proposeParams := string(by)
+
+	// stake of c is less than proposer min stake
+	_, err := execute(c, schema.TxActionPropose, proposeParams, 1000000)
+	assert.Equal(t, schema.ErrInvalidProposer, err)
+
+	// propose
+	tx, err := execute(a, schema.TxActionPropose, proposeParams, 1000001)
+	assert.NoError(t, err)
+	proposeVotingID := tx.HexHash()
+	assert.Equal(t, 1, len(h.Votings))
+	assert.Equal(t, 0, len(h.Proposals))
+	vote(a, proposeVotingID, 1010000)
+	vote(b, proposeVotingID, 1010000)
+
+	// voting params, proposal is activated by the voting ended
+	tx, err = execute(a, schema.TxActionVotingParams, `{"voteQuorum":"0.5","voteThreshold":"0.5","votingPeriod":200,"proposerMinStake":"1"}`, 1101000)
+	assert.NoError(t, err)
+	paramsVotingID := tx.HexHash()
+	assert.Equal(t, 1, len(h.Proposals))
+	assert.Equal(t, 1, len(h.Votings))
+	assert.Equal(t, paramsVotingID, h.Votings[0].ID)
+	assert.Equal(t, int64(1201), h.Votings[0].End)
+	_, err = execute(a, schema.TxActionVotingParams, `{"voteQuorum":"0.5","voteThreshold":"0.5","votingPeriod":-1}`, 1101001)
+	assert.Equal(t, schema.ErrInvalidVotingParams, err)
+
+	// terminate, voting params can't be changed by proposal
+	proposalID := h.Proposals[0].ID
+	tx, err = execute(b, schema.TxActionTerminate, `{"proposalID":"`+proposalID+`"}`, 1102000)
+	assert.NoError(t, err)
+	terminateVotingID := tx.HexHash()
+	assert.Equal(t, schema.ErrInvalidVotingParams.Error(), h.Proposals[0].ExecutedTxs[tx.EverHash])
+	assert.Equal(t, "0.5", h.VoteQuorum)
+	vote(a, paramsVotingID, 1110000)
+	vote(a, terminateVotingID, 1110001)
+
+	// settle
+	_, err = execute(b, schema.TxActionTransfer, `{"To":"`+addrA+`","Amount":"1"}`, 1203000)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(h.Votings))
+	assert.Equal(t, 0, len(h.Proposals))
+	assert.Equal(t, int64(200), h.VotingPeriod)
+	assert.Equal(t, "1", h.ProposerMinStake)
+
+	// c can propose with new proposer min stake
+	_, err = execute(c, schema.TxActionPropose, proposeParams, 1204000)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(h.Votings))
+	assert.Equal(t, int64(1404), h.Votings[0].End)
+
+	// voting is settled by timestamp of everpay tx, not nonce chosen by sender
+	_, err = executeAt(b, schema.TxActionTransfer, `{"To":"`+addrA+`","Amount":"1"}`, 9000000, 1300000)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(h.Votings))
+	assert.Equal(t, int64(1300), h.Clock)
+	_, err = executeAt(a, schema.TxActionVote, `{"votingID":"`+h.Votings[0].ID+`","option":"yes"}`, 9000001, 1300000)
+	assert.NoError(t, err)
+	// clock does not go back by an earlier timestamp
+	_, err = executeAt(b, schema.TxActionTransfer, `{"To":"`+addrA+`","Amount":"1"}`, 9000002, 1200000)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1300), h.Clock)
+	_, err = executeAt(b, schema.TxActionTransfer, `{"To":"`+addrA+`","Amount":"1"}`, 9000003, 1404000)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(h.Votings))
+}
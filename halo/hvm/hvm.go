@@ -10,6 +10,21 @@ var log = logger.New("hvm")
 
 type HVM struct {
 	schema.State
+
+	// logs of proposals executed by the last tx, only recorded if LogProposals is set
+	LogProposals bool
+	ProposalLogs []*schema.ProposalLog
+
+	stateString string // serialization of state after the last tx
+	logPrev     string // serialization of state after the last proposal logged, empty if state changed since
+}
+
+// StateString return serialization of current state, it is cached after tx executed
+func (h *HVM) StateString() string {
+	if h.stateString == "" {
+		h.stateString = h.State.String()
+	}
+	return h.stateString
 }
 
 func New(initState schema.State) (h *HVM) {
@@ -25,6 +40,9 @@ func New(initState schema.State) (h *HVM) {
 	if initState.Proposals == nil {
 		initState.Proposals = []*schema.Proposal{}
 	}
+	if initState.Votings == nil {
+		initState.Votings = []*schema.Voting{}
+	}
 	if initState.Executed == nil {
 		initState.Executed = []string{}
 	}
@@ -45,6 +63,18 @@ func New(initState schema.State) (h *HVM) {
 	}
 }
 
+// txClock return time of tx in seconds by timestamp of everpay tx, which can not be chosen by sender.
+// it never goes back before the latest tx. txs without timestamp are recorded before it is set, nonce is their clock.
+func (h *HVM) txClock(tx schema.Transaction, nonce int64) int64 {
+	if tx.Timestamp == 0 {
+		return nonce / 1000
+	}
+	if now := tx.Timestamp / 1000; now > h.Clock {
+		return now
+	}
+	return h.Clock
+}
+
 func (h *HVM) getOrCreateAccount(addr string, dryRun bool) (acc *account.Account, err error) {
 	acc = h.Accounts[addr]
 	if acc == nil {
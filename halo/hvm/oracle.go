@@ -0,0 +1,157 @@
+package hvm
+
+import (
+	"encoding/json"
+	"math/big"
+	"sort"
+
+	everSchema "github.com/everVision/everpay-kits/schema"
+	"github.com/permadao/permaswap/halo/hvm/schema"
+)
+
+// max count of prices in a tick tx
+const maxTickPrices = 100
+
+// Oracle return oracle of current state, tokens are tokens of pools of routers and prices are medians of prices reported by routers in tick txs
+func (h *HVM) Oracle() *schema.Oracle {
+	prices := make(map[string]string, len(h.Prices))
+	for tag, price := range h.Prices {
+		prices[tag] = price
+	}
+	return &schema.Oracle{
+		EverTokens: h.everTokens(),
+		Prices:     prices,
+	}
+}
+
+// everTokens return tokens of pools of routers, symbol and decimals are from token info in state
+func (h *HVM) everTokens() map[string]everSchema.TokenInfo {
+	tokens := map[string]everSchema.TokenInfo{}
+	for _, rs := range h.RouterStates {
+		for _, pool := range rs.Pools {
+			tokens[pool.TokenXTag] = h.everToken(pool.TokenXTag)
+			tokens[pool.TokenYTag] = h.everToken(pool.TokenYTag)
+		}
+	}
+	return tokens
+}
+
+func (h *HVM) everToken(tag string) everSchema.TokenInfo {
+	t, ok := h.Tokens[tag]
+	if !ok {
+		return everSchema.TokenInfo{Tag: tag}
+	}
+	return everSchema.TokenInfo{Tag: tag, Symbol: t.Symbol, Decimals: t.Decimals}
+}
+
+// TokensVerify verify token info of genesis and proposals, it is keyed by tag
+func TokensVerify(tokens map[string]*schema.TokenInfo) error {
+	for tag, t := range tokens {
+		if t == nil || tag == "" || t.Tag != tag || t.Symbol == "" || t.Decimals < 0 {
+			log.Error("invalid token info", "tag", tag, "token", t)
+			return schema.ErrInvalidTokenInfo
+		}
+	}
+	return nil
+}
+
+// TxTickParamsVerify return prices of tick tx, params of tick tx without prices is empty
+func TxTickParamsVerify(txParams string) (map[string]string, error) {
+	if txParams == "" {
+		return nil, nil
+	}
+	params := schema.TxTickParams{}
+	if err := json.Unmarshal([]byte(txParams), &params); err != nil {
+		log.Error("invalid params of tick tx to unmarshal", "params", txParams, "err", err)
+		return nil, schema.ErrInvalidTxParams
+	}
+	if len(params.Prices) > maxTickPrices {
+		log.Error("too many prices in tick tx", "count", len(params.Prices))
+		return nil, schema.ErrInvalidTxParams
+	}
+	for tag, price := range params.Prices {
+		p, ok := new(big.Float).SetString(price)
+		if tag == "" || !ok || p.Sign() <= 0 {
+			log.Error("invalid price of tick tx", "tag", tag, "price", price)
+			return nil, schema.ErrInvalidTxParams
+		}
+	}
+	return params.Prices, nil
+}
+
+// tickPricesVerify return prices of tick tx, only tokens of pools can be priced
+func (h *HVM) tickPricesVerify(txParams string) (map[string]string, error) {
+	prices, err := TxTickParamsVerify(txParams)
+	if err != nil {
+		return nil, err
+	}
+	tokens := h.everTokens()
+	for tag := range prices {
+		if _, ok := tokens[tag]; !ok {
+			log.Error("price of tick tx is not for token of pools", "tag", tag)
+			return nil, schema.ErrInvalidTxParams
+		}
+	}
+	return prices, nil
+}
+
+// updatePrices update prices reported by router, prices not reported are kept.
+// price of token in state is the median of prices reported by routers.
+func (h *HVM) updatePrices(router string, prices map[string]string) {
+	if len(prices) == 0 {
+		return
+	}
+	rps := make(map[string]map[string]string, len(h.RouterPrices)+1)
+	for r, ps := range h.RouterPrices {
+		rps[r] = ps
+	}
+	ps := make(map[string]string, len(rps[router])+len(prices))
+	for tag, price := range rps[router] {
+		ps[tag] = price
+	}
+	for tag, price := range prices {
+		ps[tag] = price
+	}
+	rps[router] = ps
+	h.RouterPrices = rps
+	h.Prices = medianPrices(rps)
+}
+
+// removePrices remove prices reported by router which leaves
+func (h *HVM) removePrices(router string) {
+	if _, ok := h.RouterPrices[router]; !ok {
+		return
+	}
+	rps := make(map[string]map[string]string, len(h.RouterPrices))
+	for r, ps := range h.RouterPrices {
+		if r != router {
+			rps[r] = ps
+		}
+	}
+	h.RouterPrices = rps
+	h.Prices = medianPrices(rps)
+}
+
+// medianPrices return median price of each token reported by routers.
+// the lower one of two middle prices is taken, so the median is always a price reported.
+func medianPrices(routerPrices map[string]map[string]string) map[string]string {
+	reported := map[string][]string{}
+	for _, ps := range routerPrices {
+		for tag, price := range ps {
+			reported[tag] = append(reported[tag], price)
+		}
+	}
+	prices := make(map[string]string, len(reported))
+	for tag, ps := range reported {
+		sort.Slice(ps, func(i, j int) bool {
+			pi, _ := new(big.Float).SetString(ps[i])
+			pj, _ := new(big.Float).SetString(ps[j])
+			if c := pi.Cmp(pj); c != 0 {
+				return c < 0
+			}
+			return ps[i] < ps[j]
+		})
+		prices[tag] = ps[(len(ps)-1)/2]
+	}
+	return prices
+}
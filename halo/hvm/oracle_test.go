@@ -0,0 +1,106 @@
+package hvm
+
+import (
+	"testing"
+
+	"github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxTickParamsVerify(t *testing.T) {
+	prices, err := TxTickParamsVerify("")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(prices))
+
+	prices, err = TxTickParamsVerify(`{"prices":{"ethereum-usdc-0x1":"1","arweave,ethereum-ar-AR":"6.32"}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "6.32", prices["arweave,ethereum-ar-AR"])
+
+	for _, params := range []string{
+		`{"prices":{"ethereum-usdc-0x1":"-1"}}`,
+		`{"prices":{"ethereum-usdc-0x1":"abc"}}`,
+		`{"prices":{"":"1"}}`,
+		`prices`,
+	} {
+		_, err = TxTickParamsVerify(params)
+		assert.Equal(t, schema.ErrInvalidTxParams, err, params)
+	}
+}
+
+func TestOracle(t *testing.T) {
+	h := New(schema.State{
+		Routers: []string{"0xrouter1", "0xrouter2", "0xrouter3"},
+		RouterStates: map[string]*schema.RouterState{
+			"0xrouter1": {Router: "0xrouter1", Pools: map[string]*schema.Pool{
+				"0xpool": {TokenXTag: "ethereum-usdc-0x1", TokenYTag: "arweave,ethereum-ar-AR"},
+			}},
+		},
+		Tokens: map[string]*schema.TokenInfo{
+			"ethereum-usdc-0x1": {Tag: "ethereum-usdc-0x1", Symbol: "USDC", Decimals: 6},
+		},
+	})
+	hash := h.Hash()
+
+	oracle := h.Oracle()
+	assert.Equal(t, 2, len(oracle.EverTokens))
+	assert.Equal(t, "USDC", oracle.EverTokens["ethereum-usdc-0x1"].Symbol)
+	assert.Equal(t, 6, oracle.EverTokens["ethereum-usdc-0x1"].Decimals)
+	// token without info
+	assert.Equal(t, "arweave,ethereum-ar-AR", oracle.EverTokens["arweave,ethereum-ar-AR"].Tag)
+	assert.Equal(t, "", oracle.EverTokens["arweave,ethereum-ar-AR"].Symbol)
+	_, ok := oracle.GetPrice("ethereum-usdc-0x1")
+	assert.False(t, ok)
+
+	// only tokens of pools can be priced
+	_, err := h.tickPricesVerify(`{"prices":{"ethereum-eth-0x0":"2000"}}`)
+	assert.Equal(t, schema.ErrInvalidTxParams, err)
+	prices, err := h.tickPricesVerify(`{"prices":{"ethereum-usdc-0x1":"1"}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", prices["ethereum-usdc-0x1"])
+
+	// price is the median of prices reported by routers
+	h.updatePrices("0xrouter1", map[string]string{"ethereum-usdc-0x1": "1", "arweave,ethereum-ar-AR": "6.32"})
+	assert.NotEqual(t, hash, h.Hash())
+	price, ok := h.Oracle().GetPrice("arweave,ethereum-ar-AR")
+	assert.True(t, ok)
+	assert.Equal(t, "6.32", price.String())
+
+	h.updatePrices("0xrouter2", map[string]string{"arweave,ethereum-ar-AR": "100"})
+	h.updatePrices("0xrouter3", map[string]string{"arweave,ethereum-ar-AR": "6.5"})
+	price, _ = h.Oracle().GetPrice("arweave,ethereum-ar-AR")
+	assert.Equal(t, "6.5", price.String())
+	price, _ = h.Oracle().GetPrice("ethereum-usdc-0x1")
+	assert.Equal(t, "1", price.String())
+
+	// prices not reported are kept
+	h.updatePrices("0xrouter1", map[string]string{"arweave,ethereum-ar-AR": "6.4"})
+	assert.Equal(t, "6.4", h.RouterPrices["0xrouter1"]["arweave,ethereum-ar-AR"])
+	assert.Equal(t, "1", h.RouterPrices["0xrouter1"]["ethereum-usdc-0x1"])
+	price, _ = h.Oracle().GetPrice("arweave,ethereum-ar-AR")
+	assert.Equal(t, "6.5", price.String())
+
+	// lower one of two middle prices
+	h.removePrices("0xrouter3")
+	price, _ = h.Oracle().GetPrice("arweave,ethereum-ar-AR")
+	assert.Equal(t, "6.4", price.String())
+
+	// oracle is a copy
+	oracle = h.Oracle()
+	oracle.Prices["ethereum-usdc-0x1"] = "2"
+	assert.Equal(t, "1", h.Prices["ethereum-usdc-0x1"])
+}
+
+func TestTokensVerify(t *testing.T) {
+	assert.NoError(t, TokensVerify(nil))
+	assert.NoError(t, TokensVerify(map[string]*schema.TokenInfo{
+		"ethereum-usdc-0x1": {Tag: "ethereum-usdc-0x1", Symbol: "USDC", Decimals: 6},
+	}))
+	for _, tokens := range []map[string]*schema.TokenInfo{
+		{"ethereum-usdc-0x1": nil},
+		{"ethereum-usdc-0x1": {Tag: "ethereum-usdc-0x2", Symbol: "USDC", Decimals: 6}},
+		{"ethereum-usdc-0x1": {Tag: "ethereum-usdc-0x1", Decimals: 6}},
+		{"ethereum-usdc-0x1": {Tag: "ethereum-usdc-0x1", Symbol: "USDC", Decimals: -1}},
+	} {
+		assert.Equal(t, schema.ErrInvalidTokenInfo, TokensVerify(tokens))
+	}
+}
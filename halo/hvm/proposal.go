@@ -1,14 +1,28 @@
 package hvm
 
 import (
+	"context"
+	"runtime/metrics"
+	"time"
+
 	"github.com/permadao/permaswap/halo/hvm/schema"
-	"github.com/permadao/permaswap/halo/hvm/symbol"
 
 	"github.com/traefik/yaegi/interp"
-	"github.com/traefik/yaegi/stdlib"
 )
 
+// NewExecutor compile proposal source in sandbox, only allowed packages can be imported and execution is limited by budget
 func NewExecutor(source string) (executor *schema.Executor, err error) {
+	return newExecutor(source, schema.NewBudget(ProposalMaxSteps, ProposalMaxAlloc))
+}
+
+// NewDryRunExecutor compile proposal source for dry run, it has smaller limits than execution of tx
+func NewDryRunExecutor(source string) (executor *schema.Executor, err error) {
+	budget := schema.NewBudget(DryRunMaxSteps, DryRunMaxAlloc)
+	budget.DryRun = true
+	return newExecutor(source, budget)
+}
+
+func newExecutor(source string, budget *schema.Budget) (executor *schema.Executor, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Error("NewExecutor failed", "err", r)
@@ -16,12 +30,25 @@ func NewExecutor(source string) (executor *schema.Executor, err error) {
 		}
 	}()
 
-	i := interp.New(interp.Options{})
-	i.Use(stdlib.Symbols)
+	if err := ProposalSourceVerify(source); err != nil {
+		return nil, err
+	}
+	instrumented, err := instrumentSource(source)
+	if err != nil {
+		return nil, err
+	}
 
-	i.Use(symbol.Symbols)
+	i := interp.New(interp.Options{})
+	i.Use(sandboxSymbols(budget))
 
-	if _, err := i.Eval(source); err != nil {
+	// init of package is limited by budget too
+	ctx, cancel := context.WithTimeout(context.Background(), executeTimeout(budget))
+	defer cancel()
+	if _, err := i.EvalWithContext(ctx, instrumented); err != nil {
+		if ctx.Err() != nil {
+			budget.Cancel()
+			return nil, schema.ErrProposalTimeout
+		}
 		return nil, err
 	}
 
@@ -30,7 +57,13 @@ func NewExecutor(source string) (executor *schema.Executor, err error) {
 		return nil, err
 	}
 	execute := v.Interface().(func(*schema.Transaction, *schema.StateForProposal, *schema.Oracle, string, string) (*schema.StateForProposal, string, string, error))
-	return &schema.Executor{Execute: execute, RunnedTimes: 0, LocalState: ""}, nil
+
+	// migration of local state is optional
+	var migrate func(string, string) (string, string, error)
+	if v, err := i.Eval("proposal.Migrate"); err == nil {
+		migrate = v.Interface().(func(string, string) (string, string, error))
+	}
+	return &schema.Executor{Execute: execute, Migrate: migrate, Budget: budget, RunnedTimes: 0, LocalState: ""}, nil
 }
 
 func NewProposal(name string, start, end, runTimes int64, source, initData string, onlyAcceptedTxActions []string, executor *schema.Executor) *schema.Proposal {
@@ -66,15 +99,32 @@ func ProposalExecute(proposal *schema.Proposal, tx *schema.Transaction, state *s
 
 	proposal.Executor.RunnedTimes++
 
-	stateNew, localStateNew, localStateHashNew, err := proposal.Executor.Execute(txCopied, state, oracle, proposal.Executor.LocalState, proposal.InitData)
+	stateNew, localStateNew, localStateHashNew, err := executeWithBudget(proposal, txCopied, state, oracle)
 	if err != nil {
 		return state, err
 	}
+	// voting params changed by proposal must be valid as by votingParams tx
+	if err := VotingParamsVerify(stateNew.VoteQuorum, stateNew.VoteThreshold, stateNew.VotingPeriod, stateNew.ProposerMinStake); err != nil {
+		log.Error("invalid voting params of state by proposal", "proposal", proposal.ID, "quorum", stateNew.VoteQuorum,
+			"threshold", stateNew.VoteThreshold, "period", stateNew.VotingPeriod, "proposerMinStake", stateNew.ProposerMinStake)
+		return state, err
+	}
+	if err := TokensVerify(stateNew.Tokens); err != nil {
+		return state, err
+	}
 	proposal.Executor.LocalState = localStateNew
 	proposal.Executor.LocalStateHash = localStateHashNew
 	return stateNew, nil
 }
 
+// proposalExecute execute proposal on current state, it is metered by budget from ProposalBudgetHeight
+func (h *HVM) proposalExecute(proposal *schema.Proposal, tx *schema.Transaction, oracle *schema.Oracle) (*schema.StateForProposal, error) {
+	if budget := proposal.Executor.Budget; budget != nil {
+		budget.Metered = int64(len(h.Executed)) >= ProposalBudgetHeight
+	}
+	return ProposalExecute(proposal, tx, h.GetStateForProposal(), oracle)
+}
+
 func FindProposal(proposals []*schema.Proposal, proposalID string) *schema.Proposal {
 	for _, proposal := range proposals {
 		if proposal.ID == proposalID {
@@ -83,3 +133,88 @@ func FindProposal(proposals []*schema.Proposal, proposalID string) *schema.Propo
 	}
 	return nil
 }
+
+type executeResult struct {
+	state          *schema.StateForProposal
+	localState     string
+	localStateHash string
+	err            error
+}
+
+// executeWithBudget run executor of proposal until it returns, exceeds max steps or timeout
+func executeWithBudget(proposal *schema.Proposal, tx *schema.Transaction, state *schema.StateForProposal, oracle *schema.Oracle) (*schema.StateForProposal, string, string, error) {
+	executor := proposal.Executor
+	r := runWithBudget(proposal.ID, executor.Budget, func() executeResult {
+		s, localState, localStateHash, err := executor.Execute(tx, state, oracle, executor.LocalState, proposal.InitData)
+		return executeResult{s, localState, localStateHash, err}
+	})
+	return r.state, r.localState, r.localStateHash, r.err
+}
+
+// runWithBudget run f of proposal until it returns or exceeds budget.
+// if it hit timeout, or heap limit for dry run, the execution fails and f is canceled at its next step.
+func runWithBudget(proposalID string, budget *schema.Budget, f func() executeResult) executeResult {
+	if budget == nil {
+		budget = schema.NewBudget(ProposalMaxSteps, ProposalMaxAlloc)
+	}
+	budget.Reset()
+
+	done := make(chan executeResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err := schema.ErrTxPanic
+				if r == schema.ErrBudgetExceeded {
+					err = schema.ErrBudgetExceeded
+				}
+				log.Error("proposal execute panic", "err", r, "proposal", proposalID, "steps", budget.Steps())
+				done <- executeResult{err: err}
+			}
+		}()
+		done <- f()
+	}()
+
+	timer := time.NewTimer(executeTimeout(budget))
+	defer timer.Stop()
+	// heap of process is not deterministic, consensus executions are not measured by it
+	var heapStart uint64
+	var heapTick <-chan time.Time
+	if budget.DryRun {
+		heapStart = heapBytes()
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		heapTick = ticker.C
+	}
+	for {
+		select {
+		case r := <-done:
+			return r
+		case <-heapTick:
+			if heap := heapBytes(); heap > heapStart && heap-heapStart > DryRunMaxHeap {
+				budget.Cancel()
+				log.Error("proposal execute out of memory", "proposal", proposalID, "heap", heap, "steps", budget.Steps())
+				return executeResult{err: schema.ErrProposalOutOfMemory}
+			}
+		case <-timer.C:
+			budget.Cancel()
+			log.Error("proposal execute timeout", "proposal", proposalID, "steps", budget.Steps())
+			return executeResult{err: schema.ErrProposalTimeout}
+		}
+	}
+}
+
+func executeTimeout(budget *schema.Budget) time.Duration {
+	if budget.DryRun {
+		return DryRunTimeout
+	}
+	return ProposalTimeout
+}
+
+func heapBytes() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,60 @@
+package hvm
+
+import (
+	"github.com/permadao/permaswap/halo/hvm/schema"
+)
+
+// proposalLogPrev return serialization of state before proposal executed, empty if proposal log is disabled.
+// serialization after the last proposal logged is reused, proposals are executed one by one.
+func (h *HVM) proposalLogPrev() string {
+	if !h.LogProposals {
+		return ""
+	}
+	if h.logPrev != "" {
+		return h.logPrev
+	}
+	return h.State.String()
+}
+
+func (h *HVM) logProposal(proposal *schema.Proposal, everHash, prev string, err error) {
+	if !h.LogProposals {
+		return
+	}
+	pl := &schema.ProposalLog{
+		ProposalID: proposal.ID,
+		EverHash:   everHash,
+		LocalState: proposal.Executor.LocalState,
+		Removed:    []string{},
+		Added:      []string{},
+	}
+	if err != nil {
+		// executed times of proposal is changed
+		pl.Error = err.Error()
+		h.logPrev = ""
+	} else {
+		cur := h.State.String()
+		pl.Removed, pl.Added = schema.DiffStateString(prev, cur)
+		h.logPrev = cur
+	}
+	h.ProposalLogs = append(h.ProposalLogs, pl)
+}
+
+// ProposalDryRun execute proposal by tx on state, state should be a copy of current state.
+// it is not a part of consensus and can run out of process of hvm.
+func ProposalDryRun(proposal *schema.Proposal, tx *schema.Transaction, state *schema.StateForProposal, oracle *schema.Oracle) *schema.ProposalLog {
+	pl := &schema.ProposalLog{
+		ProposalID: proposal.ID,
+		EverHash:   tx.EverHash,
+		Removed:    []string{},
+		Added:      []string{},
+	}
+	prev := state.String()
+	ns, err := ProposalExecute(proposal, tx, state, oracle)
+	pl.LocalState = proposal.Executor.LocalState
+	if err != nil {
+		pl.Error = err.Error()
+		return pl
+	}
+	pl.Removed, pl.Added = schema.DiffStateString(prev, ns.String())
+	return pl
+}
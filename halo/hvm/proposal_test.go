@@ -52,3 +52,72 @@ func TestProposal(t *testing.T) {
 	t.Log("Original state after execute:", state.Token.Balances)
 	t.Log("New state:", state2.Token.Balances)
 }
+
+func TestProposalDryRun(t *testing.T) {
+	executor, err := NewExecutor(source)
+	assert.NoError(t, err)
+	proposal := NewProposal("test", 0, 0, 1, source, "", nil, executor)
+	amount, _ := new(big.Int).SetString("100000000000000000000", 10)
+	h := New(schema.State{
+		FeeRecipient: "0x36da5367c7fC6f446ec9faC87Af73581cD3ADAe7",
+		Token: &token.Token{
+			Balances: map[string]*big.Int{
+				"ecosystem": amount,
+			},
+		},
+	})
+	stateHash := h.Hash()
+
+	pl := ProposalDryRun(proposal, &schema.Transaction{EverHash: "0x01"}, h.GetStateForProposal(), nil)
+	assert.Equal(t, "", pl.Error)
+	assert.Equal(t, proposal.ID, pl.ProposalID)
+	assert.Equal(t, "1234", pl.LocalState)
+	assert.Equal(t, 1, len(pl.Removed))
+	assert.Equal(t, 2, len(pl.Added))
+	// state of hvm not changed
+	assert.Equal(t, stateHash, h.Hash())
+	assert.Equal(t, amount, h.Token.Balances["ecosystem"])
+
+	// proposal log of executed tx
+	h.LogProposals = true
+	prev := h.proposalLogPrev()
+	ns, err := ProposalExecute(proposal, &schema.Transaction{EverHash: "0x02"}, h.GetStateForProposal(), nil)
+	assert.NoError(t, err)
+	h.UpdateState(ns)
+	h.logProposal(proposal, "0x02", prev, nil)
+	assert.Equal(t, 1, len(h.ProposalLogs))
+	assert.Equal(t, pl.Removed, h.ProposalLogs[0].Removed)
+	assert.Equal(t, pl.Added, h.ProposalLogs[0].Added)
+}
+
+func TestProposalLogCache(t *testing.T) {
+	executor, err := NewExecutor(source)
+	assert.NoError(t, err)
+	proposal := NewProposal("test", 0, 0, 0, source, "", nil, executor)
+	amount, _ := new(big.Int).SetString("100000000000000000000", 10)
+	h := New(schema.State{
+		FeeRecipient: "0x36da5367c7fC6f446ec9faC87Af73581cD3ADAe7",
+		Token: &token.Token{
+			Balances: map[string]*big.Int{
+				"ecosystem": amount,
+			},
+		},
+	})
+	h.LogProposals = true
+
+	// serialization after the last proposal is reused as prev of the next
+	for _, everHash := range []string{"0x01", "0x02"} {
+		prev := h.proposalLogPrev()
+		assert.Equal(t, h.State.String(), prev)
+		ns, err := ProposalExecute(proposal, &schema.Transaction{EverHash: everHash}, h.GetStateForProposal(), nil)
+		assert.NoError(t, err)
+		h.UpdateState(ns)
+		h.logProposal(proposal, everHash, prev, nil)
+	}
+	assert.Equal(t, h.State.String(), h.logPrev)
+
+	// failed execution changes executed times of proposal
+	h.logProposal(proposal, "0x03", h.proposalLogPrev(), schema.ErrTxPanic)
+	assert.Equal(t, "", h.logPrev)
+	assert.Equal(t, 3, len(h.ProposalLogs))
+}
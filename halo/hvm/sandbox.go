@@ -0,0 +1,438 @@
+package hvm
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/hvm/symbol"
+	"github.com/traefik/yaegi/stdlib"
+)
+
+// standard packages can be imported by proposal, packages with io, time or randomness are not allowed
+var AllowedStdPackages = []string{
+	"bytes",
+	"crypto/sha256",
+	"encoding/hex",
+	"encoding/json",
+	"errors",
+	"fmt",
+	"math",
+	"math/big",
+	"sort",
+	"strconv",
+	"strings",
+	"unicode",
+	"unicode/utf8",
+}
+
+// methods can allocate or compute unbounded by a small argument, proposal can not call them
+var ForbiddenMethods = []string{
+	"Exp",
+	"Lsh",
+	"SetBit",
+	"MulRange",
+	"Binomial",
+	"ModSqrt",
+	"ProbablyPrime",
+	"SetPrec",
+	"Grow",
+}
+
+var (
+	// max steps of an execution, a step is a function call or a loop iteration
+	ProposalMaxSteps int64 = 10000000
+	// max elements allocated by make, repeat, append, concatenation, formatting and big numbers of an execution
+	ProposalMaxAlloc int64 = 64 * 1024 * 1024
+	// wall-clock timeout of an execution is the last guard, it is not deterministic.
+	// max steps and max alloc are reached long before it, the tx fails if it is hit.
+	ProposalTimeout = 10 * time.Second
+	// count of executed txs from which proposal executions are metered by budget,
+	// executions of txs before it are not charged to keep their results.
+	ProposalBudgetHeight int64 = 0
+
+	// limits of dry run, it is requested by api and much smaller than execution of tx
+	DryRunMaxSteps int64 = 1000000
+	DryRunMaxAlloc int64 = 4 * 1024 * 1024
+	DryRunTimeout        = 2 * time.Second
+	// heap growth of process during a dry run, it is shared by all executions and only guards dry run
+	DryRunMaxHeap uint64 = 2 * 1024 * 1024 * 1024
+)
+
+const (
+	budgetPath  = "halo/budget"
+	budgetAlias = "hvmbudget"
+
+	sizedFunc    = "hvmSized"
+	appendedFunc = "hvmAppended"
+	// helpers charging values of proposal code, they are generic to keep types of values
+	budgetHelpers = `
+
+func ` + sizedFunc + `[T any](v T) T {
+	` + budgetAlias + `.Size(v)
+	return v
+}
+
+func ` + appendedFunc + `[T any](n int, v T) T {
+	` + budgetAlias + `.Appended(n, v)
+	return v
+}
+`
+)
+
+// methods of big numbers are charged by size of operands, results of formatting methods are charged by length
+var (
+	sizedArgMethods = []string{
+		"Add", "Sub", "Mul", "Quo", "Rem", "Div", "Mod", "QuoRem", "DivMod", "Sqrt", "Rsh",
+		"GCD", "ModInverse", "And", "Or", "Xor", "AndNot", "Not", "Neg", "Abs", "Set", "SetString", "SetFrac",
+	}
+	sizedResultMethods = []string{"String", "Text", "FloatString", "Bytes", "Append"}
+)
+
+// identifiers used by instrumented code, proposal can not declare them
+var (
+	reservedIdents = []string{budgetAlias, sizedFunc, appendedFunc}
+	builtinIdents  = []string{"len", "append", "make", "int"}
+)
+
+// sandboxSymbols return allowed symbols and budget symbols for interpreter
+func sandboxSymbols(budget *schema.Budget) map[string]map[string]reflect.Value {
+	symbols := map[string]map[string]reflect.Value{}
+	for key, syms := range stdlib.Symbols {
+		if InSlice(AllowedStdPackages, path.Dir(key)) {
+			symbols[key] = syms
+		}
+	}
+	for key, syms := range symbol.Symbols {
+		symbols[key] = syms
+	}
+	symbols[budgetPath+"/budget"] = map[string]reflect.Value{
+		"Step":     reflect.ValueOf(budget.Step),
+		"Alloc":    reflect.ValueOf(budget.Alloc),
+		"Size":     reflect.ValueOf(budget.Size),
+		"Appended": reflect.ValueOf(budget.Appended),
+	}
+
+	// repeat is charged by length of result
+	symbols["strings/strings"] = withSymbol(symbols["strings/strings"], "Repeat", func(s string, count int) string {
+		budget.Alloc(repeatLen(len(s), count))
+		return strings.Repeat(s, count)
+	})
+	symbols["bytes/bytes"] = withSymbol(symbols["bytes/bytes"], "Repeat", func(b []byte, count int) []byte {
+		budget.Alloc(repeatLen(len(b), count))
+		return bytes.Repeat(b, count)
+	})
+	// width and precision of verbs are charged before formatting
+	fmtSymbols := withSymbol(symbols["fmt/fmt"], "Sprintf", func(format string, a ...interface{}) string {
+		budget.Alloc(formatLen(format, a))
+		return fmt.Sprintf(format, a...)
+	})
+	symbols["fmt/fmt"] = withSymbol(fmtSymbols, "Errorf", func(format string, a ...interface{}) error {
+		budget.Alloc(formatLen(format, a))
+		return fmt.Errorf(format, a...)
+	})
+	return symbols
+}
+
+// withSymbol return a copy of symbols with name replaced by fn, stdlib symbols are shared and not modified
+func withSymbol(symbols map[string]reflect.Value, name string, fn interface{}) map[string]reflect.Value {
+	res := make(map[string]reflect.Value, len(symbols))
+	for k, v := range symbols {
+		res[k] = v
+	}
+	res[name] = reflect.ValueOf(fn)
+	return res
+}
+
+func repeatLen(n, count int) int {
+	if n > 0 && count > math.MaxInt/n {
+		return math.MaxInt
+	}
+	return n * count
+}
+
+// formatLen return length of format with widths and precisions of verbs,
+// integer args are added if width or precision is taken from args by '*'
+func formatLen(format string, args []interface{}) int {
+	n := len(format)
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		num := 0
+		for i++; i < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[i]) >= 0; i++ {
+			if c := format[i]; c >= '0' && c <= '9' {
+				if num < math.MaxInt32 {
+					num = num*10 + int(c-'0')
+				}
+				continue
+			}
+			n, num = addLen(n, num), 0
+		}
+		n = addLen(n, num)
+	}
+	if strings.Contains(format, "*") {
+		for _, arg := range args {
+			if v, ok := arg.(int); ok && v > 0 {
+				n = addLen(n, v)
+			}
+		}
+	}
+	return n
+}
+
+func addLen(n, m int) int {
+	if n > math.MaxInt-m {
+		return math.MaxInt
+	}
+	return n + m
+}
+
+func allowedImport(importPath string) bool {
+	if InSlice(AllowedStdPackages, importPath) {
+		return true
+	}
+	for key := range symbol.Symbols {
+		if path.Dir(key) == importPath {
+			return true
+		}
+	}
+	return false
+}
+
+// ProposalSourceVerify reject source imports forbidden packages, starts goroutines,
+// uses goto or channels which can not be limited by budget, calls forbidden methods,
+// or declares identifiers used by instrumented code
+func ProposalSourceVerify(source string) error {
+	f, err := parser.ParseFile(token.NewFileSet(), "", source, 0)
+	if err != nil {
+		return schema.ErrInvalidProposal
+	}
+	for _, imp := range f.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		if !allowedImport(importPath) {
+			log.Error("forbidden import of proposal", "import", importPath)
+			return schema.ErrForbiddenImport
+		}
+	}
+	pkgNames := importNames(f)
+
+	err = nil
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			err = schema.ErrForbiddenGoStmt
+		case *ast.BranchStmt:
+			if n.Tok == token.GOTO {
+				err = schema.ErrForbiddenStmt
+			}
+		case *ast.SelectStmt, *ast.SendStmt, *ast.ChanType:
+			err = schema.ErrForbiddenStmt
+		case *ast.UnaryExpr:
+			if n.Op == token.ARROW {
+				err = schema.ErrForbiddenStmt
+			}
+		case *ast.Ident:
+			if InSlice(reservedIdents, n.Name) || (n.Obj != nil && InSlice(builtinIdents, n.Name)) {
+				err = schema.ErrForbiddenStmt
+			}
+		case *ast.AssignStmt:
+			// lhs of += is evaluated again by instrumented code
+			if n.Tok == token.ADD_ASSIGN && !pureExpr(n.Lhs[0]) {
+				err = schema.ErrForbiddenStmt
+			}
+		case *ast.SelectorExpr:
+			// functions of packages are allowed, e.g. math.Exp
+			if isPkgIdent(n.X, pkgNames) {
+				break
+			}
+			if InSlice(ForbiddenMethods, n.Sel.Name) {
+				err = schema.ErrForbiddenCall
+			}
+		}
+		return err == nil
+	})
+	return err
+}
+
+// isPkgIdent return true if x is an imported package, identifiers declared in source are resolved by parser
+func isPkgIdent(x ast.Expr, pkgNames map[string]bool) bool {
+	ident, ok := x.(*ast.Ident)
+	return ok && ident.Obj == nil && pkgNames[ident.Name]
+}
+
+func importNames(f *ast.File) map[string]bool {
+	pkgNames := map[string]bool{}
+	for _, imp := range f.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		if imp.Name != nil {
+			pkgNames[imp.Name.Name] = true
+		} else {
+			pkgNames[path.Base(importPath)] = true
+		}
+	}
+	return pkgNames
+}
+
+// pureExpr return true if evaluating e again has no side effect
+func pureExpr(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.Ident, *ast.BasicLit:
+		return true
+	case *ast.ParenExpr:
+		return pureExpr(e.X)
+	case *ast.SelectorExpr:
+		return pureExpr(e.X)
+	case *ast.StarExpr:
+		return pureExpr(e.X)
+	case *ast.IndexExpr:
+		return pureExpr(e.X) && pureExpr(e.Index)
+	case *ast.UnaryExpr:
+		return e.Op != token.ARROW && pureExpr(e.X)
+	case *ast.BinaryExpr:
+		return pureExpr(e.X) && pureExpr(e.Y)
+	}
+	return false
+}
+
+// constExpr return true if e may be a constant, constants are not charged and keep untyped
+func constExpr(e ast.Expr, pkgNames map[string]bool) bool {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.Ident:
+		return e.Obj == nil || e.Obj.Kind == ast.Con
+	case *ast.ParenExpr:
+		return constExpr(e.X, pkgNames)
+	case *ast.SelectorExpr:
+		return isPkgIdent(e.X, pkgNames)
+	case *ast.UnaryExpr:
+		return constExpr(e.X, pkgNames)
+	case *ast.BinaryExpr:
+		// type of untyped constant shifted by variable is decided by context
+		if (e.Op == token.SHL || e.Op == token.SHR) && constExpr(e.X, pkgNames) {
+			return true
+		}
+		return constExpr(e.X, pkgNames) && constExpr(e.Y, pkgNames)
+	}
+	return false
+}
+
+// instrumentSource insert budget step at the beginning of every function and loop body,
+// charge budget for size arguments of make, results of concatenation and append,
+// operands of big number arithmetic and results of formatting methods
+func instrumentSource(source string) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", source, 0)
+	if err != nil {
+		return "", err
+	}
+	offset := func(p token.Pos) int {
+		return fset.Position(p).Offset
+	}
+	text := func(e ast.Expr) string {
+		return source[offset(e.Pos()):offset(e.End())]
+	}
+	pkgNames := importNames(f)
+
+	// text is inserted at offset, source is replaced until end if it is set
+	type insertion struct {
+		offset int
+		end    int
+		text   string
+	}
+	insertions := []insertion{}
+	wrap := func(e ast.Expr, open string) {
+		insertions = append(insertions, insertion{offset: offset(e.Pos()), text: open}, insertion{offset: offset(e.End()), text: ")"})
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		var body *ast.BlockStmt
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			body = n.Body
+		case *ast.FuncLit:
+			body = n.Body
+		case *ast.ForStmt:
+			body = n.Body
+		case *ast.RangeStmt:
+			body = n.Body
+		case *ast.GenDecl:
+			// constants are not charged
+			return n.Tok != token.CONST
+		case *ast.BinaryExpr:
+			if n.Op == token.ADD && !constExpr(n, pkgNames) {
+				wrap(n, sizedFunc+"(")
+			}
+		case *ast.AssignStmt:
+			// lhs += rhs is replaced by lhs = hvmSized(lhs + (rhs))
+			if n.Tok == token.ADD_ASSIGN {
+				insertions = append(insertions,
+					insertion{offset: offset(n.TokPos), end: offset(n.TokPos) + len(token.ADD_ASSIGN.String()),
+						text: "= " + sizedFunc + "(" + text(n.Lhs[0]) + " + ("},
+					insertion{offset: offset(n.Rhs[0].End()), text: "))"})
+			}
+		case *ast.CallExpr:
+			switch fun := n.Fun.(type) {
+			case *ast.Ident:
+				if fun.Obj != nil {
+					break
+				}
+				if fun.Name == "make" && len(n.Args) > 1 {
+					for _, arg := range n.Args[1:] {
+						wrap(arg, budgetAlias+".Alloc(int(")
+						insertions = append(insertions, insertion{offset: offset(arg.End()), text: ")"})
+					}
+				}
+				if fun.Name == "append" && len(n.Args) > 0 {
+					if pureExpr(n.Args[0]) {
+						wrap(n, appendedFunc+"(len("+text(n.Args[0])+"), ")
+					} else {
+						wrap(n, sizedFunc+"(")
+					}
+				}
+			case *ast.SelectorExpr:
+				if isPkgIdent(fun.X, pkgNames) {
+					break
+				}
+				if InSlice(sizedArgMethods, fun.Sel.Name) {
+					for _, arg := range n.Args {
+						if !constExpr(arg, pkgNames) {
+							wrap(arg, sizedFunc+"(")
+						}
+					}
+				}
+				if InSlice(sizedResultMethods, fun.Sel.Name) {
+					wrap(n, sizedFunc+"(")
+				}
+			}
+		}
+		if body != nil {
+			insertions = append(insertions, insertion{offset: offset(body.Lbrace) + 1, text: budgetAlias + ".Step();"})
+		}
+		return true
+	})
+	sort.SliceStable(insertions, func(i, j int) bool {
+		return insertions[i].offset < insertions[j].offset
+	})
+
+	last := offset(f.Name.End())
+	res := source[:last] + "; import " + budgetAlias + " " + strconv.Quote(budgetPath)
+	for _, ins := range insertions {
+		res += source[last:ins.offset] + ins.text
+		last = ins.offset
+		if ins.end > last {
+			last = ins.end
+		}
+	}
+	return res + source[last:] + budgetHelpers, nil
+}
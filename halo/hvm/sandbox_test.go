@@ -0,0 +1,255 @@
+package hvm
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/token"
+	"github.com/stretchr/testify/assert"
+)
+
+const loopSource = `package proposal
+
+import (
+	"github.com/permadao/permaswap/halo/hvm/schema"
+)
+
+func loop(n int) int {
+	for {
+		n++
+	}
+	return n
+}
+
+func Execute(tx *schema.Transaction, state *schema.StateForProposal, oracle *schema.Oracle, localState string, initData string) (*schema.StateForProposal, string, string, error) {
+	if initData == "loop" {
+		loop(0)
+	}
+	return state, localState + "1", "", nil
+}
+`
+
+func TestProposalSourceVerify(t *testing.T) {
+	assert.NoError(t, ProposalSourceVerify(source))
+	assert.NoError(t, ProposalSourceVerify(loopSource))
+
+	for _, imp := range []string{"os", "time", "net/http", "math/rand", "unsafe", "github.com/traefik/yaegi/stdlib"} {
+		src := "package proposal\n\nimport \"" + imp + "\"\n"
+		assert.Equal(t, schema.ErrForbiddenImport, ProposalSourceVerify(src), imp)
+	}
+
+	src := "package proposal\n\nfunc f() {\n\tgo func() {}()\n}\n"
+	assert.Equal(t, schema.ErrForbiddenGoStmt, ProposalSourceVerify(src))
+
+	for _, body := range []string{
+		"i := 0\nL:\n\ti++\n\tgoto L",
+		"select {}",
+		"var c chan int\n\t_ = c",
+		"c := make(chan int)\n\tc <- 1",
+	} {
+		src := "package proposal\n\nfunc f() {\n\t" + body + "\n}\n"
+		assert.Equal(t, schema.ErrForbiddenStmt, ProposalSourceVerify(src), body)
+	}
+
+	src = "package proposal\n\nimport \"math/big\"\n\nfunc f() {\n\tnew(big.Int).Exp(big.NewInt(2), big.NewInt(1<<40), nil)\n}\n"
+	assert.Equal(t, schema.ErrForbiddenCall, ProposalSourceVerify(src))
+	src = "package proposal\n\nimport \"math\"\n\nvar _ = math.Exp(1)\n"
+	assert.NoError(t, ProposalSourceVerify(src))
+	// local variable named as package is not a package
+	src = "package proposal\n\nimport (\n\t\"math\"\n\t\"math/big\"\n)\n\nvar _ = math.Pi\n\nfunc f() {\n\tmath := new(big.Int)\n\tmath.Exp(big.NewInt(2), big.NewInt(1<<40), nil)\n}\n"
+	assert.Equal(t, schema.ErrForbiddenCall, ProposalSourceVerify(src))
+
+	// identifiers of instrumented code can not be declared, lhs of += must not have side effect
+	for _, body := range []string{
+		"hvmSized := 1\n\t_ = hvmSized",
+		"hvmbudget := 1\n\t_ = hvmbudget",
+		"len := func(s string) int { return 0 }\n\t_ = len(\"\")",
+		"m := map[int]string{}\n\tm[g()] += \"a\"",
+	} {
+		src := "package proposal\n\nfunc g() int { return 0 }\n\nfunc f() {\n\t" + body + "\n}\n"
+		assert.Equal(t, schema.ErrForbiddenStmt, ProposalSourceVerify(src), body)
+	}
+
+	// forbidden packages are not in sandbox
+	_, err := NewExecutor("package proposal\n\nimport \"os\"\n\nvar _ = os.Args\n")
+	assert.Error(t, err)
+}
+
+func TestExecuteWithBudget(t *testing.T) {
+	maxSteps := ProposalMaxSteps
+	defer func() { ProposalMaxSteps = maxSteps }()
+	ProposalMaxSteps = 10000
+
+	executor, err := NewExecutor(loopSource)
+	assert.NoError(t, err)
+	proposal := NewProposal("loop", 0, 0, 0, loopSource, "", nil, executor)
+	state := &schema.StateForProposal{}
+
+	_, localState, _, err := executeWithBudget(proposal, &schema.Transaction{}, state, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", localState)
+	// a call and a concatenation
+	assert.Equal(t, int64(2), executor.Budget.Steps())
+
+	proposal.InitData = "loop"
+	_, _, _, err = executeWithBudget(proposal, &schema.Transaction{}, state, nil)
+	assert.Equal(t, schema.ErrBudgetExceeded, err)
+
+	// budget is reset for next execution
+	proposal.InitData = ""
+	_, _, _, err = executeWithBudget(proposal, &schema.Transaction{}, state, nil)
+	assert.NoError(t, err)
+}
+
+const allocSource = `package proposal
+
+import (
+	"strings"
+
+	"github.com/permadao/permaswap/halo/hvm/schema"
+)
+
+func Execute(tx *schema.Transaction, state *schema.StateForProposal, oracle *schema.Oracle, localState string, initData string) (*schema.StateForProposal, string, string, error) {
+	switch initData {
+	case "make":
+		_ = make([]int64, 1<<40)
+	case "repeat":
+		_ = strings.Repeat("a", 1<<40)
+	default:
+		_ = make([]byte, 0, 1024)
+	}
+	return state, localState, "", nil
+}
+`
+
+func TestExecuteAlloc(t *testing.T) {
+	executor, err := NewExecutor(allocSource)
+	assert.NoError(t, err)
+	proposal := NewProposal("alloc", 0, 0, 0, allocSource, "", nil, executor)
+	state := &schema.StateForProposal{}
+
+	_, _, _, err = executeWithBudget(proposal, &schema.Transaction{}, state, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024), executor.Budget.Allocated())
+
+	for _, initData := range []string{"make", "repeat"} {
+		proposal.InitData = initData
+		_, _, _, err = executeWithBudget(proposal, &schema.Transaction{}, state, nil)
+		assert.Equal(t, schema.ErrBudgetExceeded, err, initData)
+	}
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	maxSteps, timeout := ProposalMaxSteps, ProposalTimeout
+	dryRunMaxSteps, dryRunTimeout := DryRunMaxSteps, DryRunTimeout
+	defer func() {
+		ProposalMaxSteps, ProposalTimeout = maxSteps, timeout
+		DryRunMaxSteps, DryRunTimeout = dryRunMaxSteps, dryRunTimeout
+	}()
+	ProposalMaxSteps, DryRunMaxSteps = 1<<62, 1<<62
+	ProposalTimeout, DryRunTimeout = 100*time.Millisecond, 100*time.Millisecond
+
+	// timeout fails the tx instead of halting the node
+	executor, err := NewExecutor(loopSource)
+	assert.NoError(t, err)
+	proposal := NewProposal("loop", 0, 0, 0, loopSource, "loop", nil, executor)
+	_, _, _, err = executeWithBudget(proposal, &schema.Transaction{}, &schema.StateForProposal{}, nil)
+	assert.Equal(t, schema.ErrProposalTimeout, err)
+
+	executor, err = NewDryRunExecutor(loopSource)
+	assert.NoError(t, err)
+	proposal = NewProposal("loop", 0, 0, 0, loopSource, "loop", nil, executor)
+	_, _, _, err = executeWithBudget(proposal, &schema.Transaction{}, &schema.StateForProposal{}, nil)
+	assert.Equal(t, schema.ErrProposalTimeout, err)
+
+	// dry run has a smaller budget
+	DryRunMaxSteps, DryRunTimeout = dryRunMaxSteps, dryRunTimeout
+	executor, err = NewDryRunExecutor(loopSource)
+	assert.NoError(t, err)
+	proposal = NewProposal("loop", 0, 0, 0, loopSource, "loop", nil, executor)
+	_, _, _, err = executeWithBudget(proposal, &schema.Transaction{}, &schema.StateForProposal{}, nil)
+	assert.Equal(t, schema.ErrBudgetExceeded, err)
+}
+
+const growSource = `package proposal
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/permadao/permaswap/halo/hvm/schema"
+)
+
+type name string
+
+const unit = 1 + 2
+
+func Execute(tx *schema.Transaction, state *schema.StateForProposal, oracle *schema.Oracle, localState string, initData string) (*schema.StateForProposal, string, string, error) {
+	s, b, n, x := "a", []byte("a"), name("a"), big.NewInt(3)
+	for i := 0; i < 64; i++ {
+		switch initData {
+		case "concat":
+			s = s + s
+		case "assign":
+			s += s
+		case "named":
+			n += n
+		case "append":
+			b = append(b, b...)
+		case "big":
+			x.Mul(x, x)
+		case "format":
+			s = fmt.Sprintf("%1000000000d", i)
+		default:
+			if i == 1 {
+				var f float64 = 1
+				f += unit
+				var u int64 = 2
+				u = u + 1
+				return state, fmt.Sprint(s+"b", f, u, len(append(b, 'b')), n+"b"), "", nil
+			}
+		}
+	}
+	return state, localState, "", nil
+}
+`
+
+func TestExecuteGrow(t *testing.T) {
+	executor, err := NewExecutor(growSource)
+	assert.NoError(t, err)
+	proposal := NewProposal("grow", 0, 0, 0, growSource, "", nil, executor)
+
+	_, localState, _, err := executeWithBudget(proposal, &schema.Transaction{}, &schema.StateForProposal{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "ab4 3 2ab", localState)
+
+	// values doubled or formatted with a large width are charged by size
+	for _, initData := range []string{"concat", "assign", "named", "append", "big", "format"} {
+		proposal.InitData = initData
+		_, _, _, err = executeWithBudget(proposal, &schema.Transaction{}, &schema.StateForProposal{}, nil)
+		assert.Equal(t, schema.ErrBudgetExceeded, err, initData)
+	}
+}
+
+func TestProposalBudgetHeight(t *testing.T) {
+	height := ProposalBudgetHeight
+	defer func() { ProposalBudgetHeight = height }()
+	ProposalBudgetHeight = 1
+
+	executor, err := NewExecutor(loopSource)
+	assert.NoError(t, err)
+	proposal := NewProposal("loop", 0, 0, 0, loopSource, "", nil, executor)
+	h := New(schema.State{Token: &token.Token{Balances: map[string]*big.Int{}}})
+
+	// executions before activation are not charged
+	_, err = h.proposalExecute(proposal, &schema.Transaction{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), executor.Budget.Steps())
+
+	h.Executed = append(h.Executed, "0x01")
+	_, err = h.proposalExecute(proposal, &schema.Transaction{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), executor.Budget.Steps())
+}
@@ -0,0 +1,98 @@
+package hvm
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/robfig/cron/v3"
+)
+
+// ScheduleVerify verify cron expression of proposal schedule, standard 5 fields or descriptors such as @daily and @every 1h
+func ScheduleVerify(schedule string) error {
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		log.Error("invalid schedule", "schedule", schedule, "err", err)
+		return schema.ErrInvalidSchedule
+	}
+	return nil
+}
+
+// activateSchedule start schedule of proposal activated at now, the first scheduled time is after start of proposal
+func activateSchedule(proposal *schema.Proposal, now int64) {
+	if proposal.Schedule == "" {
+		return
+	}
+	proposal.LastScheduled = now
+	if proposal.Start > now {
+		proposal.LastScheduled = proposal.Start
+	}
+}
+
+// dueTime return the latest scheduled time of proposal in (lastScheduled, now], executions missed are coalesced.
+// end of proposal is the last scheduled time. return 0 if not due.
+func dueTime(proposal *schema.Proposal, now int64) int64 {
+	limit := now
+	if proposal.End > 0 && proposal.End < limit {
+		limit = proposal.End
+	}
+	if limit <= proposal.LastScheduled {
+		return 0
+	}
+	if proposal.End > 0 && proposal.End <= now {
+		return proposal.End
+	}
+
+	sched, err := cron.ParseStandard(proposal.Schedule)
+	if err != nil {
+		return 0
+	}
+	due := int64(0)
+	for t := time.Unix(proposal.LastScheduled, 0).UTC(); ; {
+		t = sched.Next(t)
+		if t.IsZero() || t.Unix() > limit {
+			break
+		}
+		due = t.Unix()
+	}
+	return due
+}
+
+// runSchedules execute scheduled proposals due at now, the clock is the timestamp of everpay tx.
+// proposals receive a tick tx whose nonce and timestamp are the scheduled time in milliseconds.
+func (h *HVM) runSchedules(tx schema.Transaction, now int64, oracle *schema.Oracle) {
+	for _, proposal := range h.Proposals {
+		if proposal.Schedule == "" {
+			continue
+		}
+		due := dueTime(proposal, now)
+		if due == 0 {
+			continue
+		}
+		proposal.LastScheduled = due
+		h.logPrev = ""
+		if proposal.RunTimes > 0 && proposal.Executor.RunnedTimes >= proposal.RunTimes {
+			continue
+		}
+
+		tick := schema.Transaction{
+			Dapp:      h.Dapp,
+			ChainID:   h.ChainID,
+			EverHash:  tx.EverHash,
+			Router:    tx.Router,
+			Action:    schema.TxActionTick,
+			Nonce:     strconv.FormatInt(due*1000, 10),
+			Version:   schema.TxVersionV1,
+			Timestamp: due * 1000,
+		}
+		log.Debug("execute scheduled proposal", "ID", proposal.ID, "name", proposal.Name, "due", due, "tx", tx.EverHash)
+		prev := h.proposalLogPrev()
+		ns, err := h.proposalExecute(proposal, &tick, oracle)
+		if err != nil {
+			log.Error("execute scheduled proposal failed", "ID", proposal.ID, "name", proposal.Name, "due", due, "err", err)
+			h.logProposal(proposal, tx.EverHash, prev, err)
+			continue
+		}
+		h.UpdateState(ns)
+		h.logProposal(proposal, tx.EverHash, prev, nil)
+	}
+}
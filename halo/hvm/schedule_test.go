@@ -0,0 +1,130 @@
+package hvm
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/everFinance/goether"
+	"github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/token"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleVerify(t *testing.T) {
+	assert.NoError(t, ScheduleVerify("0 0 * * 1"))
+	assert.NoError(t, ScheduleVerify("@every 1h"))
+	assert.NoError(t, ScheduleVerify("@daily"))
+	assert.Equal(t, schema.ErrInvalidSchedule, ScheduleVerify("* * *"))
+	assert.Equal(t, schema.ErrInvalidSchedule, ScheduleVerify("every hour"))
+}
+
+func TestDueTime(t *testing.T) {
+	// 2023-03-28 10:40:00 utc
+	start := int64(1680000000)
+	p := &schema.Proposal{Schedule: "0 * * * *"}
+	activateSchedule(p, start)
+	assert.Equal(t, start, p.LastScheduled)
+
+	assert.Equal(t, int64(0), dueTime(p, start+10*60))
+	assert.Equal(t, start+20*60, dueTime(p, start+30*60))
+	// missed executions are coalesced to the latest
+	assert.Equal(t, start+20*60+4*3600, dueTime(p, start+5*3600))
+
+	// end is the last scheduled time
+	p.End = start + 2*3600
+	assert.Equal(t, p.End, dueTime(p, start+5*3600))
+	p.LastScheduled = p.End
+	assert.Equal(t, int64(0), dueTime(p, start+5*3600))
+
+	// schedule starts from start of proposal
+	p = &schema.Proposal{Schedule: "@every 1h", Start: start + 3600}
+	activateSchedule(p, start)
+	assert.Equal(t, start+3600, p.LastScheduled)
+	assert.Equal(t, int64(0), dueTime(p, start+2*3600-1))
+	assert.Equal(t, start+2*3600, dueTime(p, start+2*3600))
+}
+
+func TestRunSchedules(t *testing.T) {
+	executor, err := NewExecutor(counterSource)
+	assert.NoError(t, err)
+	proposal := NewProposal("counter", 0, 0, 0, counterSource, "", nil, executor)
+	id := proposal.ID
+	proposal.Schedule = "@every 1h"
+	proposal.ID = proposal.HexHash()
+	assert.NotEqual(t, id, proposal.ID)
+
+	start := int64(1680000000)
+	activateSchedule(proposal, start)
+	h := New(schema.State{Token: &token.Token{}, Proposals: []*schema.Proposal{proposal}})
+	h.LogProposals = true
+	stateHash := h.Hash()
+
+	h.runSchedules(schema.Transaction{EverHash: "0x01"}, start+1800, nil)
+	assert.Equal(t, "", proposal.Executor.LocalState)
+	assert.Equal(t, 0, len(h.ProposalLogs))
+
+	h.runSchedules(schema.Transaction{EverHash: "0x02"}, start+3*3600+1, nil)
+	assert.Equal(t, "1", proposal.Executor.LocalState)
+	assert.Equal(t, start+3*3600, proposal.LastScheduled)
+	assert.Equal(t, 1, len(h.ProposalLogs))
+	assert.Equal(t, "0x02", h.ProposalLogs[0].EverHash)
+	assert.NotEqual(t, stateHash, h.Hash())
+
+	// tx with an earlier nonce does not move the clock back
+	h.runSchedules(schema.Transaction{EverHash: "0x03"}, start+3600, nil)
+	assert.Equal(t, "1", proposal.Executor.LocalState)
+	assert.Equal(t, start+3*3600, proposal.LastScheduled)
+}
+
+func TestSchedulesClock(t *testing.T) {
+	signer, err := goether.NewSigner("4c0a4ac0d5d5b5ae2c4a12a1b8b8ec1d2e5f6d4e3f9c8a7b6c5d4e3f2a1b0c9d")
+	assert.NoError(t, err)
+	from := signer.Address.String()
+
+	executor, err := NewExecutor(counterSource)
+	assert.NoError(t, err)
+	proposal := NewProposal("counter", 0, 0, 0, counterSource, "", []string{schema.TxActionTick}, executor)
+	proposal.Schedule = "@every 1h"
+	start := int64(1680000000)
+	activateSchedule(proposal, start)
+	h := New(schema.State{
+		Dapp:         "halo",
+		ChainID:      "1",
+		FeeRecipient: "0xfee",
+		Routers:      []string{"0xrouter"},
+		Token:        token.New("HALO", 18, big.NewInt(1000), map[string]*big.Int{from: big.NewInt(10)}, nil),
+		Proposals:    []*schema.Proposal{proposal},
+	})
+
+	execute := func(nonce, timestamp int64) {
+		tx := schema.Transaction{
+			Dapp:         "halo",
+			ChainID:      "1",
+			Router:       "0xrouter",
+			Action:       schema.TxActionTransfer,
+			From:         from,
+			Fee:          "0",
+			FeeRecipient: "0xfee",
+			Nonce:        strconv.FormatInt(nonce, 10),
+			Version:      schema.TxVersionV1,
+			Params:       `{"To":"0x36da5367c7fC6f446ec9faC87Af73581cD3ADAe7","Amount":"1"}`,
+		}
+		sig, err := signer.SignMsg([]byte(tx.String()))
+		assert.NoError(t, err)
+		tx.Sig = hexutil.Encode(sig)
+		tx.EverHash = tx.HexHash()
+		tx.Timestamp = timestamp
+		assert.NoError(t, h.ExecuteTx(tx))
+	}
+
+	// nonce chosen by sender does not make schedules due
+	execute((start+10*3600)*1000, (start+1800)*1000)
+	assert.Equal(t, "", proposal.Executor.LocalState)
+	assert.Equal(t, start, proposal.LastScheduled)
+
+	execute((start+10*3600)*1000+1, (start+3600)*1000)
+	assert.Equal(t, "1", proposal.Executor.LocalState)
+	assert.Equal(t, start+3600, proposal.LastScheduled)
+}
@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"math/big"
+	"reflect"
+	"sync/atomic"
+)
+
+// steps charged for every allocUnit elements allocated by proposal
+const allocUnit = 1024
+
+// Budget limits steps and allocations of proposal execution, a step is a function call or a loop iteration
+type Budget struct {
+	MaxSteps int64
+	MaxAlloc int64
+	// execution of dry run is not a part of consensus, it has smaller limits of node
+	DryRun bool
+	// steps and allocations are only charged if metered, executions before activation of budget are not metered
+	Metered bool
+
+	steps    int64
+	alloc    int64
+	canceled int32
+}
+
+func NewBudget(maxSteps, maxAlloc int64) *Budget {
+	return &Budget{MaxSteps: maxSteps, MaxAlloc: maxAlloc, Metered: true}
+}
+
+// Step is called by proposal code, panic with ErrBudgetExceeded if steps exceed or budget is canceled
+func (b *Budget) Step() {
+	b.charge(1)
+}
+
+// Alloc is called by proposal code before allocating n elements, it returns n.
+// panic with ErrBudgetExceeded if allocations exceed.
+func (b *Budget) Alloc(n int) int {
+	if n > 0 && b.Metered {
+		b.alloc += int64(n)
+		if b.alloc > b.MaxAlloc || b.alloc < 0 {
+			panic(ErrBudgetExceeded)
+		}
+	}
+	b.charge(int64(n)/allocUnit + 1)
+	return n
+}
+
+// Size is called by proposal code with result of concatenation or operands of big number arithmetic.
+// strings and slices are charged by length, big numbers by bytes and steps quadratic in words.
+func (b *Budget) Size(v interface{}) {
+	words := 0
+	switch v := v.(type) {
+	case *big.Int:
+		if v != nil {
+			words = len(v.Bits())
+		}
+	case *big.Rat:
+		if v != nil {
+			words = len(v.Num().Bits()) + len(v.Denom().Bits())
+		}
+	case *big.Float:
+		if v != nil {
+			words = int(v.MinPrec()/64) + 1
+		}
+	default:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.String || rv.Kind() == reflect.Slice {
+			b.Alloc(rv.Len())
+		}
+		return
+	}
+	b.Alloc(words * 8)
+	b.charge(int64(words) * int64(words) / allocUnit)
+}
+
+// Appended is called by proposal code with result of append and length of slice appended to,
+// elements appended are charged
+func (b *Budget) Appended(n int, v interface{}) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.Len() > n {
+		b.Alloc(rv.Len() - n)
+	}
+}
+
+func (b *Budget) charge(steps int64) {
+	if b.Metered {
+		b.steps += steps
+	}
+	if b.steps > b.MaxSteps || atomic.LoadInt32(&b.canceled) == 1 {
+		panic(ErrBudgetExceeded)
+	}
+}
+
+func (b *Budget) Steps() int64 {
+	return b.steps
+}
+
+func (b *Budget) Allocated() int64 {
+	return b.alloc
+}
+
+// Reset reset steps and allocations before execution
+func (b *Budget) Reset() {
+	b.steps = 0
+	b.alloc = 0
+	atomic.StoreInt32(&b.canceled, 0)
+}
+
+// Cancel stop the execution at next step, called when execution hit limits of node
+func (b *Budget) Cancel() {
+	atomic.StoreInt32(&b.canceled, 1)
+}
@@ -26,4 +26,18 @@ var (
 	ErrInvalidAccountType   = errors.New("err_invalid_account_type")
 	ErrNoPoolFound          = errors.New("err_no_pool_found")
 	ErrNoTokenFound         = errors.New("err_no_token_found")
+	ErrNoVotingFound        = errors.New("err_no_voting_found")
+	ErrVotingClosed         = errors.New("err_voting_closed")
+	ErrInvalidVoteOption    = errors.New("err_invalid_vote_option")
+	ErrNoVotingPower        = errors.New("err_no_voting_power")
+	ErrInvalidVotingParams  = errors.New("err_invalid_voting_params")
+	ErrForbiddenImport      = errors.New("err_forbidden_import")
+	ErrForbiddenGoStmt      = errors.New("err_forbidden_go_stmt")
+	ErrForbiddenStmt        = errors.New("err_forbidden_stmt")
+	ErrForbiddenCall        = errors.New("err_forbidden_call")
+	ErrBudgetExceeded       = errors.New("err_budget_exceeded")
+	ErrProposalTimeout      = errors.New("err_proposal_timeout")
+	ErrProposalOutOfMemory  = errors.New("err_proposal_out_of_memory")
+	ErrInvalidSchedule      = errors.New("err_invalid_schedule")
+	ErrInvalidTokenInfo     = errors.New("err_invalid_token_info")
 )
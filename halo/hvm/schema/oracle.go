@@ -0,0 +1,48 @@
+package schema
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	everSchema "github.com/everVision/everpay-kits/schema"
+)
+
+// Oracle is the external data for a tx, it is derived from consensus state so that every node and replay get the same
+type Oracle struct {
+	EverTokens map[string]everSchema.TokenInfo `json:"everTokens"`
+	Prices     map[string]string               `json:"prices,omitempty"` // token tag -> price in usd, decimal string
+}
+
+// TokenInfo is info of a token of pools, it is set by genesis or proposals registering pools
+type TokenInfo struct {
+	Tag      string `json:"tag"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+// GetPrice return price of token in usd
+func (o *Oracle) GetPrice(tokenTag string) (*big.Float, bool) {
+	if o == nil || o.Prices == nil {
+		return nil, false
+	}
+	p, ok := o.Prices[tokenTag]
+	if !ok {
+		return nil, false
+	}
+	return new(big.Float).SetString(p)
+}
+
+// String is the canonical serialization of oracle, keys of maps are sorted by json
+func (o *Oracle) String() string {
+	by, err := json.Marshal(o)
+	if err != nil {
+		return ""
+	}
+	return string(by)
+}
+
+func (o *Oracle) Hash() string {
+	return hexutil.Encode(accounts.TextHash([]byte(o.String())))
+}
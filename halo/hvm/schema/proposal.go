@@ -6,19 +6,17 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	everSchema "github.com/everVision/everpay-kits/schema"
 )
 
-type Oracle struct {
-	EverTokens map[string]everSchema.TokenInfo
-}
-
 type Executor struct {
 	LocalState     string `json:"localState"`
 	LocalStateHash string `json:"localStateHash"`
 	RunnedTimes    int64  `json:"runnedTimes"`
 	// in: tx, state, oracle, localState, initData out: state, localState, localStateHash error
 	Execute func(*Transaction, *StateForProposal, *Oracle, string, string) (*StateForProposal, string, string, error) `json:"-"`
+	// optional, in: localState of old source, initData out: localState, localStateHash, error
+	Migrate func(string, string) (string, string, error) `json:"-"`
+	Budget  *Budget                                      `json:"-"`
 }
 
 type Proposal struct {
@@ -30,6 +28,8 @@ type Proposal struct {
 	Source                string   `json:"source"`
 	InitData              string   `json:"initData"`
 	OnlyAcceptedTxActions []string `json:"onlyAcceptedTxActions"`
+	Schedule              string   `json:"schedule,omitempty"`      // cron expression in utc, proposal is executed by deterministic clock of txs
+	LastScheduled         int64    `json:"lastScheduled,omitempty"` // seconds of the last scheduled time executed
 
 	ExecutedTxs map[string]string `json:"executedTxs"`
 	Executor    *Executor         `json:"executor"`
@@ -37,13 +37,18 @@ type Proposal struct {
 
 func (p *Proposal) String() string {
 	onlyAcceptedTxActions := strings.Join(p.OnlyAcceptedTxActions, ",")
-	return "name:" + p.Name + "\n" +
+	str := "name:" + p.Name + "\n" +
 		"start:" + strconv.FormatInt(p.Start, 10) + "\n" +
 		"end:" + strconv.FormatInt(p.End, 10) + "\n" +
 		"runTimes:" + strconv.FormatInt(p.RunTimes, 10) + "\n" +
 		"source:" + p.Source + "\n" +
 		"initData:" + p.InitData + "\n" +
 		"onlyAcceptedTxActions:" + onlyAcceptedTxActions + "\n"
+	// id of proposals without schedule is not changed
+	if p.Schedule != "" {
+		str += "schedule:" + p.Schedule + "\n"
+	}
+	return str
 }
 
 func (p *Proposal) Hash() []byte {
@@ -53,3 +58,13 @@ func (p *Proposal) Hash() []byte {
 func (p *Proposal) HexHash() string {
 	return hexutil.Encode(p.Hash())
 }
+
+// ProposalLog is the result of proposal executed by a tx, removed and added are the diff of state serialization
+type ProposalLog struct {
+	ProposalID string   `json:"proposalID"`
+	EverHash   string   `json:"everHash"`
+	Error      string   `json:"error"`
+	LocalState string   `json:"localState"`
+	Removed    []string `json:"removed"`
+	Added      []string `json:"added"`
+}
@@ -2,6 +2,12 @@ package schema
 
 import (
 	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 
 	"github.com/permadao/permaswap/halo/account"
 	"github.com/permadao/permaswap/halo/token"
@@ -28,10 +34,23 @@ type State struct {
 	Govern       string `json:"govern"` // govern is an temporary solution and will use voting in the future
 	FeeRecipient string `json:"feeRecipient"`
 
+	// voting by stakers, govern decides proposals if voting period is 0
+	VoteQuorum    string    `json:"voteQuorum"`      // ratio of total staked which must vote
+	VoteThreshold string    `json:"voteThreshold"`   // ratio of yes in votes must be greater than
+	VotingPeriod  int64     `json:"votingPeriod"`    // seconds of voting window
+	Votings       []*Voting `json:"votings"`         // votings in progress
+	Clock         int64     `json:"clock,omitempty"` // seconds of the latest everpay tx timestamp, it never goes back
+
+	ProposerMinStake string `json:"proposerMinStake,omitempty"` // minum amount staker stake to propose if voting is enabled
+
 	RouterMinStake string                  `json:"routerMinStake"` // minum amount router stake
 	Routers        []string                `json:"routers"`        // [] router address
 	RouterStates   map[string]*RouterState `json:"routerState"`    // router address -> router state
 
+	Tokens       map[string]*TokenInfo        `json:"tokens,omitempty"`       // token tag -> info of token of pools
+	RouterPrices map[string]map[string]string `json:"routerPrices,omitempty"` // router address -> token tag -> usd price reported in tick txs
+	Prices       map[string]string            `json:"prices,omitempty"`       // token tag -> median of prices reported by routers
+
 	Token    *token.Token                `json:"-"`       // halo token
 	Accounts map[string]*account.Account `json:"account"` // account ID -> account
 
@@ -56,9 +75,16 @@ type StateForProposal struct {
 	Govern       string `json:"govern"` // govern is an temporary solution and will use voting in the future
 	FeeRecipient string `json:"feeRecipient"`
 
+	VoteQuorum    string `json:"voteQuorum"`
+	VoteThreshold string `json:"voteThreshold"`
+	VotingPeriod  int64  `json:"votingPeriod"`
+
+	ProposerMinStake string `json:"proposerMinStake,omitempty"`
+
 	RouterMinStake string                  `json:"minRouterStake"` // minum amount router stake
 	Routers        []string                `json:"routers"`
 	RouterStates   map[string]*RouterState `json:"routerState"` // router id -> router state
+	Tokens         map[string]*TokenInfo   `json:"tokens"`      // token tag -> info of token of pools
 
 	StakePools       []string `json:"stakePools"` // stake pool
 	OnlyUnStakePools []string `json:"onlyUnStakePools"`
@@ -66,9 +92,125 @@ type StateForProposal struct {
 	Token *token.Token `json:"token"` // halo token
 }
 
+// String return canonical serialization of consensus state: config, routers, accounts, token and proposals.
+// maps are serialized in key order, slices in their order. executed txs are not included.
+func (s *State) String() string {
+	b := &strings.Builder{}
+	b.WriteString("dapp:" + s.Dapp + "\n" +
+		"chainID:" + s.ChainID + "\n" +
+		"govern:" + s.Govern + "\n" +
+		"feeRecipient:" + s.FeeRecipient + "\n" +
+		"voteQuorum:" + s.VoteQuorum + "\n" +
+		"voteThreshold:" + s.VoteThreshold + "\n" +
+		"votingPeriod:" + strconv.FormatInt(s.VotingPeriod, 10) + "\n")
+	// omitted if not set, states without it keep their hashes
+	if s.ProposerMinStake != "" {
+		b.WriteString("proposerMinStake:" + s.ProposerMinStake + "\n")
+	}
+	if s.Clock != 0 {
+		b.WriteString("clock:" + strconv.FormatInt(s.Clock, 10) + "\n")
+	}
+	b.WriteString("routerMinStake:" + s.RouterMinStake + "\n" +
+		"stakePools:" + strings.Join(s.StakePools, ",") + "\n" +
+		"onlyUnStakePools:" + strings.Join(s.OnlyUnStakePools, ",") + "\n" +
+		"routers:" + strings.Join(s.Routers, ",") + "\n")
+
+	for _, addr := range sortedKeys(s.RouterStates) {
+		b.WriteString(s.RouterStates[addr].String())
+	}
+
+	for _, tag := range sortedKeys(s.Tokens) {
+		t := s.Tokens[tag]
+		b.WriteString("tokenInfo:" + strconv.Quote(t.Tag) + "," + strconv.Quote(t.Symbol) + "," + strconv.Itoa(t.Decimals) + "\n")
+	}
+	for _, router := range sortedKeys(s.RouterPrices) {
+		for _, tag := range sortedKeys(s.RouterPrices[router]) {
+			b.WriteString("routerPrice:" + router + "," + tag + "," + s.RouterPrices[router][tag] + "\n")
+		}
+	}
+	for _, tag := range sortedKeys(s.Prices) {
+		b.WriteString("price:" + tag + "," + s.Prices[tag] + "\n")
+	}
+
+	for _, id := range sortedKeys(s.Accounts) {
+		acc := s.Accounts[id]
+		b.WriteString("account:" + acc.ID + "," + acc.Type + "," + strconv.FormatInt(acc.Nonce, 10) + "\n")
+	}
+
+	if s.Token != nil {
+		b.WriteString("token:" + s.Token.Symbol + "," + strconv.FormatInt(s.Token.Decimals, 10) + "," + bigString(s.Token.TotalSupply) + "\n")
+		for _, id := range sortedKeys(s.Token.Balances) {
+			b.WriteString("balance:" + id + "," + bigString(s.Token.Balances[id]) + "\n")
+		}
+		for _, id := range sortedKeys(s.Token.Stakes) {
+			pools := s.Token.Stakes[id]
+			for _, pool := range sortedKeys(pools) {
+				for _, stake := range pools[pool] {
+					b.WriteString("stake:" + id + "," + pool + "," + strconv.FormatInt(stake.StakedAt, 10) + "," + bigString(stake.Amount) + "\n")
+				}
+			}
+		}
+	}
+
+	for _, p := range s.Proposals {
+		b.WriteString("proposal:" + p.ID + "," + p.HexHash())
+		if p.Executor != nil {
+			b.WriteString("," + strconv.FormatInt(p.Executor.RunnedTimes, 10) + "," + p.Executor.LocalStateHash)
+		}
+		if p.Schedule != "" {
+			b.WriteString(",scheduled:" + strconv.FormatInt(p.LastScheduled, 10))
+		}
+		b.WriteString("\n")
+	}
+
+	for _, v := range s.Votings {
+		b.WriteString(v.String())
+	}
+	return b.String()
+}
+
+// Hash return hash of consensus state, nodes executed the same txs have the same hash
 func (s *State) Hash() string {
-	// todo: implement
-	return ""
+	return StateStringHash(s.String())
+}
+
+// StateStringHash return hash of serialization of state
+func StateStringHash(str string) string {
+	return hexutil.Encode(accounts.TextHash([]byte(str)))
+}
+
+// String quote fields of router state, free-text fields such as name and desc can't be mistaken for other lines
+func (rs *RouterState) String() string {
+	str := "router:" + rs.Router + "\n" +
+		"name:" + strconv.Quote(rs.Name) + "\n" +
+		"logo:" + strconv.Quote(rs.Logo) + "\n" +
+		"desc:" + strconv.Quote(rs.Desc) + "\n" +
+		"domain:" + strconv.Quote(rs.Domain) + "\n" +
+		"ip:" + strconv.Quote(rs.Ip) + "\n" +
+		"swapFeeRatio:" + strconv.Quote(rs.SwapFeeRatio) + "\n" +
+		"swapFeeRecipient:" + strconv.Quote(rs.SwapFeeRecipient) + "\n" +
+		"lpMinStake:" + strconv.Quote(rs.LpMinStake) + "\n" +
+		"lpPenalty:" + strconv.Quote(rs.LpPenalty) + "\n"
+	for _, id := range sortedKeys(rs.Pools) {
+		str += "pool:" + strconv.Quote(id) + "\n"
+	}
+	return str
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func bigString(i *big.Int) string {
+	if i == nil {
+		return "0"
+	}
+	return i.String()
 }
 
 func CopyRouterState(dst, src *RouterState) {
@@ -125,6 +267,11 @@ func (s *State) GetStateForProposal() *StateForProposal {
 		CopyRouterState(nrs, rs)
 		routerStates[nrs.Router] = nrs
 	}
+	tokens := make(map[string]*TokenInfo, len(s.Tokens))
+	for tag, t := range s.Tokens {
+		nt := *t
+		tokens[tag] = &nt
+	}
 	token := &token.Token{}
 	CopyToken(token, s.Token)
 
@@ -137,9 +284,14 @@ func (s *State) GetStateForProposal() *StateForProposal {
 		Dapp:             s.Dapp,
 		ChainID:          s.ChainID,
 		Govern:           s.Govern,
+		VoteQuorum:       s.VoteQuorum,
+		VoteThreshold:    s.VoteThreshold,
+		VotingPeriod:     s.VotingPeriod,
+		ProposerMinStake: s.ProposerMinStake,
 		FeeRecipient:     s.FeeRecipient,
 		Routers:          routers,
 		RouterStates:     routerStates,
+		Tokens:           tokens,
 		RouterMinStake:   s.RouterMinStake,
 		Token:            token,
 		StakePools:       stakePools,
@@ -151,11 +303,49 @@ func (s *State) UpdateState(ns *StateForProposal) {
 	s.Dapp = ns.Dapp
 	s.ChainID = ns.ChainID
 	s.Govern = ns.Govern
+	s.VoteQuorum = ns.VoteQuorum
+	s.VoteThreshold = ns.VoteThreshold
+	s.VotingPeriod = ns.VotingPeriod
+	s.ProposerMinStake = ns.ProposerMinStake
 	s.FeeRecipient = ns.FeeRecipient
 	s.Routers = ns.Routers
 	s.RouterStates = ns.RouterStates
+	s.Tokens = ns.Tokens
 	s.RouterMinStake = ns.RouterMinStake
 	s.Token = ns.Token
 	s.StakePools = ns.StakePools
 	s.OnlyUnStakePools = ns.OnlyUnStakePools
 }
+
+// String return serialization of state for proposal, same as lines of State
+func (s *StateForProposal) String() string {
+	state := &State{}
+	state.UpdateState(s)
+	return state.String()
+}
+
+// DiffStateString return lines removed from and added to serialization of state
+func DiffStateString(prev, cur string) (removed, added []string) {
+	count := map[string]int{}
+	for _, line := range strings.Split(prev, "\n") {
+		count[line]++
+	}
+	for _, line := range strings.Split(cur, "\n") {
+		count[line]--
+	}
+
+	removed, added = []string{}, []string{}
+	for _, line := range strings.Split(prev, "\n") {
+		if count[line] > 0 {
+			removed = append(removed, line)
+			count[line]--
+		}
+	}
+	for _, line := range strings.Split(cur, "\n") {
+		if count[line] < 0 {
+			added = append(added, line)
+			count[line]++
+		}
+	}
+	return
+}
@@ -4,8 +4,10 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/permadao/permaswap/halo/account"
 	"github.com/permadao/permaswap/halo/token"
 	tokSchema "github.com/permadao/permaswap/halo/token/schema"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCopyToken(t *testing.T) {
@@ -26,3 +28,87 @@ func TestCopyToken(t *testing.T) {
 	t.Log("tokenCopied:", tokenCopied)
 	t.Log("stakes:", tokenCopied.Stakes)
 }
+
+func TestStateHash(t *testing.T) {
+	newState := func() *State {
+		balances := map[string]*big.Int{
+			"0x1": big.NewInt(1),
+			"0x2": big.NewInt(2),
+		}
+		stakes := map[string]map[string][]tokSchema.Stake{
+			"0x1": {"basic": {{StakedAt: 1, Amount: big.NewInt(1)}}, "dev": {{StakedAt: 2, Amount: big.NewInt(2)}}},
+		}
+		return &State{
+			Dapp:    "halo",
+			ChainID: "1",
+			Routers: []string{"0x1"},
+			RouterStates: map[string]*RouterState{
+				"0x1": {Router: "0x1", Name: "r1", Pools: map[string]*Pool{"p1": {TokenXTag: "x", TokenYTag: "y", FeeRatio: Fee003}}},
+			},
+			Token: token.New("HALO", 18, big.NewInt(3), balances, stakes),
+			Accounts: map[string]*account.Account{
+				"0x1": {ID: "0x1", Type: account.AccountTypeEVM, Nonce: 1},
+				"0x2": {ID: "0x2", Type: account.AccountTypeEVM, Nonce: 2},
+			},
+			Proposals: []*Proposal{{ID: "p", Name: "p", Executor: &Executor{LocalStateHash: "0x01"}}},
+		}
+	}
+
+	s1, s2 := newState(), newState()
+	hash := s1.Hash()
+	assert.NotEqual(t, "", hash)
+	// map order does not change hash
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, hash, s2.Hash())
+	}
+	// executed txs are not included
+	s2.Executed = []string{"0x"}
+	assert.Equal(t, hash, s2.Hash())
+
+	s2.Accounts["0x2"].Nonce = 3
+	assert.NotEqual(t, hash, s2.Hash())
+
+	s2 = newState()
+	s2.Token.Balances["0x2"] = big.NewInt(3)
+	assert.NotEqual(t, hash, s2.Hash())
+
+	s2 = newState()
+	s2.Token.Stakes["0x1"]["dev"][0].Amount = big.NewInt(3)
+	assert.NotEqual(t, hash, s2.Hash())
+
+	s2 = newState()
+	s2.Proposals[0].Executor.LocalStateHash = "0x02"
+	assert.NotEqual(t, hash, s2.Hash())
+
+	s2 = newState()
+	s2.RouterStates["0x1"].SwapFeeRatio = "0.001"
+	assert.NotEqual(t, hash, s2.Hash())
+
+	// free-text fields can't forge other lines of router state
+	s1, s2 = newState(), newState()
+	s1.RouterStates["0x1"].Name = "r1\nlogo:l"
+	s2.RouterStates["0x1"].Logo = "l"
+	assert.NotEqual(t, s1.Hash(), s2.Hash())
+	s1, s2 = newState(), newState()
+	s1.RouterStates["0x1"].Desc = "d\"\ndomain:\"x"
+	s2.RouterStates["0x1"].Desc = "d"
+	s2.RouterStates["0x1"].Domain = "x"
+	assert.NotEqual(t, s1.Hash(), s2.Hash())
+}
+
+func TestDiffStateString(t *testing.T) {
+	removed, added := DiffStateString("a\nb\nb\nc\n", "a\nb\nc\nd\n")
+	assert.Equal(t, []string{"b"}, removed)
+	assert.Equal(t, []string{"d"}, added)
+
+	removed, added = DiffStateString("a\n", "a\n")
+	assert.Equal(t, 0, len(removed))
+	assert.Equal(t, 0, len(added))
+
+	s := &State{Accounts: map[string]*account.Account{"0x1": {ID: "0x1", Nonce: 1}}}
+	prev := s.String()
+	s.Accounts["0x1"].Nonce = 2
+	removed, added = DiffStateString(prev, s.String())
+	assert.Equal(t, []string{"account:0x1,,1"}, removed)
+	assert.Equal(t, []string{"account:0x1,,2"}, added)
+}
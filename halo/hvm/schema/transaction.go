@@ -30,9 +30,13 @@ const (
 	TxActionLeave = "leave"
 
 	// proposal
-	TxActionPropose   = "propose"
-	TxActionCall      = "call"
-	TxActionTerminate = "terminate"
+	TxActionPropose      = "propose"
+	TxActionCall         = "call"
+	TxActionTerminate    = "terminate"
+	TxActionUpgrade      = "upgrade"
+	TxActionVote         = "vote"
+	TxActionVotingParams = "votingParams" // change voting params
+	TxActionTick         = "tick"         // advance deterministic clock of scheduled proposals, emitted by routers
 
 	TxActionSwap = "swap"
 )
@@ -46,6 +50,7 @@ var TxActionsSupported = []string{
 	TxActionPropose,
 	TxActionCall,
 	TxActionSwap,
+	TxActionVote,
 }
 
 type Transaction struct {
@@ -62,6 +67,8 @@ type Transaction struct {
 	Params       string     `json:"params"`
 	Sig          string     `json:"sig"`
 	SwapOrder    *SwapOrder `json:"swapOrder" gorm:"-"` // swap order json string
+	// timestamp of everpay tx in milliseconds, set by node from everpay and not signed by sender
+	Timestamp int64 `json:"timestamp,omitempty"`
 }
 
 func (t *Transaction) String() string {
@@ -114,6 +121,7 @@ type TxProposeParams struct {
 	Source                string   `json:"source"`
 	InitData              string   `json:"initData"`
 	OnlyAcceptedTxActions []string `json:"onlyAcceptedTxActions"`
+	Schedule              string   `json:"schedule"` // optional cron expression in utc, e.g. "0 0 * * 1" or "@every 1h"
 }
 
 type TxCallParams struct {
@@ -127,6 +135,23 @@ type TxTerminateParams struct {
 	Note       string `json:"note"`
 }
 
+type TxUpgradeParams struct {
+	ProposalID string `json:"proposalID"` // proposal id is hexhash
+	Source     string `json:"source"`
+	InitData   string `json:"initData"` // init data of proposal is not changed if empty
+	Note       string `json:"note"`
+}
+
+// TxTickParams carry usd prices of tokens reported by router, prices are the oracle of following txs
+type TxTickParams struct {
+	Prices map[string]string `json:"prices,omitempty"` // token tag -> price in usd, decimal string
+}
+
+type TxVoteParams struct {
+	VotingID string `json:"votingID"` // voting id is hexhash of propose or terminate tx
+	Option   string `json:"option"`   // yes or no
+}
+
 type SwapOrderItem struct {
 	PoolID    string   `json:"poolID"`
 	User      string   `json:"user"`
@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"strconv"
+)
+
+const (
+	VoteOptionYes = "yes"
+	VoteOptionNo  = "no"
+)
+
+// VotingParams are params of voting, changed by votingParams tx
+type VotingParams struct {
+	VoteQuorum       string `json:"voteQuorum"`
+	VoteThreshold    string `json:"voteThreshold"`
+	VotingPeriod     int64  `json:"votingPeriod"`
+	ProposerMinStake string `json:"proposerMinStake"`
+}
+
+func (p *VotingParams) String() string {
+	return p.VoteQuorum + "," + p.VoteThreshold + "," + strconv.FormatInt(p.VotingPeriod, 10) + "," + p.ProposerMinStake
+}
+
+// Voting decides a propose, terminate, upgrade or votingParams tx by votes of stakers
+type Voting struct {
+	ID         string            `json:"id"`     // hexhash of propose, terminate, upgrade or votingParams tx
+	Action     string            `json:"action"` // propose, terminate, upgrade or votingParams
+	Proposer   string            `json:"proposer"`
+	ProposalID string            `json:"proposalID"`
+	Proposal   *Proposal         `json:"proposal,omitempty"` // proposal to activate if action is propose, or the upgraded if upgrade
+	Start      int64             `json:"start"`
+	End        int64             `json:"end"`
+	Votes      map[string]string `json:"votes"`            // account id -> option, weighted by stake when voting ends
+	Params     *VotingParams     `json:"params,omitempty"` // voting params to apply if action is votingParams
+}
+
+func (v *Voting) String() string {
+	str := "voting:" + v.ID + "," + v.Action + "," + v.Proposer + "," + v.ProposalID + "," +
+		strconv.FormatInt(v.Start, 10) + "," + strconv.FormatInt(v.End, 10) + "\n"
+	if v.Params != nil {
+		str += "votingParams:" + v.ID + "," + v.Params.String() + "\n"
+	}
+	for _, voter := range sortedKeys(v.Votes) {
+		str += "vote:" + v.ID + "," + voter + "," + v.Votes[voter] + "\n"
+	}
+	return str
+}
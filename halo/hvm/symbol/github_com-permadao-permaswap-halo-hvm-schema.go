@@ -14,6 +14,12 @@ func init() {
 		// function, constant and variable definitions
 		"CopyRouterState":         reflect.ValueOf(schema.CopyRouterState),
 		"CopyToken":               reflect.ValueOf(schema.CopyToken),
+		"DiffStateString":         reflect.ValueOf(schema.DiffStateString),
+		"ErrBudgetExceeded":       reflect.ValueOf(&schema.ErrBudgetExceeded).Elem(),
+		"ErrForbiddenCall":        reflect.ValueOf(&schema.ErrForbiddenCall).Elem(),
+		"ErrForbiddenGoStmt":      reflect.ValueOf(&schema.ErrForbiddenGoStmt).Elem(),
+		"ErrForbiddenImport":      reflect.ValueOf(&schema.ErrForbiddenImport).Elem(),
+		"ErrForbiddenStmt":        reflect.ValueOf(&schema.ErrForbiddenStmt).Elem(),
 		"ErrInsufficientStake":    reflect.ValueOf(&schema.ErrInsufficientStake).Elem(),
 		"ErrInvalidAccountType":   reflect.ValueOf(&schema.ErrInvalidAccountType).Elem(),
 		"ErrInvalidAmount":        reflect.ValueOf(&schema.ErrInvalidAmount).Elem(),
@@ -25,26 +31,37 @@ func init() {
 		"ErrInvalidProposer":      reflect.ValueOf(&schema.ErrInvalidProposer).Elem(),
 		"ErrInvalidRouterAddress": reflect.ValueOf(&schema.ErrInvalidRouterAddress).Elem(),
 		"ErrInvalidRouterName":    reflect.ValueOf(&schema.ErrInvalidRouterName).Elem(),
+		"ErrInvalidSchedule":      reflect.ValueOf(&schema.ErrInvalidSchedule).Elem(),
 		"ErrInvalidStakePool":     reflect.ValueOf(&schema.ErrInvalidStakePool).Elem(),
+		"ErrInvalidTokenInfo":     reflect.ValueOf(&schema.ErrInvalidTokenInfo).Elem(),
 		"ErrInvalidTx":            reflect.ValueOf(&schema.ErrInvalidTx).Elem(),
 		"ErrInvalidTxAction":      reflect.ValueOf(&schema.ErrInvalidTxAction).Elem(),
 		"ErrInvalidTxField":       reflect.ValueOf(&schema.ErrInvalidTxField).Elem(),
 		"ErrInvalidTxParams":      reflect.ValueOf(&schema.ErrInvalidTxParams).Elem(),
+		"ErrInvalidVoteOption":    reflect.ValueOf(&schema.ErrInvalidVoteOption).Elem(),
+		"ErrInvalidVotingParams":  reflect.ValueOf(&schema.ErrInvalidVotingParams).Elem(),
 		"ErrNoPoolFound":          reflect.ValueOf(&schema.ErrNoPoolFound).Elem(),
 		"ErrNoProposalFound":      reflect.ValueOf(&schema.ErrNoProposalFound).Elem(),
 		"ErrNoTokenFound":         reflect.ValueOf(&schema.ErrNoTokenFound).Elem(),
+		"ErrNoVotingFound":        reflect.ValueOf(&schema.ErrNoVotingFound).Elem(),
+		"ErrNoVotingPower":        reflect.ValueOf(&schema.ErrNoVotingPower).Elem(),
 		"ErrNotARouter":           reflect.ValueOf(&schema.ErrNotARouter).Elem(),
+		"ErrProposalOutOfMemory":  reflect.ValueOf(&schema.ErrProposalOutOfMemory).Elem(),
+		"ErrProposalTimeout":      reflect.ValueOf(&schema.ErrProposalTimeout).Elem(),
 		"ErrRouterAlreadyJoined":  reflect.ValueOf(&schema.ErrRouterAlreadyJoined).Elem(),
 		"ErrTxExecuted":           reflect.ValueOf(&schema.ErrTxExecuted).Elem(),
 		"ErrTxPanic":              reflect.ValueOf(&schema.ErrTxPanic).Elem(),
+		"ErrVotingClosed":         reflect.ValueOf(&schema.ErrVotingClosed).Elem(),
 		"Fee0005":                 reflect.ValueOf(&schema.Fee0005).Elem(),
 		"Fee001":                  reflect.ValueOf(&schema.Fee001).Elem(),
 		"Fee003":                  reflect.ValueOf(&schema.Fee003).Elem(),
 		"Fee01":                   reflect.ValueOf(&schema.Fee01).Elem(),
+		"NewBudget":               reflect.ValueOf(schema.NewBudget),
 		"PoolEco":                 reflect.ValueOf(constant.MakeFromLiteral("\"ecosystem\"", token.STRING, 0)),
 		"PoolInc":                 reflect.ValueOf(constant.MakeFromLiteral("\"incentive\"", token.STRING, 0)),
 		"PoolInv":                 reflect.ValueOf(constant.MakeFromLiteral("\"investor\"", token.STRING, 0)),
 		"PoolTeam":                reflect.ValueOf(constant.MakeFromLiteral("\"team\"", token.STRING, 0)),
+		"StateStringHash":         reflect.ValueOf(schema.StateStringHash),
 		"TxActionCall":            reflect.ValueOf(constant.MakeFromLiteral("\"call\"", token.STRING, 0)),
 		"TxActionJoin":            reflect.ValueOf(constant.MakeFromLiteral("\"join\"", token.STRING, 0)),
 		"TxActionLeave":           reflect.ValueOf(constant.MakeFromLiteral("\"leave\"", token.STRING, 0)),
@@ -52,21 +69,30 @@ func init() {
 		"TxActionStake":           reflect.ValueOf(constant.MakeFromLiteral("\"stake\"", token.STRING, 0)),
 		"TxActionSwap":            reflect.ValueOf(constant.MakeFromLiteral("\"swap\"", token.STRING, 0)),
 		"TxActionTerminate":       reflect.ValueOf(constant.MakeFromLiteral("\"terminate\"", token.STRING, 0)),
+		"TxActionTick":            reflect.ValueOf(constant.MakeFromLiteral("\"tick\"", token.STRING, 0)),
 		"TxActionTransfer":        reflect.ValueOf(constant.MakeFromLiteral("\"transfer\"", token.STRING, 0)),
 		"TxActionUnstake":         reflect.ValueOf(constant.MakeFromLiteral("\"unstake\"", token.STRING, 0)),
+		"TxActionUpgrade":         reflect.ValueOf(constant.MakeFromLiteral("\"upgrade\"", token.STRING, 0)),
+		"TxActionVote":            reflect.ValueOf(constant.MakeFromLiteral("\"vote\"", token.STRING, 0)),
+		"TxActionVotingParams":    reflect.ValueOf(constant.MakeFromLiteral("\"votingParams\"", token.STRING, 0)),
 		"TxActionsSupported":      reflect.ValueOf(&schema.TxActionsSupported).Elem(),
 		"TxVersionV1":             reflect.ValueOf(constant.MakeFromLiteral("\"v1\"", token.STRING, 0)),
+		"VoteOptionNo":            reflect.ValueOf(constant.MakeFromLiteral("\"no\"", token.STRING, 0)),
+		"VoteOptionYes":           reflect.ValueOf(constant.MakeFromLiteral("\"yes\"", token.STRING, 0)),
 
 		// type definitions
+		"Budget":            reflect.ValueOf((*schema.Budget)(nil)),
 		"Executor":          reflect.ValueOf((*schema.Executor)(nil)),
 		"Oracle":            reflect.ValueOf((*schema.Oracle)(nil)),
 		"Pool":              reflect.ValueOf((*schema.Pool)(nil)),
 		"Proposal":          reflect.ValueOf((*schema.Proposal)(nil)),
+		"ProposalLog":       reflect.ValueOf((*schema.ProposalLog)(nil)),
 		"RouterState":       reflect.ValueOf((*schema.RouterState)(nil)),
 		"State":             reflect.ValueOf((*schema.State)(nil)),
 		"StateForProposal":  reflect.ValueOf((*schema.StateForProposal)(nil)),
 		"SwapOrder":         reflect.ValueOf((*schema.SwapOrder)(nil)),
 		"SwapOrderItem":     reflect.ValueOf((*schema.SwapOrderItem)(nil)),
+		"TokenInfo":         reflect.ValueOf((*schema.TokenInfo)(nil)),
 		"Transaction":       reflect.ValueOf((*schema.Transaction)(nil)),
 		"TxApply":           reflect.ValueOf((*schema.TxApply)(nil)),
 		"TxCallParams":      reflect.ValueOf((*schema.TxCallParams)(nil)),
@@ -74,7 +100,12 @@ func init() {
 		"TxStakeParams":     reflect.ValueOf((*schema.TxStakeParams)(nil)),
 		"TxSwapParams":      reflect.ValueOf((*schema.TxSwapParams)(nil)),
 		"TxTerminateParams": reflect.ValueOf((*schema.TxTerminateParams)(nil)),
+		"TxTickParams":      reflect.ValueOf((*schema.TxTickParams)(nil)),
 		"TxTransferParams":  reflect.ValueOf((*schema.TxTransferParams)(nil)),
 		"TxUnstakeParams":   reflect.ValueOf((*schema.TxUnstakeParams)(nil)),
+		"TxUpgradeParams":   reflect.ValueOf((*schema.TxUpgradeParams)(nil)),
+		"TxVoteParams":      reflect.ValueOf((*schema.TxVoteParams)(nil)),
+		"Voting":            reflect.ValueOf((*schema.Voting)(nil)),
+		"VotingParams":      reflect.ValueOf((*schema.VotingParams)(nil)),
 	}
 }
@@ -13,20 +13,28 @@ func init() {
 	Symbols["github.com/permadao/permaswap/halo/schema/schema"] = map[string]reflect.Value{
 		// function, constant and variable definitions
 		"ErrInvalidBundleTxAction":     reflect.ValueOf(&schema.ErrInvalidBundleTxAction).Elem(),
+		"ErrInvalidCheckpointHash":     reflect.ValueOf(&schema.ErrInvalidCheckpointHash).Elem(),
+		"ErrInvalidCheckpointSig":      reflect.ValueOf(&schema.ErrInvalidCheckpointSig).Elem(),
+		"ErrInvalidCheckpointSigner":   reflect.ValueOf(&schema.ErrInvalidCheckpointSigner).Elem(),
 		"ErrInvalidGenesisBalance":     reflect.ValueOf(&schema.ErrInvalidGenesisBalance).Elem(),
 		"ErrInvalidGenesisStake":       reflect.ValueOf(&schema.ErrInvalidGenesisStake).Elem(),
 		"ErrInvalidGenesisTotalSupply": reflect.ValueOf(&schema.ErrInvalidGenesisTotalSupply).Elem(),
 		"ErrInvalidGenesisTx":          reflect.ValueOf(&schema.ErrInvalidGenesisTx).Elem(),
 		"ErrInvalidSubmitTxNonce":      reflect.ValueOf(&schema.ErrInvalidSubmitTxNonce).Elem(),
 		"ErrMissParams":                reflect.ValueOf(&schema.ErrMissParams).Elem(),
+		"ErrNoCheckpoint":              reflect.ValueOf(&schema.ErrNoCheckpoint).Elem(),
+		"ErrStateHashMismatch":         reflect.ValueOf(&schema.ErrStateHashMismatch).Elem(),
 		"EverTxActionBundle":           reflect.ValueOf(constant.MakeFromLiteral("\"bundle\"", token.STRING, 0)),
 		"EverTxActionTransfer":         reflect.ValueOf(constant.MakeFromLiteral("\"transfer\"", token.STRING, 0)),
 
 		// type definitions
 		"BalanceRes":      reflect.ValueOf((*schema.BalanceRes)(nil)),
+		"Checkpoint":      reflect.ValueOf((*schema.Checkpoint)(nil)),
 		"GenesisTxData":   reflect.ValueOf((*schema.GenesisTxData)(nil)),
 		"HaloTransaction": reflect.ValueOf((*schema.HaloTransaction)(nil)),
 		"InfoRes":         reflect.ValueOf((*schema.InfoRes)(nil)),
+		"ReplayResult":    reflect.ValueOf((*schema.ReplayResult)(nil)),
+		"StateDiff":       reflect.ValueOf((*schema.StateDiff)(nil)),
 		"SubmitRes":       reflect.ValueOf((*schema.SubmitRes)(nil)),
 		"TxApply":         reflect.ValueOf((*schema.TxApply)(nil)),
 		"TxRes":           reflect.ValueOf((*schema.TxRes)(nil)),
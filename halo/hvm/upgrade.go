@@ -0,0 +1,75 @@
+package hvm
+
+import (
+	"encoding/json"
+
+	"github.com/permadao/permaswap/halo/hvm/schema"
+)
+
+func TxUpgradeParamsVerify(txParams string) (params schema.TxUpgradeParams, err error) {
+	if err := json.Unmarshal([]byte(txParams), &params); err != nil {
+		log.Error("invalid params of upgrade tx to unmarshal", "params", txParams, "err", err)
+		return params, schema.ErrInvalidTxParams
+	}
+	if params.ProposalID == "" || params.Source == "" {
+		log.Error("no proposal id or source of upgrade tx ")
+		return params, schema.ErrInvalidTxParams
+	}
+	return params, nil
+}
+
+// UpgradeVerify verify upgrade tx by the same authority as proposing, return the proposal with new source.
+// id and schedule of the upgraded are the same as the proposal.
+func (h *HVM) UpgradeVerify(tx schema.Transaction) (*schema.Proposal, error) {
+	if err := h.verifyProposer(tx.From); err != nil {
+		return nil, err
+	}
+	params, err := TxUpgradeParamsVerify(tx.Params)
+	if err != nil {
+		return nil, err
+	}
+	proposal := FindProposal(h.Proposals, params.ProposalID)
+	if proposal == nil {
+		return nil, schema.ErrNoProposalFound
+	}
+
+	executor, err := NewExecutor(params.Source)
+	if err != nil {
+		return nil, err
+	}
+	initData := params.InitData
+	if initData == "" {
+		initData = proposal.InitData
+	}
+	upgraded := NewProposal(proposal.Name, proposal.Start, proposal.End, proposal.RunTimes, params.Source, initData, proposal.OnlyAcceptedTxActions, executor)
+	upgraded.Schedule = proposal.Schedule
+	upgraded.ID = proposal.ID
+	return upgraded, nil
+}
+
+// upgradeProposal swap source and executor of proposal to the upgraded,
+// local state is carried to the new executor and migrated if Migrate is defined in new source.
+func upgradeProposal(proposal, upgraded *schema.Proposal) error {
+	executor := upgraded.Executor
+	localState, localStateHash := proposal.Executor.LocalState, proposal.Executor.LocalStateHash
+	if executor.Migrate != nil {
+		r := runWithBudget(proposal.ID, executor.Budget, func() executeResult {
+			localState, localStateHash, err := executor.Migrate(localState, upgraded.InitData)
+			return executeResult{localState: localState, localStateHash: localStateHash, err: err}
+		})
+		if r.err != nil {
+			log.Error("migrate local state of proposal failed", "ID", proposal.ID, "err", r.err)
+			return r.err
+		}
+		localState, localStateHash = r.localState, r.localStateHash
+	}
+
+	executor.LocalState = localState
+	executor.LocalStateHash = localStateHash
+	executor.RunnedTimes = proposal.Executor.RunnedTimes
+	proposal.Source = upgraded.Source
+	proposal.InitData = upgraded.InitData
+	proposal.Executor = executor
+	log.Info("proposal upgraded", "ID", proposal.ID, "name", proposal.Name)
+	return nil
+}
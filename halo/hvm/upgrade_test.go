@@ -0,0 +1,127 @@
+package hvm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+const counterSource = `package proposal
+
+import (
+	"strconv"
+
+	"github.com/permadao/permaswap/halo/hvm/schema"
+)
+
+func Execute(tx *schema.Transaction, state *schema.StateForProposal, oracle *schema.Oracle, localState string, initData string) (*schema.StateForProposal, string, string, error) {
+	n, _ := strconv.Atoi(localState)
+	return state, strconv.Itoa(n + 1), "", nil
+}
+`
+
+const counterSourceV2 = `package proposal
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/permadao/permaswap/halo/hvm/schema"
+)
+
+func Migrate(localState string, initData string) (string, string, error) {
+	if initData == "fail" {
+		return "", "", errors.New("err_migrate")
+	}
+	return "v2:" + localState, "", nil
+}
+
+func Execute(tx *schema.Transaction, state *schema.StateForProposal, oracle *schema.Oracle, localState string, initData string) (*schema.StateForProposal, string, string, error) {
+	return state, strings.TrimPrefix(localState, "v2:") + "+", "", nil
+}
+`
+
+func upgradeTx(from, proposalID, source, initData string) schema.Transaction {
+	params, _ := json.Marshal(schema.TxUpgradeParams{ProposalID: proposalID, Source: source, InitData: initData})
+	return schema.Transaction{Action: schema.TxActionUpgrade, From: from, Nonce: "1000", Params: string(params)}
+}
+
+func newCounterProposal(t *testing.T) *schema.Proposal {
+	executor, err := NewExecutor(counterSource)
+	assert.NoError(t, err)
+	assert.Nil(t, executor.Migrate)
+	proposal := NewProposal("counter", 0, 0, 10, counterSource, "init", nil, executor)
+	for i := 0; i < 2; i++ {
+		_, err := ProposalExecute(proposal, &schema.Transaction{}, &schema.StateForProposal{}, nil)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, "2", proposal.Executor.LocalState)
+	return proposal
+}
+
+func TestUpgradeProposal(t *testing.T) {
+	proposal := newCounterProposal(t)
+	id := proposal.ID
+	h := New(schema.State{Govern: "0xg", Proposals: []*schema.Proposal{proposal}})
+
+	// same authority as proposing
+	_, err := h.UpgradeVerify(upgradeTx("0xa", id, counterSourceV2, ""))
+	assert.Equal(t, schema.ErrInvalidProposer, err)
+	_, err = h.UpgradeVerify(upgradeTx("0xg", "0x01", counterSourceV2, ""))
+	assert.Equal(t, schema.ErrNoProposalFound, err)
+	_, err = h.UpgradeVerify(upgradeTx("0xg", id, "package proposal\n\nimport \"os\"\n", ""))
+	assert.Equal(t, schema.ErrForbiddenImport, err)
+
+	// migration failed, proposal not changed
+	upgraded, err := h.UpgradeVerify(upgradeTx("0xg", id, counterSourceV2, "fail"))
+	assert.NoError(t, err)
+	assert.Error(t, upgradeProposal(proposal, upgraded))
+	assert.Equal(t, counterSource, proposal.Source)
+	assert.Equal(t, "2", proposal.Executor.LocalState)
+
+	upgraded, err = h.UpgradeVerify(upgradeTx("0xg", id, counterSourceV2, ""))
+	assert.NoError(t, err)
+	assert.Equal(t, id, upgraded.ID)
+	assert.NoError(t, upgradeProposal(proposal, upgraded))
+	assert.Equal(t, id, proposal.ID)
+	assert.Equal(t, counterSourceV2, proposal.Source)
+	assert.Equal(t, "init", proposal.InitData)
+	assert.Equal(t, "v2:2", proposal.Executor.LocalState)
+	assert.Equal(t, int64(2), proposal.Executor.RunnedTimes)
+
+	_, err = ProposalExecute(proposal, &schema.Transaction{}, &schema.StateForProposal{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "2+", proposal.Executor.LocalState)
+
+	// local state is carried without Migrate
+	upgraded, err = h.UpgradeVerify(upgradeTx("0xg", id, counterSource, "new"))
+	assert.NoError(t, err)
+	assert.NoError(t, upgradeProposal(proposal, upgraded))
+	assert.Equal(t, "2+", proposal.Executor.LocalState)
+	assert.Equal(t, "new", proposal.InitData)
+}
+
+func TestVotingUpgrade(t *testing.T) {
+	h := newGovernHVM()
+	proposal := newCounterProposal(t)
+	h.Proposals = append(h.Proposals, proposal)
+	stateHash := h.Hash()
+
+	tx := upgradeTx("0xc", proposal.ID, counterSourceV2, "")
+	upgraded, err := h.UpgradeVerify(tx)
+	assert.NoError(t, err)
+	h.Votings = append(h.Votings, h.newVoting(tx, upgraded.ID, upgraded, 1))
+	assert.NoError(t, h.vote(voteTx("0xa", tx.HexHash(), schema.VoteOptionYes), 50, false))
+
+	// not upgraded in voting window
+	h.settleVotings(100)
+	assert.Equal(t, counterSource, proposal.Source)
+
+	h.settleVotings(101)
+	assert.Equal(t, 0, len(h.Votings))
+	assert.Equal(t, counterSourceV2, h.Proposals[0].Source)
+	assert.Equal(t, "v2:2", h.Proposals[0].Executor.LocalState)
+	assert.NotEqual(t, stateHash, h.Hash())
+}
@@ -11,8 +11,18 @@ import (
 	"github.com/permadao/permaswap/halo/hvm/schema"
 )
 
-// todo: stake and vote
+// proposer must be govern, or a staker staked at least proposer min stake if voting is enabled
 func (h *HVM) verifyProposer(proposer string) error {
+	if h.votingEnabled() {
+		staked, _ := new(big.Int).SetString(h.Token.TotalStaked(proposer, ""), 10)
+		if staked.Sign() != 1 {
+			return schema.ErrInvalidProposer
+		}
+		if minStake, ok := new(big.Int).SetString(h.ProposerMinStake, 10); ok && staked.Cmp(minStake) < 0 {
+			return schema.ErrInvalidProposer
+		}
+		return nil
+	}
 	if proposer != h.Govern {
 		return schema.ErrInvalidProposer
 	}
@@ -214,6 +224,13 @@ func (h *HVM) ProposalVerify(tx schema.Transaction, nonce int64) (*schema.Propos
 		return nil, err
 	}
 	proposal := NewProposal(params.Name, start, end, runTimes, source, initData, onlyAcceptedTxActions, executor)
+	if params.Schedule != "" {
+		if err := ScheduleVerify(params.Schedule); err != nil {
+			return nil, err
+		}
+		proposal.Schedule = params.Schedule
+		proposal.ID = proposal.HexHash()
+	}
 	return proposal, nil
 }
 
@@ -2,12 +2,16 @@ package halo
 
 import (
 	"encoding/json"
+	"time"
 
 	everSchema "github.com/everVision/everpay-kits/schema"
 	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
 	"github.com/permadao/permaswap/halo/schema"
 )
 
+// interval to retry saving halo tx to db
+var txSaveRetryInterval = time.Second
+
 func (h *Halo) runProcess() {
 	for {
 		select {
@@ -28,33 +32,45 @@ func (h *Halo) runProcess() {
 		case <-h.tokenChan:
 			h.tokenResChan <- h.hvm.State.Token.Info()
 
+		// copy state for dry run, proposal is executed out of process
+		case <-h.dryRunChan:
+			h.dryRunResChan <- &dryRunState{state: h.hvm.GetStateForProposal(), oracle: h.hvm.Oracle()}
+
 		// close when other process finished
 		case <-h.close:
 			log.Info("process closed")
 			close(h.txSave)
+			close(h.checkpointSave)
 			close(h.closed)
 			return
 		}
 	}
 }
 
+// txSaveProcess save halo txs in order, a failed write is retried until it succeeds.
+// process is blocked on sending the next tx meanwhile, so txs are not tracked past the unsaved one.
 func (h *Halo) txSaveProcess() {
-	for halotx := range h.txSave {
-		err := h.wdb.CreateHaloTx(halotx, nil)
-		if err != nil {
-			log.Error("create halo tx failed", "everHash", halotx.EverHash, "err", err)
+	for t := range h.txSave {
+		for {
+			err := h.wdb.saveHaloTx(t)
+			if err == nil {
+				break
+			}
+			log.Error("create halo tx failed, retry", "everHash", t.haloTx.EverHash, "err", err)
+			select {
+			case <-time.After(txSaveRetryInterval):
+			case <-h.closed:
+				return
+			}
 		}
 	}
 }
 
 func (h *Halo) txApplyProc(txToAppy *schema.TxApply) (err error) {
-	oracle := &hvmSchema.Oracle{
-		EverTokens: h.everSDK.GetTokens(),
-	}
 	if txToAppy.DryRun {
-		err = h.hvm.VerifyTx(txToAppy.Tx, oracle)
+		err = h.hvm.VerifyTx(txToAppy.Tx)
 	} else {
-		err = h.hvm.ExecuteTx(txToAppy.Tx, oracle)
+		err = h.hvm.ExecuteTx(txToAppy.Tx)
 	}
 	return
 }
@@ -79,14 +95,15 @@ func (h *Halo) processHaloTx(txResp everSchema.TxResponse) {
 		}
 		tx = *tx_
 	}
+	// timestamp in tx data is overwritten, clock of hvm is decided by everpay
+	tx.Timestamp = txResp.Timestamp
 
 	// submit to hvm
 	var err error
 	error := ""
-	oracle := &hvmSchema.Oracle{
-		EverTokens: h.everSDK.GetTokens(),
-	}
-	if err = h.hvm.ExecuteTx(tx, oracle); err != nil {
+	prevStateHash := h.hvm.StateHash
+	prevProposals := h.hvm.Proposals
+	if err = h.hvm.ExecuteTx(tx); err != nil {
 		error = err.Error()
 	}
 	log.Info("execute tx return", "everHash", txResp.EverHash, "err", err)
@@ -100,7 +117,14 @@ func (h *Halo) processHaloTx(txResp everSchema.TxResponse) {
 		HaloHash:    tx.HexHash(),
 		Transaction: tx,
 		Error:       error,
+		StateHash:   h.hvm.StateHash,
+		RawID:       txResp.RawId,
 	}
-	h.txSave <- haloTx
-
+	h.txSave <- &txToSave{
+		haloTx:     haloTx,
+		diff:       h.newStateDiff(txResp.EverHash, prevStateHash),
+		executions: newProposalExecutions(h.hvm.ProposalLogs, txResp.RawId),
+		ended:      newEndedProposals(prevProposals, h.hvm.Proposals, txResp.EverHash, txResp.RawId),
+	}
+	h.checkpoint(txResp.RawId, txResp.EverHash)
 }
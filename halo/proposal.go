@@ -0,0 +1,99 @@
+package halo
+
+import (
+	"encoding/json"
+
+	"github.com/permadao/permaswap/halo/hvm"
+	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/schema"
+)
+
+const (
+	// max count of proposals or executions in a page
+	maxPageCount = 100
+	// max count of dry runs executing at the same time
+	maxConcurrentDryRuns = 2
+)
+
+type proposalDryRun struct {
+	proposal *hvmSchema.Proposal
+	tx       hvmSchema.Transaction
+}
+
+// dryRunState is a copy of current state and oracle for dry run
+type dryRunState struct {
+	state  *hvmSchema.StateForProposal
+	oracle *hvmSchema.Oracle
+}
+
+// SetDryRunToken set token required by dry run api in header Authorization, dry run api is disabled if token is empty
+func (h *Halo) SetDryRunToken(token string) {
+	h.dryRunToken = token
+}
+
+// newProposalDryRun compile proposal source of req, the proposal is not verified as a propose tx
+func newProposalDryRun(req *schema.ProposalDryRunReq) (*proposalDryRun, error) {
+	executor, err := hvm.NewDryRunExecutor(req.Source)
+	if err != nil {
+		return nil, err
+	}
+	executor.LocalState = req.LocalState
+	return &proposalDryRun{
+		proposal: hvm.NewProposal("dryrun", 0, 0, 0, req.Source, req.InitData, nil, executor),
+		tx:       req.Tx,
+	}, nil
+}
+
+func newProposalExecutions(logs []*hvmSchema.ProposalLog, rawID int64) []*schema.ProposalExecution {
+	executions := []*schema.ProposalExecution{}
+	for _, l := range logs {
+		removed, _ := json.Marshal(l.Removed)
+		added, _ := json.Marshal(l.Added)
+		executions = append(executions, &schema.ProposalExecution{
+			ProposalID: l.ProposalID,
+			EverHash:   l.EverHash,
+			RawID:      rawID,
+			Error:      l.Error,
+			LocalState: l.LocalState,
+			Removed:    string(removed),
+			Added:      string(added),
+		})
+	}
+	return executions
+}
+
+// newEndedProposals return proposals in prev but not in cur, they are ended by tx
+func newEndedProposals(prev, cur []*hvmSchema.Proposal, everHash string, rawID int64) []*schema.EndedProposal {
+	ended := []*schema.EndedProposal{}
+	for _, p := range prev {
+		if hvm.FindProposal(cur, p.ID) != nil {
+			continue
+		}
+		data, err := json.Marshal(p)
+		if err != nil {
+			log.Error("marshal ended proposal failed", "ID", p.ID, "err", err)
+			continue
+		}
+		ended = append(ended, &schema.EndedProposal{
+			ProposalID: p.ID,
+			Name:       p.Name,
+			EverHash:   everHash,
+			RawID:      rawID,
+			Data:       string(data),
+		})
+	}
+	return ended
+}
+
+// pageProposals return proposals in page, page starts from 1
+func pageProposals(proposals []*hvmSchema.Proposal, page, count int) []*hvmSchema.Proposal {
+	start := (page - 1) * count
+	if start >= len(proposals) {
+		return []*hvmSchema.Proposal{}
+	}
+	end := start + count
+	if end > len(proposals) {
+		end = len(proposals)
+	}
+	return proposals[start:end]
+}
@@ -0,0 +1,90 @@
+package halo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEndedProposals(t *testing.T) {
+	p1 := &hvmSchema.Proposal{ID: "0x1", Name: "p1"}
+	p2 := &hvmSchema.Proposal{ID: "0x2", Name: "p2"}
+	p3 := &hvmSchema.Proposal{ID: "0x3", Name: "p3"}
+
+	ended := newEndedProposals([]*hvmSchema.Proposal{p1, p2}, []*hvmSchema.Proposal{p2, p3}, "0xa", 10)
+	assert.Equal(t, 1, len(ended))
+	assert.Equal(t, "0x1", ended[0].ProposalID)
+	assert.Equal(t, "p1", ended[0].Name)
+	assert.Equal(t, "0xa", ended[0].EverHash)
+	assert.Equal(t, int64(10), ended[0].RawID)
+
+	assert.Equal(t, 0, len(newEndedProposals([]*hvmSchema.Proposal{p1}, []*hvmSchema.Proposal{p1}, "0xa", 10)))
+}
+
+func TestPageProposals(t *testing.T) {
+	proposals := []*hvmSchema.Proposal{{ID: "0x1"}, {ID: "0x2"}, {ID: "0x3"}}
+	assert.Equal(t, proposals[:2], pageProposals(proposals, 1, 2))
+	assert.Equal(t, proposals[2:], pageProposals(proposals, 2, 2))
+	assert.Equal(t, 0, len(pageProposals(proposals, 3, 2)))
+}
+
+func TestNewProposalDryRun(t *testing.T) {
+	_, err := newProposalDryRun(&schema.ProposalDryRunReq{Source: "package proposal\n\nimport \"os\"\n"})
+	assert.Equal(t, hvmSchema.ErrForbiddenImport, err)
+
+	dryRun, err := newProposalDryRun(&schema.ProposalDryRunReq{Source: checkpointSource, LocalState: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", dryRun.proposal.Executor.LocalState)
+}
+
+func TestProposalDryRunAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Halo{
+		dryRunToken:   "secret",
+		dryRunLimit:   make(chan struct{}, 1),
+		dryRunChan:    make(chan struct{}),
+		dryRunResChan: make(chan *dryRunState),
+	}
+	e := gin.New()
+	e.POST("/proposal/dryrun", h.proposalDryRun)
+	go func() {
+		for range h.dryRunChan {
+			h.dryRunResChan <- &dryRunState{state: &hvmSchema.StateForProposal{}}
+		}
+	}()
+	defer close(h.dryRunChan)
+
+	body, _ := json.Marshal(&schema.ProposalDryRunReq{Source: checkpointSource, LocalState: "1"})
+	post := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/proposal/dryrun", strings.NewReader(string(body)))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("wrong").Code)
+
+	w := post("secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	pl := &hvmSchema.ProposalLog{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), pl))
+	assert.Equal(t, "", pl.Error)
+	assert.Equal(t, "11", pl.LocalState)
+
+	// too many dry runs
+	h.dryRunLimit <- struct{}{}
+	assert.Equal(t, http.StatusTooManyRequests, post("secret").Code)
+	<-h.dryRunLimit
+
+	// dry run is disabled without token
+	h.dryRunToken = ""
+	assert.Equal(t, http.StatusForbidden, post("").Code)
+}
@@ -0,0 +1,49 @@
+package halo
+
+import (
+	"encoding/json"
+
+	everSchema "github.com/everVision/everpay-kits/schema"
+	"github.com/permadao/permaswap/halo/hvm"
+	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/schema"
+)
+
+// GenesisState return state of genesis tx data, timestamp in milliseconds is the stake time of genesis stakes
+func GenesisState(data schema.GenesisTxData, timestamp int64) (*hvmSchema.State, error) {
+	by, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return GenesisTxVerify(everSchema.TxResponse{Data: string(by), Nonce: timestamp})
+}
+
+// Replay execute halo txs in order on a new hvm of state, return the hvm and result of every tx. txs executed before are skipped.
+func Replay(state *hvmSchema.State, haloTxs []*schema.HaloTransaction) (*hvm.HVM, []schema.ReplayResult) {
+	vm := hvm.New(*state)
+	results := []schema.ReplayResult{}
+	for _, haloTx := range haloTxs {
+		tx := haloTx.Transaction
+		if tx.EverHash == "" {
+			tx.EverHash = haloTx.EverHash
+		}
+
+		err := vm.ExecuteTx(tx)
+		if err == hvmSchema.ErrTxExecuted {
+			continue
+		}
+		result := schema.ReplayResult{
+			EverHash:          tx.EverHash,
+			HaloHash:          tx.HexHash(),
+			Validity:          err == nil,
+			StateHash:         vm.StateHash,
+			ExpectedStateHash: haloTx.StateHash,
+			Match:             haloTx.StateHash == "" || haloTx.StateHash == vm.StateHash,
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return vm, results
+}
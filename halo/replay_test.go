@@ -0,0 +1,59 @@
+package halo
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/permadao/permaswap/halo/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func readJSONLines(t *testing.T, path string, newItem func() interface{}) {
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), newItem()))
+	}
+}
+
+// TestReplay replay txs in testdata and compare with golden results
+func TestReplay(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/replay/genesis.json")
+	assert.NoError(t, err)
+	genesis := schema.GenesisTxData{}
+	assert.NoError(t, json.Unmarshal(data, &genesis))
+	state, err := GenesisState(genesis, 1680000000000)
+	assert.NoError(t, err)
+
+	txs := []*schema.HaloTransaction{}
+	readJSONLines(t, "testdata/replay/txs.jsonl", func() interface{} {
+		tx := &schema.HaloTransaction{}
+		txs = append(txs, tx)
+		return tx
+	})
+	golden := []*schema.ReplayResult{}
+	readJSONLines(t, "testdata/replay/results.jsonl", func() interface{} {
+		r := &schema.ReplayResult{}
+		golden = append(golden, r)
+		return r
+	})
+
+	vm, results := Replay(state, txs)
+	assert.Equal(t, len(golden), len(results))
+	for i, r := range results {
+		assert.Equal(t, *golden[i], r)
+	}
+	assert.Equal(t, golden[len(golden)-1].StateHash, vm.StateHash)
+
+	// recorded state hash is checked
+	txs[0].StateHash = "0x01"
+	state, _ = GenesisState(genesis, 1680000000000)
+	_, results = Replay(state, txs)
+	assert.False(t, results[0].Match)
+	assert.True(t, results[1].Match)
+}
@@ -29,3 +29,16 @@ type SubmitRes struct {
 	EverHash string `json:"everHash"`
 	HaloHash string `json:"haloHash"`
 }
+
+type ProposalsRes struct {
+	Total     int64                 `json:"total"`
+	Proposals []*hvmSchema.Proposal `json:"proposals"`
+}
+
+// ProposalDryRunReq is the proposal source executed by tx on a copy of current state
+type ProposalDryRunReq struct {
+	Source     string                `json:"source"`
+	InitData   string                `json:"initData"`
+	LocalState string                `json:"localState"` // local state before executed, empty for the first execution
+	Tx         hvmSchema.Transaction `json:"tx"`
+}
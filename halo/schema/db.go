@@ -1,8 +1,12 @@
 package schema
 
 import (
+	"crypto/sha256"
+	"strconv"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts"
+
 	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
 )
 
@@ -13,5 +17,68 @@ type HaloTransaction struct {
 	EverHash  string     `gorm:"type:varchar(66);uniqueIndex" json:"everHash"`
 	HaloHash  string     `gorm:"type:varchar(66);uniqueIndex" json:"haloHash"`
 	hvmSchema.Transaction
-	Error string `json:"error"`
+	Error     string `json:"error"`
+	StateHash string `gorm:"type:varchar(66)" json:"stateHash"` // hvm state hash after tx executed
+	RawID     int64  `gorm:"index" json:"rawId"`                // everPay cursor
+}
+
+// StateDiff is the change of hvm state serialization by a tx
+type StateDiff struct {
+	ID            int64  `gorm:"primary_key;auto_increment" json:"-"`
+	EverHash      string `gorm:"type:varchar(66);uniqueIndex" json:"everHash"`
+	PrevStateHash string `gorm:"type:varchar(66)" json:"prevStateHash"`
+	StateHash     string `gorm:"type:varchar(66)" json:"stateHash"`
+	Removed       string `gorm:"type:longtext" json:"removed"` // json of removed lines
+	Added         string `gorm:"type:longtext" json:"added"`   // json of added lines
+}
+
+// ProposalExecution is the log of proposal executed by a tx
+type ProposalExecution struct {
+	ID         int64  `gorm:"primary_key;auto_increment" json:"-"`
+	ProposalID string `gorm:"type:varchar(66);index" json:"proposalID"`
+	EverHash   string `gorm:"type:varchar(66);index" json:"everHash"`
+	RawID      int64  `gorm:"index" json:"rawId"`
+	Error      string `json:"error"`
+	LocalState string `gorm:"type:longtext" json:"localState"` // local state after executed
+	Removed    string `gorm:"type:longtext" json:"removed"`    // json of removed lines of state
+	Added      string `gorm:"type:longtext" json:"added"`      // json of added lines of state
+}
+
+// EndedProposal is the proposal finished, expired or terminated
+type EndedProposal struct {
+	ID         int64  `gorm:"primary_key;auto_increment" json:"-"`
+	ProposalID string `gorm:"type:varchar(66);uniqueIndex" json:"proposalID"`
+	Name       string `json:"name"`
+	EverHash   string `gorm:"type:varchar(66)" json:"everHash"` // tx ended the proposal
+	RawID      int64  `gorm:"index" json:"rawId"`
+	Data       string `gorm:"type:longtext" json:"data"` // json of proposal when ended
+}
+
+// Checkpoint is a signed snapshot of hvm state after the tx of everHash, node can restore from it and track txs after rawId
+type Checkpoint struct {
+	ID        int64      `gorm:"primary_key;auto_increment" json:"id"`
+	CreatedAt *time.Time `gorm:"ASSOCIATION_AUTOCREATE" json:"createdAt"`
+	RawID     int64      `gorm:"index" json:"rawId"` // everPay cursor
+	EverHash  string     `gorm:"type:varchar(66)" json:"everHash"`
+	StateHash string     `gorm:"type:varchar(66)" json:"stateHash"`
+	State     string     `gorm:"type:longtext" json:"state"` // json of hvm state
+	Token     string     `gorm:"type:longtext" json:"token"` // json of halo token, not included in state json
+	Signer    string     `json:"signer"`
+	Sig       string     `gorm:"type:text" json:"sig"`
+}
+
+func (c *Checkpoint) String() string {
+	return "rawId:" + strconv.FormatInt(c.RawID, 10) + "\n" +
+		"everHash:" + c.EverHash + "\n" +
+		"stateHash:" + c.StateHash + "\n" +
+		"signer:" + c.Signer + "\n"
+}
+
+func (c *Checkpoint) Hash() []byte {
+	return accounts.TextHash([]byte(c.String()))
+}
+
+func (c *Checkpoint) ArHash() []byte {
+	msg := sha256.Sum256([]byte(c.String()))
+	return msg[:]
 }
@@ -10,4 +10,15 @@ var (
 	ErrInvalidSubmitTxNonce      = errors.New("err_invalid_submit_tx_nonce")
 	ErrMissParams                = errors.New("err_miss_params")
 	ErrInvalidBundleTxAction     = errors.New("err_invalid_bundle_tx_action")
+	ErrNoCheckpoint              = errors.New("err_no_checkpoint")
+	ErrInvalidCheckpointSigner   = errors.New("err_invalid_checkpoint_signer")
+	ErrInvalidCheckpointSig      = errors.New("err_invalid_checkpoint_sig")
+	ErrInvalidCheckpointHash     = errors.New("err_invalid_checkpoint_hash")
+	ErrStateHashMismatch         = errors.New("err_state_hash_mismatch")
+	ErrInvalidProposalStatus     = errors.New("err_invalid_proposal_status")
+	ErrInvalidPage               = errors.New("err_invalid_page")
+	ErrInvalidCount              = errors.New("err_invalid_count")
+	ErrTooManyDryRuns            = errors.New("err_too_many_dry_runs")
+	ErrInvalidDryRunToken        = errors.New("err_invalid_dry_run_token")
+	ErrDryRunDisabled            = errors.New("err_dry_run_disabled")
 )
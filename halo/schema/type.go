@@ -7,6 +7,9 @@ import (
 const (
 	EverTxActionTransfer = "transfer"
 	EverTxActionBundle   = "bundle"
+
+	ProposalStatusActive = "active"
+	ProposalStatusEnded  = "ended"
 )
 
 type GenesisTxData struct {
@@ -14,9 +17,14 @@ type GenesisTxData struct {
 	ChainID          string                         `json:"chainID"`
 	Govern           string                         `json:"govern"` // govern is an temporary solution and will use voting in the future
 	FeeRecipient     string                         `json:"feeRecipient"`
+	VoteQuorum       string                         `json:"voteQuorum"`
+	VoteThreshold    string                         `json:"voteThreshold"`
+	VotingPeriod     int64                          `json:"votingPeriod"`
+	ProposerMinStake string                         `json:"proposerMinStake,omitempty"`
 	RouterMinStake   string                         `json:"routerMinStake"`
 	Routers          []string                       `json:"routers"`
 	RouterStates     map[string]*schema.RouterState `json:"routerStates"`
+	Tokens           map[string]*schema.TokenInfo   `json:"tokens,omitempty"` // token tag -> info of token of pools
 	StakePools       []string                       `json:"stakePools"`
 	OnlyUnStakePools []string                       `json:"onlyUnStakePools"`
 	TokenSymbol      string                         `json:"tokenSymbol"`
@@ -30,3 +38,14 @@ type TxApply struct {
 	Tx     schema.Transaction `json:"tx"`
 	DryRun bool               `json:"dryRun"`
 }
+
+// ReplayResult is the result of a tx replayed offline
+type ReplayResult struct {
+	EverHash          string `json:"everHash"`
+	HaloHash          string `json:"haloHash"`
+	Validity          bool   `json:"validity"`
+	Error             string `json:"error"`
+	StateHash         string `json:"stateHash"`
+	ExpectedStateHash string `json:"expectedStateHash,omitempty"` // state hash recorded by node
+	Match             bool   `json:"match"`                       // state hash is the same as recorded, true if not recorded
+}
@@ -116,6 +116,19 @@ func (s *SDK) Leave() (*schema.Transaction, error) {
 	return s.sendTx(schema.TxActionLeave, "")
 }
 
+// Tick advance deterministic clock of halo, scheduled proposals due are executed.
+// prices are usd prices of tokens reported to oracle of halo, token tag -> price
+func (s *SDK) Tick(prices map[string]string) (*schema.Transaction, error) {
+	if len(prices) == 0 {
+		return s.sendTx(schema.TxActionTick, "")
+	}
+	by, err := json.Marshal(schema.TxTickParams{Prices: prices})
+	if err != nil {
+		return nil, err
+	}
+	return s.sendTx(schema.TxActionTick, string(by))
+}
+
 func (s *SDK) getNonce() int64 {
 	for {
 		newNonce := time.Now().UnixNano() / 1000000
@@ -0,0 +1,37 @@
+package halo
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/schema"
+	tokSchema "github.com/permadao/permaswap/halo/token/schema"
+	"github.com/permadao/permaswap/spec"
+)
+
+const SpecVersion = "v1"
+
+// Spec return openapi of halo http api
+func Spec() *spec.Spec {
+	o := spec.NewOpenAPI("halo", SpecVersion)
+	o.Add("get", "/info", "halo state without accounts and txs", nil, nil, schema.InfoRes{})
+	o.Add("get", "/txs", "executed txs", nil, nil, schema.TxRes{})
+	o.Add("get", "/tx/{hash}", "halo tx by everhash or halohash", []*spec.Parameter{spec.PathParam("hash")}, nil, schema.HaloTransaction{})
+	o.Add("get", "/proposals", "active or ended proposals in page",
+		[]*spec.Parameter{spec.QueryParam("status", false), spec.QueryParam("page", false), spec.QueryParam("count", false)}, nil, schema.ProposalsRes{})
+	o.Add("get", "/proposal/{id}", "proposal by id",
+		[]*spec.Parameter{spec.PathParam("id"), spec.QueryParam("detail", false)}, nil, hvmSchema.Proposal{})
+	o.Add("get", "/proposal/{id}/executions", "execution logs of proposal with state diffs, only saved if state logs of node is enabled",
+		[]*spec.Parameter{spec.PathParam("id"), spec.QueryParam("page", false), spec.QueryParam("count", false)}, nil, []schema.ProposalExecution{})
+	o.Add("post", "/proposal/dryrun", "execute proposal source by tx on a copy of current state, header Authorization: Bearer <token> is required, return 403 if token of node is not set, 429 if too many dry runs", nil, schema.ProposalDryRunReq{}, hvmSchema.ProposalLog{})
+	o.Add("get", "/balance/{accid}", "balance and stakes of account", []*spec.Parameter{spec.PathParam("accid")}, nil, schema.BalanceRes{})
+	o.Add("get", "/token", "halo token info", nil, nil, tokSchema.TokenInfo{})
+	o.Add("post", "/submit", "submit halo tx", nil, hvmSchema.Transaction{}, schema.SubmitRes{})
+
+	return &spec.Spec{OpenAPI: o}
+}
+
+func (h *Halo) getSpec(c *gin.Context) {
+	c.JSON(http.StatusOK, Spec())
+}
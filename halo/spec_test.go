@@ -0,0 +1,38 @@
+package halo
+
+import (
+	"encoding/json"
+	"testing"
+
+	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpec(t *testing.T) {
+	o := Spec().OpenAPI
+	submit := o.Operation("post", "/submit")
+
+	// tx submitted by halo sdk
+	tx := hvmSchema.Transaction{
+		Dapp:    "permaswap",
+		ChainID: "1",
+		Action:  hvmSchema.TxActionTransfer,
+		From:    "0x61EbF673c200646236B2c53465bcA0699455d5FA",
+		Fee:     "0",
+		Nonce:   "1",
+		Version: hvmSchema.TxVersionV1,
+		Params:  `{"To":"0x911F42b0229c15bBB38D648B7Aa7CA480eD977d6","Amount":"1"}`,
+		Sig:     "0x1",
+	}
+	by, _ := json.Marshal(tx)
+	assert.NoError(t, submit.RequestBody.Content["application/json"].Schema.Validate(by))
+	assert.Error(t, submit.RequestBody.Content["application/json"].Schema.Validate([]byte(`{"nonce":1}`)))
+	assert.Error(t, submit.RequestBody.Content["application/json"].Schema.Validate([]byte(`{"dapp":"permaswap","action":"transfer","nonce":"1"}`)))
+
+	by, _ = json.Marshal(schema.SubmitRes{EverHash: "0x1", HaloHash: "0x2"})
+	assert.NoError(t, submit.Responses["200"].Content["application/json"].Schema.Validate(by))
+
+	by, _ = json.Marshal(schema.HaloTransaction{Transaction: tx})
+	assert.NoError(t, o.Operation("get", "/tx/{hash}").Responses["200"].Content["application/json"].Schema.Validate(by))
+}
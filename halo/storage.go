@@ -0,0 +1,129 @@
+package halo
+
+import (
+	"encoding/json"
+
+	"github.com/permadao/permaswap/halo/hvm"
+	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/permadao/permaswap/halo/schema"
+)
+
+// halo txs are loaded from db in batches when rebuild
+const rebuildBatch = 1000
+
+type txToSave struct {
+	haloTx     *schema.HaloTransaction
+	diff       *schema.StateDiff
+	executions []*schema.ProposalExecution
+	ended      []*schema.EndedProposal
+}
+
+// SetCheckConsistency replay all txs in db from genesis when run, and compare with the state rebuilt
+func (h *Halo) SetCheckConsistency(check bool) {
+	h.checkConsistency = check
+}
+
+// SetStateLogs save state diff and proposal executions of every tx, they cost serialization of state by every proposal
+func (h *Halo) SetStateLogs(enable bool) {
+	h.stateLogs = enable
+}
+
+// newStateDiff return diff of state by tx, nil if state logs are disabled. the serialization of state is cached by hvm
+func (h *Halo) newStateDiff(everHash, prevStateHash string) *schema.StateDiff {
+	if !h.stateLogs {
+		return nil
+	}
+	cur := h.hvm.StateString()
+	removed, added := hvmSchema.DiffStateString(h.lastState, cur)
+	h.lastState = cur
+
+	removedJs, _ := json.Marshal(removed)
+	addedJs, _ := json.Marshal(added)
+	return &schema.StateDiff{
+		EverHash:      everHash,
+		PrevStateHash: prevStateHash,
+		StateHash:     h.hvm.StateHash,
+		Removed:       string(removedJs),
+		Added:         string(addedJs),
+	}
+}
+
+// replayTxs execute halo txs in db after cursor on vm, state hash after each tx is checked. return cursor of the last tx.
+func (h *Halo) replayTxs(vm *hvm.HVM, cursor int64) (int64, error) {
+	for {
+		haloTxs, err := h.wdb.GetHaloTxs(cursor, rebuildBatch)
+		if err != nil {
+			return cursor, err
+		}
+		if len(haloTxs) == 0 {
+			return cursor, nil
+		}
+
+		for _, haloTx := range haloTxs {
+			cursor = haloTx.RawID
+			tx := haloTx.Transaction
+			tx.EverHash = haloTx.EverHash
+
+			if err := vm.ExecuteTx(tx); err == hvmSchema.ErrTxExecuted {
+				continue
+			}
+			if haloTx.StateHash != "" && vm.StateHash != haloTx.StateHash {
+				log.Error("state hash mismatch", "everHash", haloTx.EverHash, "rawId", haloTx.RawID, "stateHash", vm.StateHash, "expected", haloTx.StateHash)
+				return cursor, schema.ErrStateHashMismatch
+			}
+		}
+	}
+}
+
+// backfillRawID set raw id of halo txs saved before raw id is recorded, raw id is queried from everPay by ever hash
+func (h *Halo) backfillRawID() error {
+	for {
+		haloTxs, err := h.wdb.GetHaloTxsWithoutRawID(rebuildBatch)
+		if err != nil {
+			return err
+		}
+		if len(haloTxs) == 0 {
+			return nil
+		}
+		for _, haloTx := range haloTxs {
+			tx, err := h.everSDK.Cli.TxByHash(haloTx.EverHash)
+			if err != nil {
+				log.Error("get everPay tx failed", "everHash", haloTx.EverHash, "err", err)
+				return err
+			}
+			if err := h.wdb.UpdateHaloTxRawID(haloTx.EverHash, tx.Tx.RawId); err != nil {
+				return err
+			}
+		}
+		log.Info("backfilled raw id of halo txs", "count", len(haloTxs))
+	}
+}
+
+// rebuild hvm from txs in db after cursor, return cursor to track everPay
+func (h *Halo) rebuild(cursor int64) (int64, error) {
+	start := cursor
+	cursor, err := h.replayTxs(h.hvm, cursor)
+	if err != nil {
+		return cursor, err
+	}
+	log.Info("rebuilt from db", "from", start, "to", cursor, "stateHash", h.hvm.StateHash)
+	return cursor, nil
+}
+
+// CheckConsistency replay txs in db from genesis tx and compare state hash with current state
+func (h *Halo) CheckConsistency() error {
+	state, err := GenesisTxVerify(h.genesisTx)
+	if err != nil {
+		return err
+	}
+	vm := hvm.New(*state)
+	if _, err := h.replayTxs(vm, h.GenesisTxRawID); err != nil {
+		return err
+	}
+	if vm.StateHash != h.hvm.StateHash {
+		log.Error("state replayed from genesis mismatch", "stateHash", vm.StateHash, "current", h.hvm.StateHash)
+		return schema.ErrStateHashMismatch
+	}
+	log.Info("state is consistent with replay from genesis", "stateHash", vm.StateHash)
+	return nil
+}
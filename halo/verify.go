@@ -7,6 +7,7 @@ import (
 	"time"
 
 	everSchema "github.com/everVision/everpay-kits/schema"
+	"github.com/permadao/permaswap/halo/hvm"
 	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
 	tokSchema "github.com/permadao/permaswap/halo/token/schema"
 
@@ -44,6 +45,16 @@ func GenesisTxVerify(txResp everSchema.TxResponse) (state *hvmSchema.State, err
 		return nil, schema.ErrInvalidGenesisTx
 	}
 
+	if err := hvm.VotingParamsVerify(genesisTxData.VoteQuorum, genesisTxData.VoteThreshold, genesisTxData.VotingPeriod, genesisTxData.ProposerMinStake); err != nil {
+		log.Error("Invalid voting params of genesis tx", "quorum", genesisTxData.VoteQuorum, "threshold", genesisTxData.VoteThreshold,
+			"period", genesisTxData.VotingPeriod, "proposerMinStake", genesisTxData.ProposerMinStake)
+		return nil, schema.ErrInvalidGenesisTx
+	}
+
+	if err := hvm.TokensVerify(genesisTxData.Tokens); err != nil {
+		return nil, schema.ErrInvalidGenesisTx
+	}
+
 	// todo: add stake info in genesis tx
 
 	token := token.New(genesisTxData.TokenSymbol, genesisTxData.TokenDecimals, totalSupply, balance, stake)
@@ -52,9 +63,14 @@ func GenesisTxVerify(txResp everSchema.TxResponse) (state *hvmSchema.State, err
 		ChainID:          genesisTxData.ChainID,
 		Govern:           genesisTxData.Govern,
 		FeeRecipient:     genesisTxData.FeeRecipient,
+		VoteQuorum:       genesisTxData.VoteQuorum,
+		VoteThreshold:    genesisTxData.VoteThreshold,
+		VotingPeriod:     genesisTxData.VotingPeriod,
+		ProposerMinStake: genesisTxData.ProposerMinStake,
 		RouterMinStake:   genesisTxData.RouterMinStake,
 		Routers:          genesisTxData.Routers,
 		RouterStates:     genesisTxData.RouterStates,
+		Tokens:           genesisTxData.Tokens,
 		StakePools:       genesisTxData.StakePools,
 		OnlyUnStakePools: genesisTxData.OnlyUnStakePools,
 		Token:            token,
@@ -4,6 +4,7 @@ import (
 	"github.com/permadao/permaswap/halo/schema"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WDB struct {
@@ -20,7 +21,8 @@ func NewWDB(dsn string) *WDB {
 }
 
 func (w *WDB) Migrate() {
-	w.db.AutoMigrate(&schema.HaloTransaction{})
+	w.db.AutoMigrate(&schema.HaloTransaction{}, &schema.Checkpoint{}, &schema.StateDiff{},
+		&schema.ProposalExecution{}, &schema.EndedProposal{})
 }
 
 func (w *WDB) CreateHaloTx(haloTx *schema.HaloTransaction, tx *gorm.DB) error {
@@ -34,3 +36,84 @@ func (w *WDB) GetTx(hash string) (haloTx *schema.HaloTransaction, err error) {
 	err = w.db.Where("ever_hash = ?", hash).Or("halo_hash = ?", hash).First(&haloTx).Error
 	return
 }
+
+func (w *WDB) CreateCheckpoint(cp *schema.Checkpoint, tx *gorm.DB) error {
+	if tx == nil {
+		tx = w.db
+	}
+	return tx.Create(cp).Error
+}
+
+func (w *WDB) GetLatestCheckpoint() (cp *schema.Checkpoint, err error) {
+	err = w.db.Order("raw_id desc").First(&cp).Error
+	return
+}
+
+// saveHaloTx save halo tx, the state diff and proposal logs by it atomically
+func (w *WDB) saveHaloTx(t *txToSave) error {
+	return w.db.Transaction(func(tx *gorm.DB) error {
+		// halo txs saved before raw id is recorded are fetched again, raw id and state hash are backfilled
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "ever_hash"}},
+			DoUpdates: clause.AssignmentColumns([]string{"raw_id", "state_hash"}),
+		}).Create(t.haloTx).Error; err != nil {
+			return err
+		}
+		if len(t.executions) > 0 {
+			if err := tx.Create(t.executions).Error; err != nil {
+				return err
+			}
+		}
+		if len(t.ended) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(t.ended).Error; err != nil {
+				return err
+			}
+		}
+		if t.diff == nil {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(t.diff).Error
+	})
+}
+
+// GetHaloTxsWithoutRawID return halo txs saved before raw id is recorded
+func (w *WDB) GetHaloTxsWithoutRawID(limit int) (haloTxs []*schema.HaloTransaction, err error) {
+	err = w.db.Where("raw_id = ?", 0).Order("id asc").Limit(limit).Find(&haloTxs).Error
+	return
+}
+
+func (w *WDB) UpdateHaloTxRawID(everHash string, rawID int64) error {
+	return w.db.Model(&schema.HaloTransaction{}).Where("ever_hash = ?", everHash).Update("raw_id", rawID).Error
+}
+
+// GetHaloTxs return halo txs after cursor in order of everPay
+func (w *WDB) GetHaloTxs(cursor int64, limit int) (haloTxs []*schema.HaloTransaction, err error) {
+	err = w.db.Where("raw_id > ?", cursor).Order("raw_id asc").Limit(limit).Find(&haloTxs).Error
+	return
+}
+
+func (w *WDB) GetStateDiff(everHash string) (diff *schema.StateDiff, err error) {
+	err = w.db.Where("ever_hash = ?", everHash).First(&diff).Error
+	return
+}
+
+// GetProposalExecutions return logs of proposal in order of everPay desc, page starts from 1
+func (w *WDB) GetProposalExecutions(proposalID string, page, count int) (executions []*schema.ProposalExecution, err error) {
+	err = w.db.Where("proposal_id = ?", proposalID).Order("raw_id desc").Order("id desc").
+		Offset((page - 1) * count).Limit(count).Find(&executions).Error
+	return
+}
+
+// GetEndedProposals return proposals ended in order of everPay desc and the total number, page starts from 1
+func (w *WDB) GetEndedProposals(page, count int) (proposals []*schema.EndedProposal, total int64, err error) {
+	if err = w.db.Model(&schema.EndedProposal{}).Count(&total).Error; err != nil {
+		return
+	}
+	err = w.db.Order("raw_id desc").Order("id desc").Offset((page - 1) * count).Limit(count).Find(&proposals).Error
+	return
+}
+
+func (w *WDB) GetEndedProposal(proposalID string) (proposal *schema.EndedProposal, err error) {
+	err = w.db.Where("proposal_id = ?", proposalID).First(&proposal).Error
+	return
+}
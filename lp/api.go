@@ -1,22 +1,62 @@
 package lp
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"errors"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/everVision/everpay-kits/utils"
 	"github.com/gin-gonic/gin"
+	coreSchema "github.com/permadao/permaswap/core/schema"
 	"github.com/permadao/permaswap/lp/schema"
+	"github.com/permadao/permaswap/router"
 	routerSchema "github.com/permadao/permaswap/router/schema"
 )
 
+const (
+	defaultAPIAddr = ":8081"
+
+	apiHeaderTimestamp = "X-Timestamp"
+	apiHeaderSignature = "X-Signature"
+	apiHeaderNonce     = "X-Nonce"
+	// signed api requests expire after
+	apiSigExpiration = 5 * time.Minute
+	// max length of nonce, nonces are unique in expiration of signed requests
+	apiNonceMaxLen = 64
+
+	maxFillsCount = 100
+)
+
+// SetAPI set listen address of api and token for auth.
+// requests are authorized by "Authorization: Bearer <token>",
+// or signed by lp account, see apiSigMsg.
+func (l *Lp) SetAPI(addr, token string) {
+	if addr != "" {
+		l.apiAddr = addr
+	}
+	l.apiToken = token
+}
+
 func (l *Lp) runAPI(port string) {
 
 	l.engine.GET("/info", l.info)
-	l.engine.GET("/orders", l.getOrders)
-	l.engine.POST("/remove_lp", l.removeLp)
-	l.engine.POST("/add_lp", l.addLp)
+
+	auth := l.engine.Group("/", l.auth)
+	auth.GET("/orders", l.getOrders)
+	auth.GET("/pending_orders", l.getPendingOrders)
+	auth.GET("/pnl", l.getPnL)
+	auth.GET("/fills", l.getFills)
+	auth.GET("/fills/csv", l.exportFills)
+	auth.POST("/remove_lp", l.removeLp)
+	auth.POST("/add_lp", l.addLp)
+	auth.POST("/modify_lp", l.modifyLp)
+	auth.POST("/pause", l.pause)
+	auth.POST("/resume", l.resume)
 
 	if !strings.Contains(port, ":") {
 		port = ":" + port
@@ -26,6 +66,83 @@ func (l *Lp) runAPI(port string) {
 	}
 }
 
+// apiSigMsg is the msg signed by lp account for api request
+func apiSigMsg(method, uri, timestamp, nonce string, body []byte) string {
+	return method + "\n" + uri + "\n" + timestamp + "\n" + nonce + "\n" + string(body)
+}
+
+func (l *Lp) auth(c *gin.Context) {
+	if l.apiToken != "" {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(l.apiToken)) == 1 {
+			c.Next()
+			return
+		}
+	}
+
+	if err := l.verifyAPISig(c); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, err.Error())
+		return
+	}
+	c.Next()
+}
+
+func (l *Lp) verifyAPISig(c *gin.Context) error {
+	timestamp := c.GetHeader(apiHeaderTimestamp)
+	nonce := c.GetHeader(apiHeaderNonce)
+	sig := c.GetHeader(apiHeaderSignature)
+	if timestamp == "" || nonce == "" || len(nonce) > apiNonceMaxLen || sig == "" {
+		return ERR_UNAUTHORIZED
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ERR_UNAUTHORIZED
+	}
+	if d := time.Since(time.Unix(ts, 0)); d > apiSigExpiration || d < -apiSigExpiration {
+		return ERR_API_SIG_EXPIRED
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	accType, accID, err := utils.IDCheck(l.rsdk.AccID)
+	if err != nil {
+		return err
+	}
+	msg := apiSigMsg(c.Request.Method, c.Request.URL.RequestURI(), timestamp, nonce, body)
+	if err := router.VerifySig(accType, accID, msg, sig, int(l.chainID)); err != nil {
+		return ERR_UNAUTHORIZED
+	}
+	return l.useAPINonce(nonce)
+}
+
+// useAPINonce reject nonce used before, so signed requests can not be replayed before expired
+func (l *Lp) useAPINonce(nonce string) error {
+	l.apiNoncesLock.Lock()
+	defer l.apiNoncesLock.Unlock()
+
+	now := time.Now()
+	if l.apiNonces == nil {
+		l.apiNonces = make(map[string]time.Time)
+	}
+	for n, expiration := range l.apiNonces {
+		if now.After(expiration) {
+			delete(l.apiNonces, n)
+		}
+	}
+
+	if _, ok := l.apiNonces[nonce]; ok {
+		return ERR_API_NONCE_USED
+	}
+	// timestamp of request is in 5 minutes of now, so it is expired in 10 minutes
+	l.apiNonces[nonce] = now.Add(2 * apiSigExpiration)
+	return nil
+}
+
 func (l *Lp) info(c *gin.Context) {
 	l.apiInfoReq <- struct{}{}
 	c.JSON(http.StatusOK, <-l.apiInfoRes)
@@ -56,6 +173,75 @@ func (l *Lp) addLp(c *gin.Context) {
 	c.JSON(http.StatusOK, <-l.apiAddLpRes)
 }
 
+func (l *Lp) modifyLp(c *gin.Context) {
+	req := schema.ModifyLpReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.LpID == "" {
+		c.JSON(http.StatusBadRequest, "err_no_lpid")
+		return
+	}
+
+	l.apiModifyLpReq <- &req
+	c.JSON(http.StatusOK, <-l.apiModifyLpRes)
+}
+
+func (l *Lp) pause(c *gin.Context) {
+	l.apiPauseReq <- true
+	c.JSON(http.StatusOK, <-l.apiPauseRes)
+}
+
+func (l *Lp) resume(c *gin.Context) {
+	l.apiPauseReq <- false
+	c.JSON(http.StatusOK, <-l.apiPauseRes)
+}
+
+func (l *Lp) getPendingOrders(c *gin.Context) {
+	l.apiPendingOrdersReq <- struct{}{}
+	c.JSON(http.StatusOK, <-l.apiPendingOrdersRes)
+}
+
+// getPnL return pnl of positions from fills, filtered by pool, start and end in unix milliseconds
+func (l *Lp) getPnL(c *gin.Context) {
+	query, err := fillQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	// flows of all lps of positions are counted
+	query.LpID = ""
+
+	l.apiInfoReq <- struct{}{}
+	info := <-l.apiInfoRes
+	lps := map[string]coreSchema.Lp{}
+	for id, lp := range info.Lps {
+		if query.PoolID == "" || lp.PoolID == query.PoolID {
+			lps[id] = lp
+		}
+	}
+
+	fills, err := l.wdb.GetFills(query, 0, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	positions, err := l.wdb.GetPositions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	pnl, err := lpsPnL(lps, positions, fills)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, pnl)
+}
+
 func (l *Lp) getOrders(c *gin.Context) {
 	pageStr := c.DefaultQuery("page", "1")
 	page, err := strconv.ParseInt(pageStr, 10, 32)
@@ -67,3 +253,65 @@ func (l *Lp) getOrders(c *gin.Context) {
 	orders, _ := l.wdb.GetOrders(int(page), 10)
 	c.JSON(http.StatusOK, orders)
 }
+
+// fillQuery parse filters of fills: pool, lp, start and end in unix milliseconds
+func fillQuery(c *gin.Context) (query schema.FillQuery, err error) {
+	query.PoolID = c.Query("pool")
+	query.LpID = c.Query("lp")
+	if start := c.Query("start"); start != "" {
+		if query.Start, err = strconv.ParseInt(start, 10, 64); err != nil {
+			return
+		}
+	}
+	if end := c.Query("end"); end != "" {
+		if query.End, err = strconv.ParseInt(end, 10, 64); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (l *Lp) getFills(c *gin.Context) {
+	query, err := fillQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	count, err := strconv.ParseInt(c.DefaultQuery("count", "10"), 10, 32)
+	if err != nil || count < 1 || count > maxFillsCount {
+		c.JSON(http.StatusBadRequest, "err_invalid_count")
+		return
+	}
+
+	fills, err := l.wdb.GetFills(query, int(page), int(count))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, fills)
+}
+
+func (l *Lp) exportFills(c *gin.Context) {
+	query, err := fillQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fills, err := l.wdb.GetFills(query, 0, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename=fills.csv")
+	if err := writeFillsCSV(c.Writer, fills); err != nil {
+		log.Error("failed to export fills", "err", err)
+	}
+}
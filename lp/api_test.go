@@ -0,0 +1,80 @@
+package lp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/everFinance/goether"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIAuth(t *testing.T) {
+	signer, err := goether.NewSigner("1a7ffbdae668acf43251ed8913596f7db0ce0f90bcd27d4aa85b2bd8a3d0c550")
+	assert.NoError(t, err)
+
+	l := &Lp{chainID: 5, rsdk: &RSDK{AccID: signer.Address.String()}, apiToken: "secret"}
+	engine := gin.New()
+	engine.POST("/pause", l.auth, func(c *gin.Context) {
+		c.JSON(http.StatusOK, "ok")
+	})
+
+	body := `{"lpID":"0x1"}`
+	request := func(set func(r *http.Request)) int {
+		r := httptest.NewRequest(http.MethodPost, "/pause", strings.NewReader(body))
+		set(r)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		return w.Code
+	}
+	nonce := 0
+	signWithNonce := func(r *http.Request, ts int64, n, signedBody string) {
+		timestamp := strconv.FormatInt(ts, 10)
+		sig, err := signer.SignMsg([]byte(apiSigMsg(http.MethodPost, "/pause", timestamp, n, []byte(signedBody))))
+		assert.NoError(t, err)
+		r.Header.Set(apiHeaderTimestamp, timestamp)
+		r.Header.Set(apiHeaderNonce, n)
+		r.Header.Set(apiHeaderSignature, hexutil.Encode(sig))
+	}
+	sign := func(r *http.Request, ts int64, signedBody string) {
+		nonce++
+		signWithNonce(r, ts, strconv.Itoa(nonce), signedBody)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request(func(r *http.Request) {}))
+	assert.Equal(t, http.StatusUnauthorized, request(func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer wrong")
+	}))
+	assert.Equal(t, http.StatusOK, request(func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer secret")
+	}))
+	assert.Equal(t, http.StatusOK, request(func(r *http.Request) {
+		sign(r, time.Now().Unix(), body)
+	}))
+	// expired
+	assert.Equal(t, http.StatusUnauthorized, request(func(r *http.Request) {
+		sign(r, time.Now().Add(-10*time.Minute).Unix(), body)
+	}))
+	// body is changed
+	assert.Equal(t, http.StatusUnauthorized, request(func(r *http.Request) {
+		sign(r, time.Now().Unix(), `{"lpID":"0x2"}`)
+	}))
+
+	// replayed request is rejected
+	ts := time.Now().Unix()
+	assert.Equal(t, http.StatusOK, request(func(r *http.Request) {
+		signWithNonce(r, ts, "replay", body)
+	}))
+	assert.Equal(t, http.StatusUnauthorized, request(func(r *http.Request) {
+		signWithNonce(r, ts, "replay", body)
+	}))
+	// nonce is required
+	assert.Equal(t, http.StatusUnauthorized, request(func(r *http.Request) {
+		signWithNonce(r, ts, "", body)
+	}))
+}
@@ -0,0 +1,198 @@
+package lp
+
+import (
+	"math/big"
+	"time"
+
+	everSchema "github.com/everVision/everpay-kits/schema"
+	"github.com/everVision/everpay-kits/utils"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+)
+
+const (
+	balanceRefreshInterval = time.Minute
+	// amounts of lps are rounded up, shortage less than committed/balanceTolerance is ignored
+	balanceTolerance = 10000
+)
+
+func (l *Lp) subscribeBalance() {
+	latestTxRawId := int64(0)
+	txs, err := l.rsdk.EverSDK.Cli.Txs(0, "desc", 1, everSchema.TxOpts{
+		Address: l.rsdk.AccID,
+	})
+	if err != nil {
+		log.Error("failed to get the latest tx of lp")
+		panic(err)
+	}
+	if len(txs.Txs) > 0 {
+		latestTxRawId = txs.Txs[0].RawId
+	}
+
+	l.balanceSub = l.rsdk.EverSDK.Cli.SubscribeTxs(everSchema.FilterQuery{
+		StartCursor: latestTxRawId,
+		Address:     l.rsdk.AccID,
+	})
+	log.Info("Start to subscribe lp's txs", "address", l.rsdk.AccID, "latestTxRawId", latestTxRawId)
+}
+
+// runBalanceMonitor refresh balances when lp has new tx, order is finished or interval passed
+func (l *Lp) runBalanceMonitor() {
+	ticker := time.NewTicker(balanceRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		balances, err := l.rsdk.GetBalances()
+		if err != nil {
+			log.Warn("failed to get balances", "err", err)
+		} else {
+			select {
+			case l.balanceUpdate <- balances:
+			case <-l.close:
+				return
+			}
+		}
+
+		select {
+		case <-l.balanceSub.Subscribe():
+		case <-l.balanceRefresh:
+		case <-ticker.C:
+		case <-l.close:
+			return
+		}
+	}
+}
+
+// refreshBalance notice balance monitor without blocking
+func (l *Lp) refreshBalance() {
+	select {
+	case l.balanceRefresh <- struct{}{}:
+	default:
+	}
+}
+
+// balanceProc scale down lps of tokens which committed amounts exceed balances
+func (l *Lp) balanceProc(balances map[string]*big.Int) {
+	l.balances = balances
+
+	// balances are changed by processing orders before lps are updated
+	if len(l.orders) > 0 {
+		return
+	}
+
+	committed, err := lpsAmounts(l.core.GetLps(l.rsdk.AccID))
+	if err != nil {
+		log.Error("failed to get committed amounts of lps", "err", err)
+		return
+	}
+
+	// token -> [balance, committed] of tokens which are short
+	ratios := map[string][2]*big.Int{}
+	for token, amount := range committed {
+		balance := l.balance(token)
+		shortage := new(big.Int).Sub(amount, balance)
+		if shortage.Cmp(new(big.Int).Div(amount, big.NewInt(balanceTolerance))) != 1 {
+			continue
+		}
+		log.Warn("committed amount exceeds balance, scale down lps", "token", token, "committed", amount, "balance", balance)
+		ratios[token] = [2]*big.Int{balance, amount}
+	}
+	if len(ratios) == 0 {
+		return
+	}
+
+	for _, lp := range l.core.GetLps(l.rsdk.AccID) {
+		rx, okX := ratios[lp.TokenXTag]
+		ry, okY := ratios[lp.TokenYTag]
+		switch {
+		case okX && okY:
+			// use the smaller ratio, bx/cx < by/cy
+			if new(big.Int).Mul(rx[0], ry[1]).Cmp(new(big.Int).Mul(ry[0], rx[1])) == -1 {
+				l.scaleLp(lp, rx[0], rx[1])
+			} else {
+				l.scaleLp(lp, ry[0], ry[1])
+			}
+		case okX:
+			l.scaleLp(lp, rx[0], rx[1])
+		case okY:
+			l.scaleLp(lp, ry[0], ry[1])
+		}
+	}
+}
+
+// scaleLp scale liquidity of lp by numerator/denominator, lp is removed if liquidity is too small
+func (l *Lp) scaleLp(lp coreSchema.Lp, numerator, denominator *big.Int) {
+	lpID := lp.ID()
+	if res := l.removeLpProc(lpID); res.Result != "ok" {
+		log.Error("failed to remove lp for balance", "lpID", lpID, "result", res.Result, "err", res.Error)
+		return
+	}
+
+	liquidity := new(big.Int).Mul(lp.Liquidity, numerator)
+	liquidity.Div(liquidity, denominator)
+	if liquidity.Sign() != 1 {
+		log.Warn("lp is removed for balance", "lpID", lpID)
+		return
+	}
+
+	msg := LpToAddMsg(lp)
+	msg.Liquidity = liquidity.String()
+	res := l.addLpProc(&msg)
+	if res.Result != "ok" {
+		log.Error("failed to add scaled lp, lp is removed for balance", "lpID", lpID, "result", res.Result, "err", res.Error)
+		return
+	}
+	l.succeedLp(lpID, res.LpID)
+	log.Info("lp is scaled down for balance", "lpID", lpID, "newLpID", res.LpID, "liquidity", msg.Liquidity)
+}
+
+func (l *Lp) balance(token string) *big.Int {
+	if b, ok := l.balances[token]; ok {
+		return b
+	}
+	return big.NewInt(0)
+}
+
+// checkOrderBalance return ERR_NO_ENOUGH_BALANCE if lp can not pay the order and processing orders
+func (l *Lp) checkOrderBalance(paths []coreSchema.Path) error {
+	// balances are unknown before balance monitor starts
+	if l.balances == nil {
+		return nil
+	}
+
+	out := map[string]*big.Int{}
+	addOut := func(paths []coreSchema.Path) error {
+		for _, path := range paths {
+			_, from, err := utils.IDCheck(path.From)
+			if err != nil || from != l.rsdk.AccID {
+				continue
+			}
+			amount, ok := new(big.Int).SetString(path.Amount, 10)
+			if !ok {
+				return ERR_INVALID_AMOUNT
+			}
+			if b, ok := out[path.TokenTag]; ok {
+				out[path.TokenTag] = new(big.Int).Add(b, amount)
+			} else {
+				out[path.TokenTag] = amount
+			}
+		}
+		return nil
+	}
+
+	if err := addOut(paths); err != nil {
+		return err
+	}
+	for _, order := range l.orders {
+		if err := addOut(order.Paths); err != nil {
+			return err
+		}
+	}
+
+	for token, amount := range out {
+		if amount.Cmp(l.balance(token)) == 1 {
+			log.Warn("order would overdraw lp balance", "token", token, "out", amount, "balance", l.balance(token))
+			return ERR_NO_ENOUGH_BALANCE
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package lp
+
+import (
+	"math/big"
+	"testing"
+
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	routerSchema "github.com/permadao/permaswap/router/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckOrderBalance(t *testing.T) {
+	lpAddr := "0x61EbF673c200646236B2c53465bcA0699455d5FA"
+	tokenX := "ethereum-eth-0x0000000000000000000000000000000000000000"
+	tokenY := "ethereum-usdt-0xd85476c906b5301e8e9eb58d174a6f96b9dfc5ee"
+	l := &Lp{
+		rsdk:   &RSDK{AccID: lpAddr},
+		orders: make(map[string]*routerSchema.LpMsgOrder),
+	}
+	paths := []coreSchema.Path{
+		{From: "0x1", To: lpAddr, TokenTag: tokenX, Amount: "100"},
+		{From: lpAddr, To: "0x1", TokenTag: tokenY, Amount: "60"},
+	}
+
+	// balances are unknown
+	assert.NoError(t, l.checkOrderBalance(paths))
+
+	l.balances = map[string]*big.Int{tokenY: big.NewInt(100)}
+	assert.NoError(t, l.checkOrderBalance(paths))
+
+	// processing order
+	l.orders["0x2"] = &routerSchema.LpMsgOrder{Paths: paths}
+	assert.Equal(t, ERR_NO_ENOUGH_BALANCE, l.checkOrderBalance(paths))
+
+	l.balances[tokenY] = big.NewInt(120)
+	assert.NoError(t, l.checkOrderBalance(paths))
+}
+
+func TestLpsAmounts(t *testing.T) {
+	lp1 := testStrategyLp(t, "0.5", "1", "2", "100000")
+	lp2 := testStrategyLp(t, "0.5", "2", "2", "100000")
+
+	amounts, err := lpsAmounts([]coreSchema.Lp{lp1, lp2})
+	assert.NoError(t, err)
+	assert.Equal(t, "50001", amounts[lp1.TokenXTag].String())
+	assert.Equal(t, "200002", amounts[lp1.TokenYTag].String())
+}
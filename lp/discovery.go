@@ -0,0 +1,97 @@
+package lp
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+
+	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
+	haloSdk "github.com/permadao/permaswap/halo/sdk"
+)
+
+// RouterEndpoint is a router which lp can connect to
+type RouterEndpoint struct {
+	Address      string `json:"address"` // router address on everPay, empty if not discovered from halo
+	Name         string `json:"name"`
+	WsURL        string `json:"wsURL"`
+	HttpURL      string `json:"httpURL"`
+	SwapFeeRatio string `json:"swapFeeRatio"`
+	LpMinStake   string `json:"lpMinStake"`
+}
+
+// DiscoverRouters return routers registered on halo, ranked by swap fee ratio and lp min stake
+func DiscoverRouters(haloURL string) ([]RouterEndpoint, error) {
+	info, err := haloSdk.NewClient(haloURL).GetInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := rankRouters(info.Routers, info.RouterStates)
+	if len(endpoints) == 0 {
+		return nil, ERR_NO_ROUTER
+	}
+	return endpoints, nil
+}
+
+// rankRouters sort routers by swap fee ratio then lp min stake, both ascending.
+// routers without domain or ip are ignored.
+func rankRouters(routers []string, states map[string]*hvmSchema.RouterState) []RouterEndpoint {
+	endpoints := []RouterEndpoint{}
+	for _, addr := range routers {
+		state, ok := states[addr]
+		if !ok {
+			continue
+		}
+		wsURL, httpURL := routerURLs(state.Domain, state.Ip)
+		if wsURL == "" {
+			continue
+		}
+		endpoints = append(endpoints, RouterEndpoint{
+			Address:      addr,
+			Name:         state.Name,
+			WsURL:        wsURL,
+			HttpURL:      httpURL,
+			SwapFeeRatio: state.SwapFeeRatio,
+			LpMinStake:   state.LpMinStake,
+		})
+	}
+
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if c := cmpDecimalString(endpoints[i].SwapFeeRatio, endpoints[j].SwapFeeRatio); c != 0 {
+			return c == -1
+		}
+		if c := cmpDecimalString(endpoints[i].LpMinStake, endpoints[j].LpMinStake); c != 0 {
+			return c == -1
+		}
+		return endpoints[i].Address < endpoints[j].Address
+	})
+	return endpoints
+}
+
+// routerURLs return urls of router, domain is served with tls
+func routerURLs(domain, ip string) (wsURL, httpURL string) {
+	host, scheme := domain, "s"
+	if host == "" {
+		host, scheme = ip, ""
+	}
+	if host == "" {
+		return "", ""
+	}
+	host = strings.TrimSuffix(host, "/")
+	return "ws" + scheme + "://" + host + "/wslp", "http" + scheme + "://" + host
+}
+
+// cmpDecimalString compare decimal strings, invalid or empty string is greater than all numbers
+func cmpDecimalString(a, b string) int {
+	a_, okA := new(big.Float).SetString(a)
+	b_, okB := new(big.Float).SetString(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return 1
+	case !okB:
+		return -1
+	}
+	return a_.Cmp(b_)
+}
@@ -0,0 +1,36 @@
+package lp
+
+import (
+	"testing"
+
+	hvmSchema "github.com/permadao/permaswap/halo/hvm/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankRouters(t *testing.T) {
+	routers := []string{"0xa", "0xb", "0xc", "0xd", "0xe"}
+	states := map[string]*hvmSchema.RouterState{
+		"0xa": {Domain: "a.permaswap.network", SwapFeeRatio: "0.003", LpMinStake: "100"},
+		"0xb": {Domain: "b.permaswap.network", SwapFeeRatio: "0.001", LpMinStake: "1000"},
+		"0xc": {Ip: "10.0.0.1", SwapFeeRatio: "0.001", LpMinStake: "10"},
+		// no domain and ip
+		"0xd": {SwapFeeRatio: "0"},
+		// invalid fee ratio
+		"0xe": {Domain: "e.permaswap.network/", SwapFeeRatio: ""},
+	}
+
+	endpoints := rankRouters(routers, states)
+	assert.Equal(t, 4, len(endpoints))
+	assert.Equal(t, "0xc", endpoints[0].Address)
+	assert.Equal(t, "ws://10.0.0.1/wslp", endpoints[0].WsURL)
+	assert.Equal(t, "http://10.0.0.1", endpoints[0].HttpURL)
+	assert.Equal(t, "0xb", endpoints[1].Address)
+	assert.Equal(t, "wss://b.permaswap.network/wslp", endpoints[1].WsURL)
+	assert.Equal(t, "https://b.permaswap.network", endpoints[1].HttpURL)
+	assert.Equal(t, "0xa", endpoints[2].Address)
+	assert.Equal(t, "0xe", endpoints[3].Address)
+	assert.Equal(t, "https://e.permaswap.network", endpoints[3].HttpURL)
+
+	// router without state
+	assert.Equal(t, 0, len(rankRouters([]string{"0xf"}, states)))
+}
@@ -6,3 +6,16 @@ var (
 	ERR_NO_ENOUGH_BALANCE = errors.New("err_no_enough_balance")
 	ERR_INVALID_AMOUNT    = errors.New("err_invalid_amount")
 )
+
+var (
+	ERR_UNAUTHORIZED    = errors.New("err_unauthorized")
+	ERR_API_SIG_EXPIRED = errors.New("err_api_sig_expired")
+	ERR_API_NONCE_USED  = errors.New("err_api_nonce_used")
+)
+
+var (
+	ERR_NO_ROUTER           = errors.New("err_no_router")
+	ERR_ROUTER_TIMEOUT      = errors.New("err_router_timeout")
+	ERR_ROUTER_CLOSED       = errors.New("err_router_closed")
+	ERR_ROUTER_DISCONNECTED = errors.New("err_router_disconnected")
+)
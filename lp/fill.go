@@ -0,0 +1,115 @@
+package lp
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	everSchema "github.com/everVision/everpay-kits/schema"
+	"github.com/everVision/everpay-kits/utils"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/permadao/permaswap/lp/schema"
+)
+
+var fillCSVHeader = []string{"id", "timestamp", "orderHash", "everHash", "poolID", "lpID",
+	"tokenIn", "tokenOut", "amountIn", "amountOut", "fee", "price"}
+
+// orderFills return fills of lps in paths, lps must be the state before the order is applied
+func orderFills(accID string, lps map[string]*coreSchema.Lp, tokens map[string]*everSchema.Token,
+	orderHash string, tx everSchema.TxResponse, paths []coreSchema.Path) ([]*schema.Fill, error) {
+	timestamp := tx.Timestamp
+	if timestamp == 0 {
+		timestamp = time.Now().UnixMilli()
+	}
+
+	fills := []*schema.Fill{}
+	fillsByLp := map[string]*schema.Fill{}
+	for _, path := range paths {
+		lp, ok := lps[path.LpID]
+		if !ok {
+			continue
+		}
+		_, from, _ := utils.IDCheck(path.From)
+		_, to, _ := utils.IDCheck(path.To)
+		if from != accID && to != accID {
+			continue
+		}
+
+		fill, ok := fillsByLp[path.LpID]
+		if !ok {
+			fill = &schema.Fill{
+				OrderHash: orderHash,
+				EverHash:  tx.EverHash,
+				LpID:      path.LpID,
+				PoolID:    lp.PoolID,
+				Timestamp: timestamp,
+			}
+			fillsByLp[path.LpID] = fill
+			fills = append(fills, fill)
+		}
+
+		if to == accID {
+			amount, ok := new(big.Int).SetString(path.Amount, 10)
+			if !ok {
+				return nil, ERR_INVALID_AMOUNT
+			}
+			fee, err := lpFee(amount, lp.FeeRatio)
+			if err != nil {
+				return nil, err
+			}
+			fill.TokenIn = path.TokenTag
+			fill.AmountIn = path.Amount
+			fill.Fee = fee.String()
+		} else {
+			fill.TokenOut = path.TokenTag
+			fill.AmountOut = path.Amount
+		}
+	}
+
+	for _, fill := range fills {
+		fill.Price = fillPrice(lps[fill.LpID], tokens, fill)
+	}
+	return fills, nil
+}
+
+// fillPrice return price of tokenX in tokenY with decimals, empty if it can not be calculated
+func fillPrice(lp *coreSchema.Lp, tokens map[string]*everSchema.Token, fill *schema.Fill) string {
+	tX, okX := tokens[lp.TokenXTag]
+	tY, okY := tokens[lp.TokenYTag]
+	if !okX || !okY {
+		return ""
+	}
+
+	amountX, amountY := fill.AmountIn, fill.AmountOut
+	if fill.TokenIn == lp.TokenYTag {
+		amountX, amountY = fill.AmountOut, fill.AmountIn
+	}
+	x, okX := new(big.Float).SetString(amountX)
+	y, okY := new(big.Float).SetString(amountY)
+	if !okX || !okY || x.Sign() != 1 {
+		return ""
+	}
+
+	price := new(big.Float).Quo(y, x)
+	price.Mul(price, big.NewFloat(math.Pow(10, float64(tX.Decimals-tY.Decimals))))
+	return price.Text('f', -1)
+}
+
+func writeFillsCSV(w io.Writer, fills []*schema.Fill) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(fillCSVHeader); err != nil {
+		return err
+	}
+	for _, f := range fills {
+		record := []string{strconv.FormatInt(f.ID, 10), strconv.FormatInt(f.Timestamp, 10), f.OrderHash, f.EverHash, f.PoolID, f.LpID,
+			f.TokenIn, f.TokenOut, f.AmountIn, f.AmountOut, f.Fee, f.Price}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
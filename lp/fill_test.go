@@ -0,0 +1,83 @@
+package lp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	everSchema "github.com/everVision/everpay-kits/schema"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/permadao/permaswap/lp/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderFills(t *testing.T) {
+	lpAddr := "0x61EbF673c200646236B2c53465bcA0699455d5FA"
+	user := "0x4002ED1a1410aF1b4930cF6c479ae373dEbD6223"
+	lp := testStrategyLp(t, "1", "2", "4", "100000")
+	lps := map[string]*coreSchema.Lp{lp.ID(): &lp}
+	tokens := map[string]*everSchema.Token{
+		lp.TokenXTag: {Decimals: 18},
+		lp.TokenYTag: {Decimals: 6},
+	}
+	tx := everSchema.TxResponse{EverHash: "0xeverhash", Timestamp: 1700000000000}
+
+	fills, err := orderFills(lpAddr, lps, tokens, "0xorder", tx, []coreSchema.Path{
+		{LpID: lp.ID(), From: user, To: lpAddr, TokenTag: lp.TokenXTag, Amount: "1000000000000000000"},
+		{LpID: lp.ID(), From: lpAddr, To: user, TokenTag: lp.TokenYTag, Amount: "1500000000"},
+		{LpID: "0x1", From: user, To: "0x2", TokenTag: lp.TokenXTag, Amount: "1000"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(fills))
+	f := fills[0]
+	assert.Equal(t, lp.ID(), f.LpID)
+	assert.Equal(t, lp.PoolID, f.PoolID)
+	assert.Equal(t, "0xorder", f.OrderHash)
+	assert.Equal(t, "0xeverhash", f.EverHash)
+	assert.Equal(t, lp.TokenXTag, f.TokenIn)
+	assert.Equal(t, lp.TokenYTag, f.TokenOut)
+	assert.Equal(t, "3000000000000000", f.Fee)
+	assert.Equal(t, "1500", f.Price)
+	assert.Equal(t, int64(1700000000000), f.Timestamp)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, writeFillsCSV(buf, fills))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, strings.Join(fillCSVHeader, ","), lines[0])
+}
+
+func TestGetFills(t *testing.T) {
+	wdb := NewWDB(t.TempDir())
+	wdb.Migrate()
+
+	assert.NoError(t, wdb.CreateFills([]*schema.Fill{
+		{LpID: "lp1", PoolID: "pool1", Timestamp: 100},
+		{LpID: "lp2", PoolID: "pool1", Timestamp: 200},
+		{LpID: "lp3", PoolID: "pool2", Timestamp: 300},
+	}, nil))
+
+	fills, err := wdb.GetFills(schema.FillQuery{}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(fills))
+	assert.Equal(t, "lp1", fills[0].LpID)
+
+	fills, err = wdb.GetFills(schema.FillQuery{PoolID: "pool1"}, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(fills))
+	assert.Equal(t, "lp2", fills[0].LpID)
+
+	fills, err = wdb.GetFills(schema.FillQuery{LpID: "lp3"}, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(fills))
+
+	fills, err = wdb.GetFills(schema.FillQuery{Start: 200, End: 300}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(fills))
+	assert.Equal(t, "lp2", fills[0].LpID)
+
+	fills, err = wdb.GetFills(schema.FillQuery{}, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(fills))
+	assert.Equal(t, "lp1", fills[0].LpID)
+}
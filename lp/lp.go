@@ -6,8 +6,10 @@ import (
 	"math/big"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	apd "github.com/cockroachdb/apd/v3"
 	everSchema "github.com/everVision/everpay-kits/schema"
 	"github.com/everVision/everpay-kits/sdk"
 	"github.com/gin-gonic/gin"
@@ -23,32 +25,76 @@ var LpVersion = "v0.5.5"
 
 var log = logger.New("lp")
 
+// msgs of routers are buffered while process is waiting for lp responses
+const routerMsgBuffer = 64
+
 type Lp struct {
 	chainID int64
 	tokens  map[string]*everSchema.Token
 	pools   map[string]*coreSchema.Pool
 
 	core *core.Core
-	rsdk *RSDK
-
-	configPath string
-	order      *routerSchema.LpMsgOrder
+	rsdk *RSDK // sdk of the first router, also used for the account on everPay
+
+	routers           []*Router
+	routerOrder       chan routerOrder
+	routerOrderStatus chan *routerSchema.OrderMsgStatus
+	routerReconnect   chan *Router
+	routerTx          chan everSchema.TxResponse
+
+	configPath   string
+	strategy     Strategy
+	rebalancing  bool                         // market prices are being got for rebalance
+	marketPrices chan map[string]*apd.Decimal // market sqrt prices for rebalance, pool id -> sqrt price
+
+	orders           map[string]*routerSchema.LpMsgOrder // processing orders, orderHash -> order
+	reservedLps      map[string]string                   // lps reserved by processing orders, lpID -> orderHash
+	confirmingOrders map[string]bool                     // orders confirming on everpay
+	orderRouters     map[string]*Router                  // routers of processing orders, orderHash -> router
+	orderCursors     map[string]int64                    // raw id of latest router tx when order arrived, orderHash -> raw id
+	txCursor         int64                               // raw id of latest router tx seen
+	completedTxs     []everSchema.TxResponse             // txs of completed orders not saved as latest order yet
+	orderConfirmed   chan *orderResult
+
+	priceFeed      PriceFeed
+	priceThreshold float64
+	priceInterval  time.Duration
+	feedPrices     chan map[string]float64
+	prices         map[string]float64           // latest prices from feed
+	guardedLps     map[string]coreSchema.Lp     // lps removed by price guard
+	guarding       bool                         // pool prices are being got for price guard
+	guardPrices    chan map[string]*apd.Decimal // market sqrt prices for price guard, pool id -> sqrt price
+	poolPrices     map[string]*apd.Decimal      // latest market sqrt prices of pools on router
 
 	close  chan struct{}
 	closed chan struct{}
 
-	routerAddress string
-	sub           *sdk.SubscribeTx
+	balanceSub     *sdk.SubscribeTx // txs of lp
+	balances       map[string]*big.Int
+	balanceUpdate  chan map[string]*big.Int
+	balanceRefresh chan struct{}
+
+	paused bool // orders are rejected when paused
 
 	engine *gin.Engine
 
-	apiEnabled     bool
-	apiInfoReq     chan struct{}
-	apiInfoRes     chan *schema.InfoRes
-	apiRemoveLpReq chan string
-	apiRemoveLpRes chan *schema.RemoveLpRes
-	apiAddLpReq    chan *routerSchema.LpMsgAdd
-	apiAddLpRes    chan *schema.AddLpRes
+	apiEnabled          bool
+	apiAddr             string
+	apiToken            string
+	apiNonces           map[string]time.Time // nonces of signed requests -> expiration
+	apiNoncesLock       sync.Mutex
+	apiInfoReq          chan struct{}
+	apiInfoRes          chan *schema.InfoRes
+	apiRemoveLpReq      chan string
+	apiRemoveLpRes      chan *schema.RemoveLpRes
+	apiAddLpReq         chan *routerSchema.LpMsgAdd
+	apiAddLpRes         chan *schema.AddLpRes
+	apiModifyLpReq      chan *schema.ModifyLpReq
+	apiModifyLpRes      chan *schema.ModifyLpRes
+	apiPauseReq         chan bool
+	apiPauseRes         chan *schema.PauseRes
+	apiPendingOrdersReq chan struct{}
+	apiPendingOrdersRes chan []schema.PendingOrder
 
 	wdb *WDB
 }
@@ -65,18 +111,49 @@ func New(chainID int64, apiEnabled bool, rsdk *RSDK) *Lp {
 		pools:   make(map[string]*coreSchema.Pool),
 		rsdk:    rsdk,
 
+		routers:           []*Router{NewRouter(rsdk, nil)},
+		routerOrder:       make(chan routerOrder, routerMsgBuffer),
+		routerOrderStatus: make(chan *routerSchema.OrderMsgStatus, routerMsgBuffer),
+		routerReconnect:   make(chan *Router),
+		routerTx:          make(chan everSchema.TxResponse, routerMsgBuffer),
+
+		orders:           make(map[string]*routerSchema.LpMsgOrder),
+		reservedLps:      make(map[string]string),
+		confirmingOrders: make(map[string]bool),
+		orderRouters:     make(map[string]*Router),
+		orderCursors:     make(map[string]int64),
+		orderConfirmed:   make(chan *orderResult),
+
+		marketPrices: make(chan map[string]*apd.Decimal),
+
+		balanceUpdate:  make(chan map[string]*big.Int),
+		balanceRefresh: make(chan struct{}, 1),
+
+		feedPrices:  make(chan map[string]float64),
+		prices:      make(map[string]float64),
+		guardedLps:  make(map[string]coreSchema.Lp),
+		guardPrices: make(chan map[string]*apd.Decimal),
+		poolPrices:  make(map[string]*apd.Decimal),
+
 		close:  make(chan struct{}),
 		closed: make(chan struct{}),
 
 		engine: gin.Default(),
 
-		apiEnabled:     apiEnabled,
-		apiInfoReq:     make(chan struct{}),
-		apiInfoRes:     make(chan *schema.InfoRes),
-		apiRemoveLpReq: make(chan string),
-		apiRemoveLpRes: make(chan *schema.RemoveLpRes),
-		apiAddLpReq:    make(chan *routerSchema.LpMsgAdd),
-		apiAddLpRes:    make(chan *schema.AddLpRes),
+		apiEnabled:          apiEnabled,
+		apiAddr:             defaultAPIAddr,
+		apiInfoReq:          make(chan struct{}),
+		apiInfoRes:          make(chan *schema.InfoRes),
+		apiRemoveLpReq:      make(chan string),
+		apiRemoveLpRes:      make(chan *schema.RemoveLpRes),
+		apiAddLpReq:         make(chan *routerSchema.LpMsgAdd),
+		apiAddLpRes:         make(chan *schema.AddLpRes),
+		apiModifyLpReq:      make(chan *schema.ModifyLpReq),
+		apiModifyLpRes:      make(chan *schema.ModifyLpRes),
+		apiPauseReq:         make(chan bool),
+		apiPauseRes:         make(chan *schema.PauseRes),
+		apiPendingOrdersReq: make(chan struct{}),
+		apiPendingOrdersRes: make(chan []schema.PendingOrder),
 	}
 }
 
@@ -101,8 +178,16 @@ func (l *Lp) Run(configPath string) {
 
 	l.processPendingOrder()
 	l.subscribeRouterOrder()
+	l.subscribeBalance()
 
 	go l.runProcess()
+	for _, r := range l.routers {
+		go l.runRouter(r)
+	}
+	go l.runBalanceMonitor()
+	if l.priceFeed != nil {
+		go l.runPriceFeed()
+	}
 
 	if err := l.cleanLiquidity(); err != nil {
 		panic(err)
@@ -112,15 +197,20 @@ func (l *Lp) Run(configPath string) {
 	}
 
 	if l.apiEnabled {
-		go l.runAPI("8081")
+		go l.runAPI(l.apiAddr)
 	}
 }
 
 func (l *Lp) Close() {
 	l.cleanLiquidity()
-	l.rsdk.Close()
+	for _, r := range l.routers {
+		r.rsdk.Close()
+	}
 	close(l.close)
-	l.sub.Unsubscribe()
+	for _, r := range l.routers {
+		r.sub.Unsubscribe()
+	}
+	l.balanceSub.Unsubscribe()
 	<-l.closed
 	log.Info("lp closed")
 }
@@ -148,37 +238,13 @@ func (l *Lp) loadConfig() (err error) {
 			return
 		}
 	}
-	return
+	return l.loadGuardedLps()
 }
 
 func (l *Lp) checkBalance(lps []coreSchema.Lp, retry bool) (err error) {
-	balances := map[string]*big.Int{}
-
-	for _, lp := range lps {
-		amountX, amountY, err := core.LiquidityToAmount(lp.Liquidity.String(), lp.LowSqrtPrice, lp.CurrentSqrtPrice, lp.HighSqrtPrice, lp.PriceDirection)
-		if err != nil {
-			return err
-		}
-		x, ok := new(big.Int).SetString(amountX, 10)
-		if !ok {
-			return ERR_INVALID_AMOUNT
-		}
-		y, ok := new(big.Int).SetString(amountY, 10)
-		if !ok {
-			return ERR_INVALID_AMOUNT
-		}
-
-		if b, ok := balances[lp.TokenXTag]; ok {
-			balances[lp.TokenXTag] = new(big.Int).Add(b, x)
-		} else {
-			balances[lp.TokenXTag] = x
-		}
-
-		if b, ok := balances[lp.TokenYTag]; ok {
-			balances[lp.TokenYTag] = new(big.Int).Add(b, y)
-		} else {
-			balances[lp.TokenYTag] = y
-		}
+	balances, err := lpsAmounts(lps)
+	if err != nil {
+		return
 	}
 
 	log.Info("balance needed:")
@@ -219,25 +285,45 @@ func (l *Lp) checkBalance(lps []coreSchema.Lp, retry bool) (err error) {
 	return
 }
 
+// lpsAmounts return token amounts needed by lps, token tag -> amount
+func lpsAmounts(lps []coreSchema.Lp) (map[string]*big.Int, error) {
+	balances := map[string]*big.Int{}
+
+	for _, lp := range lps {
+		amountX, amountY, err := core.LiquidityToAmount(lp.Liquidity.String(), lp.LowSqrtPrice, lp.CurrentSqrtPrice, lp.HighSqrtPrice, lp.PriceDirection)
+		if err != nil {
+			return nil, err
+		}
+		x, ok := new(big.Int).SetString(amountX, 10)
+		if !ok {
+			return nil, ERR_INVALID_AMOUNT
+		}
+		y, ok := new(big.Int).SetString(amountY, 10)
+		if !ok {
+			return nil, ERR_INVALID_AMOUNT
+		}
+
+		if b, ok := balances[lp.TokenXTag]; ok {
+			balances[lp.TokenXTag] = new(big.Int).Add(b, x)
+		} else {
+			balances[lp.TokenXTag] = x
+		}
+
+		if b, ok := balances[lp.TokenYTag]; ok {
+			balances[lp.TokenYTag] = new(big.Int).Add(b, y)
+		} else {
+			balances[lp.TokenYTag] = y
+		}
+	}
+	return balances, nil
+}
+
 func (l *Lp) getRouterInfo() {
-	for {
-		info, err := l.rsdk.GetInfo()
-		if err == nil {
-			l.routerAddress = info.RouterAddress
-			for _, p := range info.PoolList {
-				pool, err := core.NewPool(p.TokenXTag, p.TokenYTag, p.FeeRatio.String())
-				if err != nil {
-					log.Error("failed to get pool from router api", "err", err)
-					panic(err)
-				}
-				l.pools[pool.ID()] = pool
-			}
-			break
+	for _, r := range l.routers {
+		for id, pool := range r.getInfo() {
+			l.pools[id] = pool
 		}
-		log.Warn("failed to get router address", "err", err)
-		time.Sleep(100 * time.Millisecond)
 	}
-	log.Info("Router address:", "router", l.routerAddress)
 }
 
 func (l *Lp) getCore() {
@@ -245,43 +331,46 @@ func (l *Lp) getCore() {
 }
 
 func (l *Lp) subscribeRouterOrder() {
-	latestTxRawId := int64(0)
-	txs, err := l.rsdk.EverSDK.Cli.Txs(0, "desc", 1, everSchema.TxOpts{
-		Address: l.routerAddress,
-	})
-	if err != nil {
-		log.Error("failed to get the latest tx of router")
-		panic(err)
+	for _, r := range l.routers {
+		l.subscribeRouter(r)
 	}
-	if txs.Txs != nil {
-		if len(txs.Txs) > 0 {
-			latestTxRawId = txs.Txs[0].RawId
-			log.Info("latest tx of router", "rawId", latestTxRawId)
-		}
-	}
-
-	l.sub = l.rsdk.EverSDK.Cli.SubscribeTxs(everSchema.FilterQuery{
-		StartCursor: latestTxRawId,
-		Address:     l.routerAddress,
-	})
-	log.Info("Start to subscribe router's order", "routerAddress", l.routerAddress, "latestTxRawId", latestTxRawId)
 }
 
-func (l *Lp) reconnect() (err error) {
-	if tokens, err := l.rsdk.EverSDK.Cli.GetTokens(); err != nil {
-		return err
-	} else {
+func (l *Lp) reconnect(r *Router) (err error) {
+	// router may be changed by failover
+	address := r.address
+	defer func() {
+		if r.address != address {
+			log.Warn("router address changed, resubscribe router's order", "old", address, "new", r.address)
+			l.subscribeRouter(r)
+		}
+	}()
+
+	if r == l.routers[0] {
+		tokens, err := l.rsdk.EverSDK.Cli.GetTokens()
+		if err != nil {
+			return err
+		}
 		l.tokens = tokens
 	}
 
-	l.getRouterInfo()
-	l.getCore()
-	if err := l.loadConfig(); err != nil {
-		log.Error("failed to load config.", "err", err)
-		panic(err)
+	// local core is kept, so orders reserved on other routers are not lost.
+	// pools added to router after start are not supported until restart.
+	for id := range r.getInfo() {
+		if _, ok := l.core.Pools[id]; !ok {
+			log.Warn("new pool of router is not supported until restart", "router", r.address, "poolID", id)
+		}
 	}
-	l.processPendingOrder()
-	return l.registerLiquidity()
+	l.recoverOrders(r)
+
+	if r == l.routers[0] {
+		if err := l.checkBalance(l.core.GetLps(l.rsdk.AccID), true); err != nil {
+			log.Error("check lp balance: failed.", "err", err)
+			return err
+		}
+	}
+	// lps on router are diffed with local core instead of assumed to be clean
+	return l.resyncRouter(r)
 }
 
 func (l *Lp) registerLiquidity() (err error) {
@@ -291,47 +380,29 @@ func (l *Lp) registerLiquidity() (err error) {
 		panic(err)
 	}
 
-	for _, lp := range lps {
-		err = l.rsdk.AddLiquidity(LpToAddMsg(lp))
-		if err != nil {
+	for _, r := range l.routers {
+		if err = r.registerLps(lps); err != nil {
 			return
 		}
-
-		amountX, amountY, _ := core.LiquidityToAmount(lp.Liquidity.String(), lp.LowSqrtPrice, lp.CurrentSqrtPrice, lp.HighSqrtPrice, lp.PriceDirection)
-		log.Info("register liquidity", "x", lp.TokenXTag, "y", lp.TokenYTag, "amountX", amountX, "amountY", amountY, "currentSqrtPrice", lp.CurrentSqrtPrice)
-		log.Info("lp price range", "low", core.LpLowPrice(lp), "current", core.LpCurrentPrice(lp), "high", core.LpHighPrice(lp), "liquidity", lp.Liquidity.String())
 	}
-
 	return
 }
 
 func (l *Lp) cleanLiquidity() (err error) {
-	lps, err := l.rsdk.GetLps()
-	if err != nil {
-		return
-	}
-
-	for {
-		for _, lp := range lps {
-			l.rsdk.RemoveLiquidity(LpToRemoveMsg(lp))
-		}
-
-		lps, err = l.rsdk.GetLps()
-		if err != nil {
-			return
-		}
-		if len(lps) == 0 {
-			log.Info("liqudity is clean")
+	for _, r := range l.routers {
+		if err = r.cleanLps(); err != nil {
 			return
 		}
-
-		log.Warn("liquidity not clean", "accID", l.rsdk.AccID, "len(lps)", len(lps))
-		time.Sleep(200 * time.Millisecond)
 	}
+	return
 }
 
 func (l *Lp) UpdateLiquidity() error {
 	lps := l.core.GetLps(l.rsdk.AccID)
+	// keep lps removed by price guard in config
+	for _, lp := range l.guardedLps {
+		lps = append(lps, lp)
+	}
 
 	msgs := make([]routerSchema.LpMsgAdd, len(lps))
 	for i, lp := range lps {
@@ -379,8 +450,9 @@ func (l *Lp) getInfo() *schema.InfoRes {
 		ChainID:       l.chainID,
 		Tokens:        l.tokens,
 		Pools:         l.core.Pools,
-		RouterAddress: l.routerAddress,
+		RouterAddress: l.routers[0].address,
 		Lps:           lps,
+		Paused:        l.paused,
 	}
 	return info
 }
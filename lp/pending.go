@@ -3,6 +3,7 @@ package lp
 import (
 	"encoding/json"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 
@@ -10,14 +11,19 @@ import (
 	"github.com/permadao/permaswap/router/schema"
 )
 
-func (l *Lp) processPendingOrder() {
-	pendingOrder, err := l.loadPendingOrder()
+const (
+	pendingOrdersFile = "pending_orders.json"
+	// pending order file of versions before concurrent orders
+	pendingOrderFile = "pending_order.json"
+)
 
-	if err != nil {
+func (l *Lp) processPendingOrder() {
+	pendingOrders, err := l.loadPendingOrders()
+	if err != nil || len(pendingOrders) == 0 {
 		return
 	}
 
-	log.Warn("found pending order")
+	log.Warn("found pending orders", "count", len(pendingOrders))
 	latestOrder, err := l.loadLatestOrder()
 	if err != nil {
 		panic("failed to load latest order")
@@ -31,23 +37,43 @@ func (l *Lp) processPendingOrder() {
 	}
 	lastRawID := tx.Tx.RawId
 
-	txs := getTxsByCursor(l.rsdk.EverSDK.Cli, l.routerAddress, lastRawID)
-	if len(txs) == 0 {
-		log.Info("pending order was not submitted to everpay. ignore it.")
-		l.removePendingOrder()
-		return
-	}
+	l.orders = pendingOrders
+	l.reservedLps = make(map[string]string)
+	l.confirmingOrders = make(map[string]bool)
 
-	l.order = pendingOrder
-	for _, tx := range txs {
-		l.processRouterOrder(tx)
-	}
-	if l.order != nil {
-		log.Info("pending order was not submitted to everpay. ignore it.")
-		l.removePendingOrder()
-		return
+	l.orderRouters = make(map[string]*Router)
+	l.orderCursors = make(map[string]int64)
+
+	txs := []everSchema.TxResponse{}
+	for _, r := range l.routers {
+		txs = append(txs, getTxsByCursor(l.rsdk.EverSDK.Cli, r.address, lastRawID)...)
 	}
-	log.Info("finish pending order")
+	for _, tx := range txs {
+		bundleData := everSchema.BundleData{}
+		if err := json.Unmarshal([]byte(tx.Data), &bundleData); err != nil {
+			continue
+		}
+		order, ok := l.orders[bundleData.Bundle.Bundle.HashHex()]
+		if !ok {
+			continue
+		}
+		msg := schema.OrderMsgStatus{
+			Event:     schema.OrderMsgEventStatus,
+			OrderHash: order.Bundle.HashHex(),
+			EverHash:  tx.EverHash,
+		}
+		l.orderConfirmedProc(l.confirmOrder(msg, order))
+	}
+
+	if len(l.orders) > 0 {
+		log.Info("pending orders were not submitted to everpay. ignore them.", "count", len(l.orders))
+		l.orders = make(map[string]*schema.LpMsgOrder)
+		l.saveOrderCursor("", nil)
+	}
+	if err := l.removePendingOrders(); err != nil {
+		log.Error("failed to remove pending orders", "err", err)
+	}
+	log.Info("finish pending orders")
 }
 
 func (l *Lp) getFilePath(fileName string) (string, error) {
@@ -60,50 +86,97 @@ func (l *Lp) getFilePath(fileName string) (string, error) {
 	return filePath, nil
 }
 
-func (l *Lp) savePendingOrder(msg *schema.LpMsgOrder) error {
-	orderFilePath, err := l.getFilePath("pending_order.json")
+// savePendingOrders save processing orders, orderHash -> order
+func (l *Lp) savePendingOrders() error {
+	orderFilePath, err := l.getFilePath(pendingOrdersFile)
 	if err != nil {
 		return err
 	}
 
-	by, err := json.MarshalIndent(msg, "", "  ")
+	by, err := json.MarshalIndent(l.orders, "", "  ")
 	if err != nil {
 		return err
 	}
 	return ioutil.WriteFile(orderFilePath, by, 0644)
 }
 
-func (l *Lp) loadPendingOrder() (msg *schema.LpMsgOrder, err error) {
-	orderFilePath, err := l.getFilePath("pending_order.json")
-	if err != nil {
-		return
-	}
+func (l *Lp) loadPendingOrders() (orders map[string]*schema.LpMsgOrder, err error) {
+	orders = make(map[string]*schema.LpMsgOrder)
 
-	file, err := os.Open(orderFilePath)
+	orderFilePath, err := l.getFilePath(pendingOrdersFile)
 	if err != nil {
 		return
 	}
-	defer file.Close()
+	if data, err := ioutil.ReadFile(orderFilePath); err == nil {
+		if err := json.Unmarshal(data, &orders); err != nil {
+			return nil, err
+		}
+	}
 
-	data, err := ioutil.ReadAll(file)
+	orderFilePath, err = l.getFilePath(pendingOrderFile)
 	if err != nil {
 		return
 	}
-
-	if err = json.Unmarshal(data, &msg); err != nil {
-		return
+	if data, err := ioutil.ReadFile(orderFilePath); err == nil {
+		order := &schema.LpMsgOrder{}
+		if err := json.Unmarshal(data, order); err != nil {
+			return nil, err
+		}
+		orders[order.Bundle.HashHex()] = order
 	}
 
-	return
+	return orders, nil
 }
 
-func (l *Lp) removePendingOrder() error {
-	orderFilePath, err := l.getFilePath("pending_order.json")
-	if err != nil {
-		return err
-	}
+func (l *Lp) removePendingOrders() error {
+	for _, fileName := range []string{pendingOrdersFile, pendingOrderFile} {
+		orderFilePath, err := l.getFilePath(fileName)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(orderFilePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
 
-	return os.Remove(orderFilePath)
+// saveOrderCursor save the latest tx of completed orders, which raw id is not greater than cursor of any other processing order,
+// so pending orders are scanned from it on restart without skipping unconfirmed orders. tx is nil if order failed.
+func (l *Lp) saveOrderCursor(orderHash string, tx *everSchema.TxResponse) {
+	if tx != nil {
+		l.completedTxs = append(l.completedTxs, *tx)
+	}
+
+	bound := int64(math.MaxInt64)
+	for hash := range l.orders {
+		if hash == orderHash {
+			continue
+		}
+		if cursor := l.orderCursors[hash]; cursor < bound {
+			bound = cursor
+		}
+	}
+
+	latest := -1
+	remain := []everSchema.TxResponse{}
+	for i, completed := range l.completedTxs {
+		if completed.RawId > bound {
+			remain = append(remain, completed)
+			continue
+		}
+		if latest == -1 || completed.RawId > l.completedTxs[latest].RawId {
+			latest = i
+		}
+	}
+	if latest == -1 {
+		return
+	}
+	if err := l.saveLatestOrder(l.completedTxs[latest]); err != nil {
+		log.Error("failed to save latest order", "err", err)
+		return
+	}
+	l.completedTxs = remain
 }
 
 func (l *Lp) saveLatestOrder(tx everSchema.TxResponse) error {
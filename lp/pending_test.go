@@ -0,0 +1,136 @@
+package lp
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	everSchema "github.com/everVision/everpay-kits/schema"
+	"github.com/permadao/permaswap/core"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/permadao/permaswap/lp/schema"
+	routerSchema "github.com/permadao/permaswap/router/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPendingOrders(t *testing.T) {
+	dir := t.TempDir()
+	l := &Lp{
+		configPath:       filepath.Join(dir, "config.json"),
+		orders:           make(map[string]*routerSchema.LpMsgOrder),
+		reservedLps:      make(map[string]string),
+		confirmingOrders: make(map[string]bool),
+	}
+
+	order1 := &routerSchema.LpMsgOrder{Bundle: everSchema.Bundle{Salt: "1"}}
+	order2 := &routerSchema.LpMsgOrder{Bundle: everSchema.Bundle{Salt: "2"}}
+	l.orders[order1.Bundle.HashHex()] = order1
+	l.orders[order2.Bundle.HashHex()] = order2
+	l.reservedLps["lp1"] = order1.Bundle.HashHex()
+	l.reservedLps["lp2"] = order2.Bundle.HashHex()
+	assert.NoError(t, l.savePendingOrders())
+
+	orders, err := l.loadPendingOrders()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(orders))
+	assert.Equal(t, "2", orders[order2.Bundle.HashHex()].Bundle.Salt)
+
+	// release order and its lps
+	l.releaseOrder(order1.Bundle.HashHex())
+	assert.Equal(t, 1, len(l.orders))
+	assert.Equal(t, map[string]string{"lp2": order2.Bundle.HashHex()}, l.reservedLps)
+	orders, err = l.loadPendingOrders()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(orders))
+
+	// pending order of old versions
+	order3 := routerSchema.LpMsgOrder{Bundle: everSchema.Bundle{Salt: "3"}}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, pendingOrderFile), order3.Marshal(), 0644))
+	orders, err = l.loadPendingOrders()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(orders))
+	assert.Equal(t, "3", orders[order3.Bundle.HashHex()].Bundle.Salt)
+
+	assert.NoError(t, l.removePendingOrders())
+	orders, err = l.loadPendingOrders()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(orders))
+}
+
+func TestSaveOrderCursor(t *testing.T) {
+	dir := t.TempDir()
+	l := &Lp{
+		configPath:   filepath.Join(dir, "config.json"),
+		orders:       make(map[string]*routerSchema.LpMsgOrder),
+		orderCursors: make(map[string]int64),
+	}
+	for i, cursor := range []int64{10, 20, 30} {
+		hash := strconv.Itoa(i)
+		l.orders[hash] = &routerSchema.LpMsgOrder{}
+		l.orderCursors[hash] = cursor
+	}
+	latest := func() int64 {
+		tx, err := l.loadLatestOrder()
+		assert.NoError(t, err)
+		return tx.RawId
+	}
+
+	// order 0 arrived at 10 is processing, tx of order 2 after it is not saved
+	l.saveOrderCursor("2", &everSchema.TxResponse{RawId: 35, EverHash: "0x35"})
+	delete(l.orders, "2")
+	_, err := l.loadLatestOrder()
+	assert.Error(t, err)
+
+	// order 1 failed
+	l.saveOrderCursor("1", nil)
+	delete(l.orders, "1")
+	_, err = l.loadLatestOrder()
+	assert.Error(t, err)
+
+	// all orders completed
+	l.saveOrderCursor("0", &everSchema.TxResponse{RawId: 12, EverHash: "0x12"})
+	delete(l.orders, "0")
+	assert.Equal(t, int64(35), latest())
+	assert.Equal(t, 0, len(l.completedTxs))
+
+	// completed tx before cursor of processing order is saved
+	l.orders["3"] = &routerSchema.LpMsgOrder{}
+	l.orderCursors["3"] = 40
+	l.orders["4"] = &routerSchema.LpMsgOrder{}
+	l.orderCursors["4"] = 45
+	l.saveOrderCursor("4", &everSchema.TxResponse{RawId: 42, EverHash: "0x42"})
+	delete(l.orders, "4")
+	assert.Equal(t, int64(35), latest())
+	l.orders["5"] = &routerSchema.LpMsgOrder{}
+	l.orderCursors["5"] = 50
+	l.saveOrderCursor("3", &everSchema.TxResponse{RawId: 48, EverHash: "0x48"})
+	delete(l.orders, "3")
+	assert.Equal(t, int64(48), latest())
+}
+
+func TestUpdateReservedLp(t *testing.T) {
+	lp := testStrategyLp(t, "0.5", "1", "2", "100000")
+	other := testStrategyLp(t, "0.25", "1", "4", "100000")
+	pool, err := core.NewPool(lp.TokenXTag, lp.TokenYTag, "0.003")
+	assert.NoError(t, err)
+	l := &Lp{
+		rsdk:        &RSDK{AccID: lp.AccID},
+		core:        core.New(map[string]*coreSchema.Pool{pool.ID(): pool}, "", ""),
+		orders:      map[string]*routerSchema.LpMsgOrder{"0xorder": {}},
+		reservedLps: map[string]string{lp.ID(): "0xorder"},
+	}
+	assert.NoError(t, l.core.AddLiquidityByLp(&lp))
+	assert.NoError(t, l.core.AddLiquidityByLp(&other))
+
+	// lp reserved by processing order can not be updated
+	assert.Equal(t, "err_can_not_update_lp_with_order", l.removeLpProc(lp.ID()).Error)
+	assert.Equal(t, "err_can_not_update_lp_with_order", l.modifyLpProc(&schema.ModifyLpReq{LpID: lp.ID(), Liquidity: "1"}).Error)
+	msg := LpToAddMsg(lp)
+	assert.Equal(t, "err_can_not_update_lp_with_order", l.addLpProc(&msg).Error)
+
+	// other lps are not blocked by the order
+	assert.Equal(t, "err_invalid_lp", l.modifyLpProc(&schema.ModifyLpReq{LpID: other.ID(), LowSqrtPrice: other.HighSqrtPrice}).Error)
+	msg = LpToAddMsg(other)
+	assert.Equal(t, "err_close_lp_first", l.addLpProc(&msg).Error)
+}
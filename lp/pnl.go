@@ -0,0 +1,192 @@
+package lp
+
+import (
+	"math/big"
+	"sort"
+
+	apd "github.com/cockroachdb/apd/v3"
+	"github.com/permadao/permaswap/core"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/permadao/permaswap/lp/schema"
+)
+
+type lpFlows struct {
+	inX, outX, inY, outY, feeX, feeY *big.Int
+	orders                           int
+}
+
+// succeedLp save new lp as successor of old lp, pnl of the position is kept when lp id changes
+func (l *Lp) succeedLp(oldID, newID string) {
+	if oldID == newID || newID == "" || l.wdb == nil {
+		return
+	}
+	positionID, err := l.wdb.GetPosition(oldID)
+	if err != nil {
+		log.Error("failed to get position of lp", "lpID", oldID, "err", err)
+		return
+	}
+	if err := l.wdb.SavePosition(&schema.Position{LpID: newID, PositionID: positionID}); err != nil {
+		log.Error("failed to save position of lp", "lpID", newID, "positionID", positionID, "err", err)
+	}
+}
+
+// lpsPnL return pnl of positions of current lps from fills, position id -> pnl.
+// positions are lp id -> position id, lp is position of its own if not in positions.
+func lpsPnL(lps map[string]coreSchema.Lp, positions map[string]string, fills []*schema.Fill) (map[string]*schema.LpPnL, error) {
+	position := func(lpID string) string {
+		if p, ok := positions[lpID]; ok {
+			return p
+		}
+		return lpID
+	}
+
+	// current lps of positions, sorted by lp id
+	lpIDs := make([]string, 0, len(lps))
+	for lpID := range lps {
+		lpIDs = append(lpIDs, lpID)
+	}
+	sort.Strings(lpIDs)
+	positionLps := map[string][]coreSchema.Lp{}
+	flows := map[string]*lpFlows{}
+	for _, lpID := range lpIDs {
+		p := position(lpID)
+		positionLps[p] = append(positionLps[p], lps[lpID])
+		flows[p] = &lpFlows{
+			inX: big.NewInt(0), outX: big.NewInt(0),
+			inY: big.NewInt(0), outY: big.NewInt(0),
+			feeX: big.NewInt(0), feeY: big.NewInt(0),
+		}
+	}
+
+	counted := map[string]bool{}
+	for _, fill := range fills {
+		// lps of a position are in the same pool
+		p := position(fill.LpID)
+		pLps, ok := positionLps[p]
+		if !ok {
+			continue
+		}
+		lp := pLps[0]
+		f := flows[p]
+		if fill.TokenIn != "" {
+			amount, ok := new(big.Int).SetString(fill.AmountIn, 10)
+			if !ok {
+				return nil, ERR_INVALID_AMOUNT
+			}
+			fee, ok := new(big.Int).SetString(fill.Fee, 10)
+			if !ok {
+				return nil, ERR_INVALID_AMOUNT
+			}
+			if fill.TokenIn == lp.TokenXTag {
+				f.inX.Add(f.inX, amount)
+				f.feeX.Add(f.feeX, fee)
+			} else {
+				f.inY.Add(f.inY, amount)
+				f.feeY.Add(f.feeY, fee)
+			}
+		}
+		if fill.TokenOut != "" {
+			amount, ok := new(big.Int).SetString(fill.AmountOut, 10)
+			if !ok {
+				return nil, ERR_INVALID_AMOUNT
+			}
+			if fill.TokenOut == lp.TokenXTag {
+				f.outX.Add(f.outX, amount)
+			} else {
+				f.outY.Add(f.outY, amount)
+			}
+		}
+		// lps of a position may be filled by the same order
+		if !counted[p+fill.OrderHash] {
+			counted[p+fill.OrderHash] = true
+			f.orders++
+		}
+	}
+
+	res := map[string]*schema.LpPnL{}
+	for p, pLps := range positionLps {
+		f := flows[p]
+		amounts, err := lpsAmounts(pLps)
+		if err != nil {
+			return nil, err
+		}
+		lp := pLps[0]
+		netValue, err := netValueY(new(big.Int).Sub(f.inX, f.outX), new(big.Int).Sub(f.inY, f.outY), lp.CurrentSqrtPrice)
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, len(pLps))
+		for i, l := range pLps {
+			ids[i] = l.ID()
+		}
+		res[p] = &schema.LpPnL{
+			PositionID: p,
+			LpIDs:      ids,
+			PoolID:     lp.PoolID,
+			TokenX:     lp.TokenXTag,
+			TokenY:     lp.TokenYTag,
+			AmountX:    amounts[lp.TokenXTag].String(),
+			AmountY:    amounts[lp.TokenYTag].String(),
+			InX:        f.inX.String(),
+			OutX:       f.outX.String(),
+			InY:        f.inY.String(),
+			OutY:       f.outY.String(),
+			FeeX:       f.feeX.String(),
+			FeeY:       f.feeY.String(),
+			NetValueY:  netValue.String(),
+			Orders:     f.orders,
+		}
+	}
+	return res, nil
+}
+
+// lpFee is the fee charged by lp in amountIn, rounded up as core does
+func lpFee(amountIn *big.Int, feeRatio *apd.Decimal) (*big.Int, error) {
+	amount, err := core.BigDotIntToDecimal(amountIn)
+	if err != nil {
+		return nil, err
+	}
+	c := apd.BaseContext.WithPrecision(core.PRECISION)
+	fee := new(apd.Decimal)
+	if _, err := c.Mul(fee, amount, feeRatio); err != nil {
+		return nil, err
+	}
+	return core.DecimalToBigDotInt(fee, true)
+}
+
+// netValueY return netY + netX * sqrtPrice**2
+func netValueY(netX, netY *big.Int, sqrtPrice *apd.Decimal) (*big.Int, error) {
+	x, err := core.BigDotIntToDecimal(netX)
+	if err != nil {
+		return nil, err
+	}
+	y, err := core.BigDotIntToDecimal(netY)
+	if err != nil {
+		return nil, err
+	}
+
+	c := apd.BaseContext.WithPrecision(core.PRECISION)
+	value := new(apd.Decimal)
+	if _, err := c.Mul(value, x, sqrtPrice); err != nil {
+		return nil, err
+	}
+	if _, err := c.Mul(value, value, sqrtPrice); err != nil {
+		return nil, err
+	}
+	if _, err := c.Add(value, value, y); err != nil {
+		return nil, err
+	}
+
+	// DecimalToBigDotInt does not support negative numbers
+	negative := value.Negative
+	value.Abs(value)
+	v, err := core.DecimalToBigDotInt(value, false)
+	if err != nil {
+		return nil, err
+	}
+	if negative {
+		v.Neg(v)
+	}
+	return v, nil
+}
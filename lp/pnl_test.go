@@ -0,0 +1,89 @@
+package lp
+
+import (
+	"testing"
+
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/permadao/permaswap/lp/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLpsPnL(t *testing.T) {
+	lp := testStrategyLp(t, "1", "2", "4", "100000")
+	lpID := lp.ID()
+
+	fills := []*schema.Fill{
+		{OrderHash: "0xorder1", LpID: lpID, PoolID: lp.PoolID, TokenIn: lp.TokenXTag, AmountIn: "1000", Fee: "3",
+			TokenOut: lp.TokenYTag, AmountOut: "3000", Timestamp: 1000},
+		{OrderHash: "0xorder1", LpID: "0x1", PoolID: lp.PoolID, TokenIn: lp.TokenXTag, AmountIn: "1000", Fee: "3", Timestamp: 1000},
+		{OrderHash: "0xorder2", LpID: lpID, PoolID: lp.PoolID, TokenIn: lp.TokenYTag, AmountIn: "2000", Fee: "6",
+			TokenOut: lp.TokenXTag, AmountOut: "400", Timestamp: 2000},
+	}
+
+	pnl, err := lpsPnL(map[string]coreSchema.Lp{lpID: lp}, nil, fills)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(pnl))
+
+	res := pnl[lpID]
+	assert.Equal(t, []string{lpID}, res.LpIDs)
+	assert.Equal(t, 2, res.Orders)
+	assert.Equal(t, "1000", res.InX)
+	assert.Equal(t, "400", res.OutX)
+	assert.Equal(t, "2000", res.InY)
+	assert.Equal(t, "3000", res.OutY)
+	assert.Equal(t, "3", res.FeeX)
+	assert.Equal(t, "6", res.FeeY)
+	// -1000 + 600 * 2**2
+	assert.Equal(t, "1400", res.NetValueY)
+
+	// lp is replaced by modify, flows of the old lp are kept in its position
+	newLp := testStrategyLp(t, "1.5", "2", "4", "100000")
+	newLpID := newLp.ID()
+	fills = append(fills, &schema.Fill{OrderHash: "0xorder3", LpID: newLpID, PoolID: lp.PoolID, TokenIn: lp.TokenXTag, AmountIn: "1000", Fee: "3",
+		TokenOut: lp.TokenYTag, AmountOut: "3000", Timestamp: 3000})
+	pnl, err = lpsPnL(map[string]coreSchema.Lp{newLpID: newLp}, map[string]string{newLpID: lpID}, fills)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(pnl))
+	res = pnl[lpID]
+	assert.Equal(t, []string{newLpID}, res.LpIDs)
+	assert.Equal(t, 3, res.Orders)
+	assert.Equal(t, "2000", res.InX)
+	assert.Equal(t, "6000", res.OutY)
+
+	// fills in time range from db
+	wdb := NewWDB(t.TempDir())
+	wdb.Migrate()
+	assert.NoError(t, wdb.CreateFills(fills, nil))
+	fills, err = wdb.GetFills(schema.FillQuery{PoolID: lp.PoolID, Start: 2000, End: 3000}, 0, 0)
+	assert.NoError(t, err)
+	pnl, err = lpsPnL(map[string]coreSchema.Lp{newLpID: newLp}, map[string]string{newLpID: lpID}, fills)
+	assert.NoError(t, err)
+	res = pnl[lpID]
+	assert.Equal(t, 1, res.Orders)
+	assert.Equal(t, "0", res.InX)
+	assert.Equal(t, "2000", res.InY)
+
+	// no fill
+	pnl, err = lpsPnL(map[string]coreSchema.Lp{lpID: lp}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "0", pnl[lpID].NetValueY)
+	assert.Equal(t, 0, pnl[lpID].Orders)
+}
+
+func TestSucceedLp(t *testing.T) {
+	wdb := NewWDB(t.TempDir())
+	wdb.Migrate()
+	l := &Lp{wdb: wdb}
+
+	l.succeedLp("lp1", "lp2")
+	l.succeedLp("lp2", "lp3")
+	l.succeedLp("lp4", "lp4")
+
+	positions, err := wdb.GetPositions()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"lp2": "lp1", "lp3": "lp1"}, positions)
+
+	p, err := wdb.GetPosition("lp4")
+	assert.NoError(t, err)
+	assert.Equal(t, "lp4", p)
+}
@@ -0,0 +1,295 @@
+package lp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	apd "github.com/cockroachdb/apd/v3"
+	"github.com/permadao/permaswap/core"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"gopkg.in/h2non/gentleman.v2"
+)
+
+// PriceFeed return usd prices of tokens, token tag -> price
+type PriceFeed interface {
+	GetPrices() (map[string]float64, error)
+}
+
+// NewPriceFeed return http feed for url, file feed for others.
+// both are json object of token tag -> usd price.
+func NewPriceFeed(source string) PriceFeed {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return &HTTPPriceFeed{url: source, cli: gentleman.New()}
+	}
+	return &FilePriceFeed{path: source}
+}
+
+type FilePriceFeed struct {
+	path string
+}
+
+func (f *FilePriceFeed) GetPrices() (map[string]float64, error) {
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := map[string]float64{}
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+type HTTPPriceFeed struct {
+	url string
+	cli *gentleman.Client
+}
+
+func (f *HTTPPriceFeed) GetPrices() (map[string]float64, error) {
+	res, err := f.cli.Request().URL(f.url).Send()
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	prices := map[string]float64{}
+	if err := json.Unmarshal(res.Bytes(), &prices); err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// ids of lps removed by price guard
+const guardedLpsFile = "guarded_lps.json"
+
+// SetPriceGuard remove lps whose pool price diverges from feed beyond threshold, e.g. 0.05 for 5%.
+// removed lps are added back when pool price converges.
+func (l *Lp) SetPriceGuard(feed PriceFeed, threshold float64, interval time.Duration) {
+	l.priceFeed = feed
+	l.priceThreshold = threshold
+	l.priceInterval = interval
+}
+
+func (l *Lp) runPriceFeed() {
+	ticker := time.NewTicker(l.priceInterval)
+	defer ticker.Stop()
+
+	for {
+		prices, err := l.priceFeed.GetPrices()
+		if err != nil {
+			log.Warn("failed to get prices from feed", "err", err)
+		} else {
+			select {
+			case l.feedPrices <- prices:
+			case <-l.close:
+				return
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-l.close:
+			return
+		}
+	}
+}
+
+// priceGuardProc get market prices of pools of lps and guarded lps off the process,
+// lps are removed or added back by guardPricesProc
+func (l *Lp) priceGuardProc(prices map[string]float64) {
+	l.prices = prices
+	if l.guarding {
+		return
+	}
+
+	poolLps := l.poolLps()
+	for _, lp := range l.guardedLps {
+		poolLps[lp.PoolID] = append(poolLps[lp.PoolID], lp)
+	}
+	if len(poolLps) == 0 {
+		return
+	}
+
+	l.guarding = true
+	go func() {
+		prices := map[string]*apd.Decimal{}
+		for poolID, lps := range poolLps {
+			price, err := l.marketSqrtPrice(poolID, lps)
+			if err != nil {
+				log.Warn("can not get market price", "poolID", poolID, "err", err)
+				continue
+			}
+			prices[poolID] = price
+		}
+
+		select {
+		case l.guardPrices <- prices:
+		case <-l.close:
+		}
+	}()
+}
+
+// guardPricesProc remove lps at risk by market prices of pools, pool id -> sqrt price, and add back guarded lps not at risk
+func (l *Lp) guardPricesProc(prices map[string]*apd.Decimal) {
+	l.guarding = false
+	l.poolPrices = prices
+
+	changed := false
+	defer func() {
+		if !changed {
+			return
+		}
+		if err := l.saveGuardedLps(); err != nil {
+			log.Error("can not save guarded lps", "err", err)
+		}
+	}()
+
+	// remove lps at risk
+	for _, lp := range l.core.GetLps(l.rsdk.AccID) {
+		if !l.isLpAtRisk(lp) {
+			continue
+		}
+
+		lpID := lp.ID()
+		log.Warn("pool price diverges from feed, remove lp", "lpID", lpID, "price", l.poolPrice(lp))
+		res := l.removeLpProc(lpID)
+		if res.Result != "ok" {
+			log.Error("failed to remove lp at risk", "lpID", lpID, "result", res.Result, "err", res.Error)
+			continue
+		}
+		l.guardedLps[lpID] = lp
+		changed = true
+		if err := l.UpdateLiquidity(); err != nil {
+			log.Error("can not update liquidity config file", "err", err)
+		}
+	}
+
+	// add back lps which price converges
+	for lpID, lp := range l.guardedLps {
+		if l.isLpAtRisk(lp) {
+			continue
+		}
+
+		log.Info("pool price converges to feed, add lp back", "lpID", lpID, "price", l.poolPrice(lp))
+		msg := LpToAddMsg(lp)
+		res := l.addLpProc(&msg)
+		if res.Result != "ok" {
+			log.Error("failed to add back lp", "lpID", lpID, "result", res.Result, "err", res.Error)
+			continue
+		}
+		delete(l.guardedLps, lpID)
+		changed = true
+	}
+}
+
+// poolPrice return price implied by market price of pool of lp, current price of lp if no market price
+func (l *Lp) poolPrice(lp coreSchema.Lp) string {
+	sqrtPrice := lp.CurrentSqrtPrice
+	if p, ok := l.poolPrices[lp.PoolID]; ok {
+		sqrtPrice = p
+	}
+	price, _ := core.SqrtPriceToPrice(*sqrtPrice)
+	return price
+}
+
+// isLpAtRisk return true if lp sells tokenX or buys tokenX at price of pool beyond threshold from the feed price
+func (l *Lp) isLpAtRisk(lp coreSchema.Lp) bool {
+	fair, ok := l.fairPrice(lp.TokenXTag, lp.TokenYTag)
+	if !ok {
+		return false
+	}
+	price, ok := new(big.Float).SetString(l.poolPrice(lp))
+	if !ok {
+		return false
+	}
+	p, _ := price.Float64()
+
+	// lp holds tokenX and pool sells it below fair price
+	if lp.CurrentSqrtPrice.Cmp(lp.HighSqrtPrice) == -1 && lp.PriceDirection != coreSchema.PriceDirectionDown &&
+		p < fair*(1-l.priceThreshold) {
+		return true
+	}
+	// lp holds tokenY and pool buys tokenX above fair price
+	if lp.CurrentSqrtPrice.Cmp(lp.LowSqrtPrice) == 1 && lp.PriceDirection != coreSchema.PriceDirectionUp &&
+		p > fair*(1+l.priceThreshold) {
+		return true
+	}
+	return false
+}
+
+// fairPrice return price of tokenX in tokenY with decimals, the same unit as lp price
+func (l *Lp) fairPrice(tokenX, tokenY string) (float64, bool) {
+	priceX, okX := l.prices[tokenX]
+	priceY, okY := l.prices[tokenY]
+	tX, okTX := l.tokens[tokenX]
+	tY, okTY := l.tokens[tokenY]
+	if !okX || !okY || !okTX || !okTY || priceX <= 0 || priceY <= 0 {
+		return 0, false
+	}
+	return priceX / priceY * math.Pow(10, float64(tY.Decimals-tX.Decimals)), true
+}
+
+// isOrderAtRisk return true if any lp of the client in paths is at risk
+func (l *Lp) isOrderAtRisk(paths []coreSchema.Path) bool {
+	for _, path := range paths {
+		if lp, ok := l.core.Lps[path.LpID]; ok && l.isLpAtRisk(*lp) {
+			return true
+		}
+	}
+	return false
+}
+
+// saveGuardedLps save ids of guarded lps, they are kept in config but not registered on restart
+func (l *Lp) saveGuardedLps() error {
+	path, err := l.getFilePath(guardedLpsFile)
+	if err != nil {
+		return err
+	}
+
+	ids := []string{}
+	for lpID := range l.guardedLps {
+		ids = append(ids, lpID)
+	}
+	sort.Strings(ids)
+	by, err := json.MarshalIndent(ids, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, by, 0644)
+}
+
+// loadGuardedLps move guarded lps loaded from config out of core
+func (l *Lp) loadGuardedLps() error {
+	path, err := l.getFilePath(guardedLpsFile)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	ids := []string{}
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return err
+	}
+	for _, lpID := range ids {
+		lp, err := l.core.RemoveLiquidityByID(lpID)
+		if err != nil {
+			log.Warn("guarded lp not found in config", "lpID", lpID)
+			continue
+		}
+		l.guardedLps[lpID] = *lp
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package lp
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	apd "github.com/cockroachdb/apd/v3"
+	everSchema "github.com/everVision/everpay-kits/schema"
+	"github.com/permadao/permaswap/core"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilePriceFeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"ethereum-eth-0x0000000000000000000000000000000000000000":2000.5}`), 0644))
+
+	feed := NewPriceFeed(path)
+	prices, err := feed.GetPrices()
+	assert.NoError(t, err)
+	assert.Equal(t, 2000.5, prices["ethereum-eth-0x0000000000000000000000000000000000000000"])
+
+	_, ok := NewPriceFeed("https://example.com/prices").(*HTTPPriceFeed)
+	assert.True(t, ok)
+}
+
+func TestIsLpAtRisk(t *testing.T) {
+	// lp price is 1 in range [0.25, 4]
+	lp := testStrategyLp(t, "0.5", "1", "2", "100000")
+	l := &Lp{
+		tokens: map[string]*everSchema.Token{
+			lp.TokenXTag: {Decimals: 18},
+			lp.TokenYTag: {Decimals: 18},
+		},
+		prices:         map[string]float64{},
+		priceThreshold: 0.05,
+	}
+
+	// no price
+	assert.False(t, l.isLpAtRisk(lp))
+
+	l.prices[lp.TokenXTag] = 1
+	l.prices[lp.TokenYTag] = 1
+	assert.False(t, l.isLpAtRisk(lp))
+
+	// lp sells tokenX too cheap
+	l.prices[lp.TokenXTag] = 1.1
+	assert.True(t, l.isLpAtRisk(lp))
+	// lp buys tokenX too dear
+	l.prices[lp.TokenXTag] = 0.9
+	assert.True(t, l.isLpAtRisk(lp))
+	l.prices[lp.TokenXTag] = 0.96
+	assert.False(t, l.isLpAtRisk(lp))
+
+	// lp only holds tokenY, it is safe when tokenX is dearer
+	lp = testStrategyLp(t, "0.5", "2", "2", "100000")
+	l.prices[lp.TokenXTag] = 8
+	assert.False(t, l.isLpAtRisk(lp))
+	l.prices[lp.TokenXTag] = 3
+	assert.True(t, l.isLpAtRisk(lp))
+
+	// decimals
+	lp = testStrategyLp(t, "0.5", "1", "2", "100000")
+	l.tokens[lp.TokenYTag].Decimals = 6
+	l.prices[lp.TokenXTag] = 1e12
+	assert.False(t, l.isLpAtRisk(lp))
+
+	// price of pool is used instead of price of lp, e.g. lp removed by guard
+	l.tokens[lp.TokenYTag].Decimals = 18
+	l.prices[lp.TokenXTag] = 1
+	l.poolPrices = map[string]*apd.Decimal{}
+	l.poolPrices[lp.PoolID], _, _ = apd.NewFromString("1.1")
+	assert.True(t, l.isLpAtRisk(lp))
+	l.prices[lp.TokenXTag] = 1.2
+	assert.False(t, l.isLpAtRisk(lp))
+}
+
+func TestGuardedLps(t *testing.T) {
+	lp := testStrategyLp(t, "0.5", "1", "2", "100000")
+	pool, err := core.NewPool(lp.TokenXTag, lp.TokenYTag, "0.003")
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	l := &Lp{
+		configPath: filepath.Join(dir, "config.json"),
+		core:       core.New(map[string]*coreSchema.Pool{pool.ID(): pool}, "", ""),
+		guardedLps: map[string]coreSchema.Lp{},
+	}
+	// no guarded lps
+	assert.NoError(t, l.loadGuardedLps())
+
+	l.guardedLps[lp.ID()] = lp
+	assert.NoError(t, l.saveGuardedLps())
+
+	// guarded lps loaded from config are not in core on restart, so they are not registered
+	l.guardedLps = map[string]coreSchema.Lp{}
+	assert.NoError(t, l.core.AddLiquidityByLp(&lp))
+	assert.NoError(t, l.loadGuardedLps())
+	assert.Equal(t, 0, len(l.core.Lps))
+	guarded, ok := l.guardedLps[lp.ID()]
+	assert.True(t, ok)
+	assert.Equal(t, lp.CurrentSqrtPrice.String(), guarded.CurrentSqrtPrice.String())
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/permadao/permaswap/lp/schema"
 
 	"github.com/permadao/permaswap/router"
+	"gorm.io/gorm"
 
 	//"github.com/permadao/permaswap/lp/schema"
 	routerSchema "github.com/permadao/permaswap/router/schema"
@@ -22,18 +23,36 @@ func (l *Lp) runProcess() {
 	for {
 		select {
 
-		case msg := <-l.rsdk.SubscribeOrder():
-			l.processOrder(msg)
+		case order := <-l.routerOrder:
+			l.processOrder(order.router, order.msg)
 
-		case msg := <-l.rsdk.SubscribeOrderStatus():
+		case msg := <-l.routerOrderStatus:
 			l.processOrderStatus(*msg)
 
-		case <-l.rsdk.SubscribeReconnect():
-			l.reconnect()
+		case res := <-l.orderConfirmed:
+			l.orderConfirmedProc(res)
+			l.rebalance()
 
-		case tx := <-l.sub.Subscribe():
+		case prices := <-l.marketPrices:
+			l.marketPricesProc(prices)
+
+		case prices := <-l.guardPrices:
+			l.guardPricesProc(prices)
+
+		case r := <-l.routerReconnect:
+			if err := l.reconnect(r); err != nil {
+				log.Error("failed to resync with router after reconnect", "router", r.address, "err", err)
+			}
+
+		case tx := <-l.routerTx:
 			l.processRouterOrder(tx)
 
+		case balances := <-l.balanceUpdate:
+			l.balanceProc(balances)
+
+		case prices := <-l.feedPrices:
+			l.priceGuardProc(prices)
+
 		// api
 		case <-l.apiInfoReq:
 			l.apiInfoRes <- l.getInfo()
@@ -44,6 +63,17 @@ func (l *Lp) runProcess() {
 		case lpMsgAdd := <-l.apiAddLpReq:
 			l.apiAddLpRes <- l.addLpProc(lpMsgAdd)
 
+		case req := <-l.apiModifyLpReq:
+			l.apiModifyLpRes <- l.modifyLpProc(req)
+
+		case paused := <-l.apiPauseReq:
+			l.paused = paused
+			log.Info("lp pause changed", "paused", paused)
+			l.apiPauseRes <- &schema.PauseRes{Paused: l.paused}
+
+		case <-l.apiPendingOrdersReq:
+			l.apiPendingOrdersRes <- l.pendingOrders()
+
 		case <-l.close:
 			log.Info("process closed")
 			close(l.closed)
@@ -52,14 +82,20 @@ func (l *Lp) runProcess() {
 	}
 }
 
-func (l *Lp) processOrder(msg *routerSchema.LpMsgOrder) {
-	// proce order one by one
-	if l.order != nil {
-		// notice router processing
-		log.Warn("order is processing", "orderHash", l.order.Bundle.HashHex())
+func (l *Lp) processOrder(r *Router, msg *routerSchema.LpMsgOrder) {
+	orderHash := msg.Bundle.HashHex()
+	if _, ok := l.orders[orderHash]; ok {
+		log.Warn("order is processing", "orderHash", orderHash)
+		return
+	}
+
+	if l.paused {
+		log.Warn("lp is paused, reject order", "orderHash", orderHash)
+		if err := r.rsdk.RejectOrder(*msg); err != nil {
+			log.Error("order reject failed", "err", err)
+		}
 		return
 	}
-	l.order = msg
 
 	paths := msg.Paths
 	if err := router.VerifyBundleAndPaths(msg.Bundle, paths, l.tokens); err != nil {
@@ -70,40 +106,106 @@ func (l *Lp) processOrder(msg *routerSchema.LpMsgOrder) {
 	log.Debug("pathFilter", "originPaths", paths)
 	paths = pathsFilter(l.rsdk.AccID, paths)
 	log.Debug("pathFilter", "resPaths", paths)
+
+	// lps in processing orders are reserved, they can not be signed again until the orders finish
+	for _, path := range paths {
+		if hash, ok := l.reservedLps[path.LpID]; ok {
+			log.Warn("lp is reserved by processing order, reject order", "lpID", path.LpID, "processingOrderHash", hash, "orderHash", orderHash)
+			if err := r.rsdk.RejectOrder(*msg); err != nil {
+				log.Error("order reject failed", "err", err)
+			}
+			return
+		}
+	}
+
 	if err := l.core.Verify(msg.UserAddr, paths); err != nil {
 		log.Error("order verify failed", "err", err)
-		if err := l.rsdk.RejectOrder(*msg); err != nil {
+		if err := r.rsdk.RejectOrder(*msg); err != nil {
 			log.Error("order reject failed", "err", err)
 		}
 		return
 	}
 
-	l.savePendingOrder(msg)
+	if err := l.checkOrderBalance(paths); err != nil {
+		log.Warn("lp balance is not enough, reject order", "orderHash", orderHash, "err", err)
+		if err := r.rsdk.RejectOrder(*msg); err != nil {
+			log.Error("order reject failed", "err", err)
+		}
+		return
+	}
 
-	if err := l.rsdk.SignOrder(*msg); err != nil {
+	if l.isOrderAtRisk(paths) {
+		log.Warn("lp price diverges from feed, reject order", "orderHash", orderHash)
+		if err := r.rsdk.RejectOrder(*msg); err != nil {
+			log.Error("order reject failed", "err", err)
+		}
+		return
+	}
+
+	l.orders[orderHash] = msg
+	l.orderRouters[orderHash] = r
+	l.orderCursors[orderHash] = l.txCursor
+	for _, path := range paths {
+		l.reservedLps[path.LpID] = orderHash
+	}
+	if err := l.savePendingOrders(); err != nil {
+		log.Error("failed to save pending orders", "err", err)
+	}
+
+	if err := r.rsdk.SignOrder(*msg); err != nil {
 		log.Error("order sign failed", "err", err)
 	}
 }
 
-func (l *Lp) processOrderStatus(msg routerSchema.OrderMsgStatus) {
-	// clean cache
-	defer func() { l.order = nil }()
+// releaseOrder remove order and its reserved lps
+func (l *Lp) releaseOrder(orderHash string) {
+	delete(l.orders, orderHash)
+	delete(l.confirmingOrders, orderHash)
+	delete(l.orderRouters, orderHash)
+	delete(l.orderCursors, orderHash)
+	for lpID, hash := range l.reservedLps {
+		if hash == orderHash {
+			delete(l.reservedLps, lpID)
+		}
+	}
+	if err := l.savePendingOrders(); err != nil {
+		log.Error("failed to save pending orders", "err", err)
+	}
+}
 
-	if l.order == nil {
+func (l *Lp) processOrderStatus(msg routerSchema.OrderMsgStatus) {
+	order, ok := l.orders[msg.OrderHash]
+	if !ok {
 		return
 	}
-
-	if l.order.Bundle.HashHex() != msg.OrderHash {
-		log.Warn("invalid orderHash", "curOrderHash", l.order.Bundle.HashHex(), "orderHash", msg.OrderHash)
+	if l.confirmingOrders[msg.OrderHash] {
 		return
 	}
 
 	if msg.EverHash == "" {
 		log.Warn("No everHash", "everHash", msg.EverHash, "status", msg.Status)
+		l.releaseOrder(msg.OrderHash)
 		return
 	}
 
-	// verify order on everPay with retry
+	// confirm order on everpay without blocking other orders
+	l.confirmingOrders[msg.OrderHash] = true
+	go func() {
+		l.orderConfirmed <- l.confirmOrder(msg, order)
+	}()
+}
+
+type orderResult struct {
+	status  routerSchema.OrderMsgStatus
+	order   *routerSchema.LpMsgOrder
+	tx      everSchema.TxResponse
+	success bool
+}
+
+// confirmOrder verify order on everPay with retry
+func (l *Lp) confirmOrder(msg routerSchema.OrderMsgStatus, order *routerSchema.LpMsgOrder) *orderResult {
+	res := &orderResult{status: msg, order: order}
+
 	tx, bundle, status, err := l.rsdk.EverSDK.Cli.BundleByHash(msg.EverHash)
 	if err != nil {
 		log.Error("can not get submited bundle tx in first time", "everHash", msg.EverHash, "err", err)
@@ -119,39 +221,61 @@ func (l *Lp) processOrderStatus(msg routerSchema.OrderMsgStatus) {
 		log.Warn("bundle tx after retry", "everHash", msg.EverHash, "err", err)
 	}
 
-	if l.order.Bundle.HashHex() != bundle.HashHex() {
-		log.Warn("invalid orderHash", "curOrderHash", l.order.Bundle.HashHex(), "bundleHash", bundle.HashHex)
-		return
+	if order.Bundle.HashHex() != bundle.HashHex() {
+		log.Warn("invalid orderHash", "curOrderHash", order.Bundle.HashHex(), "bundleHash", bundle.HashHex())
+		return res
 	}
 	if status.Status != everSchema.InternalStatusSuccess {
-		log.Warn("order failed", "curOrderHash", l.order.Bundle.HashHex(), "status", status.Status)
-		return
+		log.Warn("order failed", "curOrderHash", order.Bundle.HashHex(), "status", status.Status)
+		return res
 	}
 
-	if err = l.removePendingOrder(); err != nil {
-		log.Error("failed remove pending order", "err", err)
-	}
+	res.tx = tx
+	res.success = true
+	return res
+}
 
-	if err = l.saveLatestOrder(tx); err != nil {
-		log.Error("failed to save latest order", "err", err)
+func (l *Lp) orderConfirmedProc(res *orderResult) {
+	orderHash := res.order.Bundle.HashHex()
+	// order may be finished by pending orders process after reconnect
+	if _, ok := l.orders[orderHash]; !ok {
+		return
 	}
+	defer l.releaseOrder(orderHash)
+	defer l.refreshBalance()
 
-	go l.saveOrder(msg, l.order)
+	if !res.success {
+		l.saveOrderCursor(orderHash, nil)
+		return
+	}
+	l.saveOrderCursor(orderHash, &res.tx)
 
 	// if order is success update
-	paths := pathsFilter(l.rsdk.AccID, l.order.Paths)
-	if err := l.core.Update(l.order.UserAddr, paths); err != nil {
+	paths := pathsFilter(l.rsdk.AccID, res.order.Paths)
+
+	// fills are calculated with lps before update
+	fills, err := orderFills(l.rsdk.AccID, l.core.Lps, l.tokens, orderHash, res.tx, paths)
+	if err != nil {
+		log.Error("failed to get fills of order", "orderHash", orderHash, "err", err)
+	}
+	go l.saveOrder(res.status, res.order, fills)
+
+	if err := l.core.Update(res.order.UserAddr, paths); err != nil {
 		log.Error("core update failed", "err", err)
 	}
-	log.Info("core update success")
+	log.Info("core update success", "orderHash", orderHash)
+	l.resyncLps(l.orderRouters[orderHash], paths)
 
-	if err = l.UpdateLiquidity(); err != nil {
+	if err := l.UpdateLiquidity(); err != nil {
 		log.Error("can not update liquidity config", "err", err)
 	}
 }
 
 func (l *Lp) processRouterOrder(tx everSchema.TxResponse) {
-	if l.order == nil {
+	if tx.RawId > l.txCursor {
+		l.txCursor = tx.RawId
+	}
+	if len(l.orders) == 0 {
 		return
 	}
 	bundleData := everSchema.BundleData{}
@@ -162,7 +286,7 @@ func (l *Lp) processRouterOrder(tx everSchema.TxResponse) {
 	}
 
 	orderHash := bundleData.Bundle.Bundle.HashHex()
-	if orderHash == l.order.Bundle.HashHex() {
+	if _, ok := l.orders[orderHash]; ok {
 		msg := routerSchema.OrderMsgStatus{
 			Event:     "status",
 			OrderHash: orderHash,
@@ -174,8 +298,8 @@ func (l *Lp) processRouterOrder(tx everSchema.TxResponse) {
 }
 
 func (l *Lp) removeLpProc(lpID string) *schema.RemoveLpRes {
-	if l.order != nil {
-		log.Error("order is processing, so can not update lp.", "orderHash", l.order.Bundle.HashHex())
+	if orderHash, ok := l.reservedLps[lpID]; ok {
+		log.Error("order is processing, so can not update lp.", "orderHash", orderHash)
 		return &schema.RemoveLpRes{
 			LpID:   lpID,
 			Result: "failed",
@@ -191,25 +315,33 @@ func (l *Lp) removeLpProc(lpID string) *schema.RemoveLpRes {
 		}
 	}
 	lp := *l.core.Lps[lpID]
-	l.rsdk.RemoveLiquidity(LpToRemoveMsg(lp))
 
-	// wait and process lp remove response
-	resp := <-l.rsdk.SubscribeLpRemoveResponseOnce()
-	if resp.LpID != "" && resp.LpID != lpID {
-		log.Error("Lp remove resopnse wiht invalid lpid", "LpRemoveResponse", resp)
-		return &schema.RemoveLpRes{
-			LpID:   lpID,
-			Result: "failed",
-			Error:  "err_invalid_response",
+	// lp is added back to routers removed if any router failed
+	removed := []*Router{}
+	for _, r := range l.routers {
+		if !r.hasPool(lp.PoolID) {
+			continue
 		}
-	}
 
-	if resp.Msg != "ok" {
-		return &schema.RemoveLpRes{
-			LpID:   lpID,
-			Result: resp.Msg,
-			Error:  resp.Error,
+		res := &schema.RemoveLpRes{LpID: lpID}
+		resp := r.removeLp(LpToRemoveMsg(lp))
+		if resp.LpID != "" && resp.LpID != lpID {
+			log.Error("Lp remove resopnse wiht invalid lpid", "LpRemoveResponse", resp)
+			res.Result, res.Error = "failed", "err_invalid_response"
+		} else if resp.Msg != "ok" {
+			res.Result, res.Error = resp.Msg, resp.Error
+		}
+		if res.Result == "" {
+			removed = append(removed, r)
+			continue
+		}
+
+		for _, r := range removed {
+			if resp := r.addLp(LpToAddMsg(lp)); resp.Msg != "ok" {
+				log.Error("failed to add lp back", "router", r.address, "lpID", lpID, "result", resp.Msg, "err", resp.Error)
+			}
 		}
+		return res
 	}
 
 	if _, err := l.core.RemoveLiquidityByID(lpID); err != nil {
@@ -231,14 +363,6 @@ func (l *Lp) removeLpProc(lpID string) *schema.RemoveLpRes {
 }
 
 func (l *Lp) addLpProc(msg *routerSchema.LpMsgAdd) *schema.AddLpRes {
-	if l.order != nil {
-		log.Error("order is processing, so can not update lp.", "orderHash", l.order.Bundle.HashHex())
-		return &schema.AddLpRes{
-			Result: "failed",
-			Error:  "err_can_not_update_lp_with_order",
-		}
-	}
-
 	pool, err := l.core.FindPool(msg.TokenX, msg.TokenY, msg.FeeRatio)
 	if err != nil {
 		return &schema.AddLpRes{
@@ -248,6 +372,14 @@ func (l *Lp) addLpProc(msg *routerSchema.LpMsgAdd) *schema.AddLpRes {
 	}
 
 	lpID := core.GetLpID(pool.ID(), l.rsdk.AccID, msg.LowSqrtPrice, msg.HighSqrtPrice, msg.PriceDirection)
+	if orderHash, ok := l.reservedLps[lpID]; ok {
+		log.Error("order is processing, so can not update lp.", "orderHash", orderHash)
+		return &schema.AddLpRes{
+			LpID:   lpID,
+			Result: "failed",
+			Error:  "err_can_not_update_lp_with_order",
+		}
+	}
 	if _, ok := l.core.Lps[lpID]; ok {
 		return &schema.AddLpRes{
 			Result: "failed",
@@ -275,11 +407,24 @@ func (l *Lp) addLpProc(msg *routerSchema.LpMsgAdd) *schema.AddLpRes {
 		}
 	}
 
-	l.rsdk.AddLiquidity(*msg)
+	// lp is removed from routers added if any router failed
+	added := []*Router{}
+	for _, r := range l.routers {
+		if !r.hasPool(pool.ID()) {
+			continue
+		}
+
+		resp := r.addLp(*msg)
+		if resp.Msg == "ok" {
+			added = append(added, r)
+			continue
+		}
 
-	// wait and process lp remove response
-	resp := <-l.rsdk.SubscribeLpAddResponseOnce()
-	if resp.Msg != "ok" {
+		for _, r := range added {
+			if resp := r.removeLp(LpToRemoveMsg(*lpToAdd)); resp.Msg != "ok" {
+				log.Error("failed to remove added lp", "router", r.address, "lpID", lpID, "result", resp.Msg, "err", resp.Error)
+			}
+		}
 		return &schema.AddLpRes{
 			LpID:   resp.LpID,
 			Result: resp.Msg,
@@ -298,12 +443,90 @@ func (l *Lp) addLpProc(msg *routerSchema.LpMsgAdd) *schema.AddLpRes {
 	}
 
 	return &schema.AddLpRes{
-		LpID:   resp.LpID,
+		LpID:   lpID,
 		Result: "ok",
 		Error:  "",
 	}
 }
 
+// modifyLpProc replace lp with modified one, the old lp is added back if new one failed
+func (l *Lp) modifyLpProc(req *schema.ModifyLpReq) *schema.ModifyLpRes {
+	res := &schema.ModifyLpRes{LpID: req.LpID, Result: "failed"}
+
+	lp, ok := l.core.Lps[req.LpID]
+	if !ok {
+		res.Error = "err_invalid_lpid"
+		return res
+	}
+	if orderHash, ok := l.reservedLps[req.LpID]; ok {
+		log.Error("order is processing, so can not update lp.", "orderHash", orderHash)
+		res.Error = "err_can_not_update_lp_with_order"
+		return res
+	}
+
+	old := LpToAddMsg(*lp)
+	msg := LpToAddMsg(*lp)
+	if req.LowSqrtPrice != nil {
+		msg.LowSqrtPrice = req.LowSqrtPrice
+	}
+	if req.CurrentSqrtPrice != nil {
+		msg.CurrentSqrtPrice = req.CurrentSqrtPrice
+	}
+	if req.HighSqrtPrice != nil {
+		msg.HighSqrtPrice = req.HighSqrtPrice
+	}
+	if req.Liquidity != "" {
+		msg.Liquidity = req.Liquidity
+	}
+	if req.PriceDirection != "" {
+		msg.PriceDirection = req.PriceDirection
+	}
+
+	if _, err := core.NewLp(lp.PoolID, msg.TokenX, msg.TokenY, l.rsdk.AccID,
+		msg.FeeRatio, msg.LowSqrtPrice, msg.CurrentSqrtPrice, msg.HighSqrtPrice,
+		msg.Liquidity, msg.PriceDirection); err != nil {
+		res.Error = "err_invalid_lp"
+		return res
+	}
+	if err := l.checkRebalanceBalance([]coreSchema.Lp{*lp}, []routerSchema.LpMsgAdd{msg}); err != nil {
+		res.Error = "err_balance_not_enough"
+		return res
+	}
+
+	if removeRes := l.removeLpProc(req.LpID); removeRes.Result != "ok" {
+		res.Error = removeRes.Error
+		return res
+	}
+	addRes := l.addLpProc(&msg)
+	if addRes.Result != "ok" {
+		log.Error("failed to add modified lp, add old lp back", "lpID", req.LpID, "result", addRes.Result, "err", addRes.Error)
+		if oldRes := l.addLpProc(&old); oldRes.Result != "ok" {
+			log.Error("failed to add old lp back", "lpID", req.LpID, "result", oldRes.Result, "err", oldRes.Error)
+		}
+		res.Error = addRes.Error
+		return res
+	}
+
+	l.succeedLp(req.LpID, addRes.LpID)
+	log.Info("lp modified", "lpID", req.LpID, "newLpID", addRes.LpID)
+	res.NewLpID = addRes.LpID
+	res.Result = "ok"
+	return res
+}
+
+func (l *Lp) pendingOrders() []schema.PendingOrder {
+	orders := []schema.PendingOrder{}
+	for hash, order := range l.orders {
+		orders = append(orders, schema.PendingOrder{
+			OrderHash:  hash,
+			UserAddr:   order.UserAddr,
+			Paths:      pathsFilter(l.rsdk.AccID, order.Paths),
+			Confirming: l.confirmingOrders[hash],
+		})
+	}
+	return orders
+}
+
 func pathsFilter(lpAccID string, paths []coreSchema.Path) (resPaths []coreSchema.Path) {
 	resPaths = []coreSchema.Path{}
 	for _, path := range paths {
@@ -318,14 +541,20 @@ func pathsFilter(lpAccID string, paths []coreSchema.Path) (resPaths []coreSchema
 	return
 }
 
-func (l *Lp) saveOrder(msg routerSchema.OrderMsgStatus, order *routerSchema.LpMsgOrder) {
+func (l *Lp) saveOrder(msg routerSchema.OrderMsgStatus, order *routerSchema.LpMsgOrder, fills []*schema.Fill) {
 	orderToSave := schema.Order{
 		UserAddr:    l.rsdk.AccID,
 		EverHash:    msg.EverHash,
 		OrderStatus: msg.Status,
 		LpMsgOrder:  string(order.Marshal()),
 	}
-	if err := l.wdb.CreateOrder(&orderToSave, nil); err != nil {
+	err := l.wdb.db.Transaction(func(tx *gorm.DB) error {
+		if err := l.wdb.CreateOrder(&orderToSave, tx); err != nil {
+			return err
+		}
+		return l.wdb.CreateFills(fills, tx)
+	})
+	if err != nil {
 		log.Error("save order to db failed", "err", err)
 	}
 }
@@ -0,0 +1,131 @@
+package lp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	everSchema "github.com/everVision/everpay-kits/schema"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	routerSchema "github.com/permadao/permaswap/router/schema"
+)
+
+// page size of txs of router scanned for orders missed during outage
+const missedOrderScanPage = 50
+
+// diffLps return lps to remove from router and lps to add to router, lps with different state are replaced
+func diffLps(local, remote []coreSchema.Lp) (toRemove, toAdd []coreSchema.Lp) {
+	localLps := map[string]coreSchema.Lp{}
+	for _, lp := range local {
+		localLps[lp.ID()] = lp
+	}
+
+	synced := map[string]bool{}
+	for _, lp := range remote {
+		id := lp.ID()
+		l, ok := localLps[id]
+		if ok && l.CurrentSqrtPrice.Cmp(lp.CurrentSqrtPrice) == 0 && l.Liquidity.Cmp(lp.Liquidity) == 0 {
+			synced[id] = true
+			continue
+		}
+		toRemove = append(toRemove, lp)
+	}
+
+	for _, lp := range local {
+		if !synced[lp.ID()] {
+			toAdd = append(toAdd, lp)
+		}
+	}
+	return
+}
+
+// resyncRouter make lps on router the same as lps of its pools in local core
+func (l *Lp) resyncRouter(r *Router) error {
+	remote, err := r.rsdk.GetLps()
+	if err != nil {
+		return err
+	}
+	local := []coreSchema.Lp{}
+	for _, lp := range l.core.GetLps(l.rsdk.AccID) {
+		if r.hasPool(lp.PoolID) {
+			local = append(local, lp)
+		}
+	}
+
+	toRemove, toAdd := diffLps(local, remote)
+	log.Info("resync lps with router", "router", r.address, "local", len(local), "remote", len(remote), "remove", len(toRemove), "add", len(toAdd))
+	for _, lp := range toRemove {
+		if res := r.removeLp(LpToRemoveMsg(lp)); res.Msg != "ok" {
+			return fmt.Errorf("remove lp %s: %s %s", lp.ID(), res.Msg, res.Error)
+		}
+	}
+	for _, lp := range toAdd {
+		if res := r.addLp(LpToAddMsg(lp)); res.Msg != "ok" {
+			return fmt.Errorf("add lp %s: %s %s", lp.ID(), res.Msg, res.Error)
+		}
+	}
+	return nil
+}
+
+// recoverOrders find processing orders of router on everPay, which status may be missed during outage.
+// all orders are checked if router is nil. orders not found are released after bundle expired.
+func (l *Lp) recoverOrders(r *Router) {
+	for orderHash, order := range l.orders {
+		if l.confirmingOrders[orderHash] {
+			continue
+		}
+		orderRouter := l.orderRouters[orderHash]
+		if r != nil && orderRouter != r {
+			continue
+		}
+
+		routers := l.routers
+		if orderRouter != nil {
+			routers = []*Router{orderRouter}
+		}
+		tx, found := l.findOrderTx(routers, orderHash, l.orderCursors[orderHash])
+		if found {
+			log.Info("found missed order on everpay", "orderHash", orderHash, "everHash", tx.EverHash)
+			l.processOrderStatus(routerSchema.OrderMsgStatus{
+				Event:     routerSchema.OrderMsgEventStatus,
+				OrderHash: orderHash,
+				EverHash:  tx.EverHash,
+			})
+			continue
+		}
+
+		if order.Bundle.Expiration < time.Now().Unix() {
+			log.Warn("order expired without submitted, release it", "orderHash", orderHash)
+			l.releaseOrder(orderHash)
+		}
+	}
+}
+
+// findOrderTx return bundle tx of order in txs of routers after cursor, the raw id of latest router tx when order arrived
+func (l *Lp) findOrderTx(routers []*Router, orderHash string, cursor int64) (everSchema.TxResponse, bool) {
+	for _, r := range routers {
+		for c := cursor; ; {
+			txs, err := l.rsdk.EverSDK.Cli.Txs(c, "asc", missedOrderScanPage, everSchema.TxOpts{
+				Address: r.address,
+			})
+			if err != nil {
+				log.Warn("failed to get txs of router", "router", r.address, "cursor", c, "err", err)
+				break
+			}
+			for _, tx := range txs.Txs {
+				bundleData := everSchema.BundleData{}
+				if err := json.Unmarshal([]byte(tx.Data), &bundleData); err != nil {
+					continue
+				}
+				if bundleData.Bundle.Bundle.HashHex() == orderHash {
+					return tx, true
+				}
+			}
+			if len(txs.Txs) < missedOrderScanPage {
+				break
+			}
+			c = txs.Txs[len(txs.Txs)-1].RawId
+		}
+	}
+	return everSchema.TxResponse{}, false
+}
@@ -0,0 +1,50 @@
+package lp
+
+import (
+	"math/big"
+	"testing"
+
+	apd "github.com/cockroachdb/apd/v3"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffLps(t *testing.T) {
+	lp1 := testStrategyLp(t, "1", "2", "4", "100000")
+	lp2 := testStrategyLp(t, "2", "3", "4", "100000")
+	lp3 := testStrategyLp(t, "0.5", "1", "2", "100000")
+
+	// lp1 is synced, lp2 is changed by a missed order, lp3 is not on router
+	lp2Remote := lp2
+	lp2Remote.CurrentSqrtPrice, _, _ = apd.NewFromString("3.5")
+	lp2Remote.Liquidity = big.NewInt(90000)
+	// trailing zeros of decimals in json are the same price
+	lp1Remote := lp1
+	lp1Remote.CurrentSqrtPrice, _, _ = apd.NewFromString("2.000")
+	// stale lp on router
+	lp4 := testStrategyLp(t, "4", "5", "8", "100000")
+
+	toRemove, toAdd := diffLps([]coreSchema.Lp{lp1, lp2, lp3}, []coreSchema.Lp{lp1Remote, lp2Remote, lp4})
+	assert.Equal(t, 2, len(toRemove))
+	assert.Equal(t, lp2.ID(), toRemove[0].ID())
+	assert.Equal(t, lp4.ID(), toRemove[1].ID())
+	assert.Equal(t, 2, len(toAdd))
+	assert.Equal(t, lp2.ID(), toAdd[0].ID())
+	assert.Equal(t, "3", toAdd[0].CurrentSqrtPrice.String())
+	assert.Equal(t, lp3.ID(), toAdd[1].ID())
+
+	toRemove, toAdd = diffLps([]coreSchema.Lp{lp1}, []coreSchema.Lp{lp1})
+	assert.Equal(t, 0, len(toRemove))
+	assert.Equal(t, 0, len(toAdd))
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		b := reconnectBackoff(0)
+		assert.True(t, b >= reconnectBackoffMin/2 && b <= reconnectBackoffMin)
+		b = reconnectBackoff(3)
+		assert.True(t, b >= 4*reconnectBackoffMin && b <= 8*reconnectBackoffMin)
+		b = reconnectBackoff(100)
+		assert.True(t, b >= reconnectBackoffMax/2 && b <= reconnectBackoffMax)
+	}
+}
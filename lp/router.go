@@ -0,0 +1,287 @@
+package lp
+
+import (
+	"time"
+
+	everSchema "github.com/everVision/everpay-kits/schema"
+	"github.com/everVision/everpay-kits/sdk"
+	"github.com/permadao/permaswap/core"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	routerSchema "github.com/permadao/permaswap/router/schema"
+)
+
+// max duration to wait response of lp add or remove from router
+var lpResponseTimeout = 10 * time.Second
+
+// Router is a router which lps are registered with.
+// lps are kept in one local core and shared by all routers of the client.
+type Router struct {
+	rsdk        *RSDK
+	pools       map[string]bool // pools to register on the router, all pools if empty
+	routerPools map[string]bool // pools supported by the router
+	address     string          // router address on everPay
+	cursor      int64           // raw id of latest tx of router when subscribed
+	sub         *sdk.SubscribeTx
+	subQuit     chan struct{}
+}
+
+func NewRouter(rsdk *RSDK, pools []string) *Router {
+	r := &Router{
+		rsdk:        rsdk,
+		pools:       make(map[string]bool),
+		routerPools: make(map[string]bool),
+	}
+	for _, p := range pools {
+		r.pools[p] = true
+	}
+	return r
+}
+
+type routerOrder struct {
+	router *Router
+	msg    *routerSchema.LpMsgOrder
+}
+
+// hasPool return true if lps of the pool are registered on the router
+func (r *Router) hasPool(poolID string) bool {
+	if !r.routerPools[poolID] {
+		return false
+	}
+	return len(r.pools) == 0 || r.pools[poolID]
+}
+
+// getInfo get router address and pools, retry until success
+func (r *Router) getInfo() map[string]*coreSchema.Pool {
+	for {
+		info, err := r.rsdk.GetInfo()
+		if err == nil {
+			pools := map[string]*coreSchema.Pool{}
+			r.address = info.RouterAddress
+			r.routerPools = make(map[string]bool)
+			for _, p := range info.PoolList {
+				pool, err := core.NewPool(p.TokenXTag, p.TokenYTag, p.FeeRatio.String())
+				if err != nil {
+					log.Error("failed to get pool from router api", "err", err)
+					panic(err)
+				}
+				pools[pool.ID()] = pool
+				r.routerPools[pool.ID()] = true
+			}
+			log.Info("Router address:", "router", r.address)
+			return pools
+		}
+		log.Warn("failed to get router address", "err", err)
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (r *Router) subscribe() {
+	latestTxRawId := int64(0)
+	txs, err := r.rsdk.EverSDK.Cli.Txs(0, "desc", 1, everSchema.TxOpts{
+		Address: r.address,
+	})
+	if err != nil {
+		log.Error("failed to get the latest tx of router")
+		panic(err)
+	}
+	if txs.Txs != nil {
+		if len(txs.Txs) > 0 {
+			latestTxRawId = txs.Txs[0].RawId
+			log.Info("latest tx of router", "rawId", latestTxRawId)
+		}
+	}
+
+	r.cursor = latestTxRawId
+	r.sub = r.rsdk.EverSDK.Cli.SubscribeTxs(everSchema.FilterQuery{
+		StartCursor: latestTxRawId,
+		Address:     r.address,
+	})
+	log.Info("Start to subscribe router's order", "routerAddress", r.address, "latestTxRawId", latestTxRawId)
+}
+
+// addLp send lp to router and wait response, it fails if router does not response in time, is closed or disconnected
+func (r *Router) addLp(msg routerSchema.LpMsgAdd) *routerSchema.LpMsgAddResponse {
+	res, err := r.rsdk.RequestAddLiquidity(msg)
+	if err != nil {
+		return &routerSchema.LpMsgAddResponse{Msg: "failed", Error: err.Error()}
+	}
+	timer := time.NewTimer(lpResponseTimeout)
+	defer timer.Stop()
+	select {
+	case resp := <-res:
+		return resp
+	case <-timer.C:
+		log.Error("add lp response timeout", "router", r.address)
+		return &routerSchema.LpMsgAddResponse{Msg: "failed", Error: ERR_ROUTER_TIMEOUT.Error()}
+	case <-r.rsdk.Closed():
+		return &routerSchema.LpMsgAddResponse{Msg: "failed", Error: ERR_ROUTER_CLOSED.Error()}
+	}
+}
+
+// removeLp remove lp from router and wait response, it fails if router does not response in time, is closed or disconnected
+func (r *Router) removeLp(msg routerSchema.LpMsgRemove) *routerSchema.LpMsgRemoveResponse {
+	res, err := r.rsdk.RequestRemoveLiquidity(msg)
+	if err != nil {
+		return &routerSchema.LpMsgRemoveResponse{Msg: "failed", Error: err.Error()}
+	}
+	timer := time.NewTimer(lpResponseTimeout)
+	defer timer.Stop()
+	select {
+	case resp := <-res:
+		return resp
+	case <-timer.C:
+		log.Error("remove lp response timeout", "router", r.address)
+		return &routerSchema.LpMsgRemoveResponse{Msg: "failed", Error: ERR_ROUTER_TIMEOUT.Error()}
+	case <-r.rsdk.Closed():
+		return &routerSchema.LpMsgRemoveResponse{Msg: "failed", Error: ERR_ROUTER_CLOSED.Error()}
+	}
+}
+
+// registerLps add lps of pools registered on the router
+func (r *Router) registerLps(lps []coreSchema.Lp) (err error) {
+	for _, lp := range lps {
+		if !r.hasPool(lp.PoolID) {
+			continue
+		}
+		if err = r.rsdk.AddLiquidity(LpToAddMsg(lp)); err != nil {
+			return
+		}
+
+		amountX, amountY, _ := core.LiquidityToAmount(lp.Liquidity.String(), lp.LowSqrtPrice, lp.CurrentSqrtPrice, lp.HighSqrtPrice, lp.PriceDirection)
+		log.Info("register liquidity", "router", r.address, "x", lp.TokenXTag, "y", lp.TokenYTag, "amountX", amountX, "amountY", amountY, "currentSqrtPrice", lp.CurrentSqrtPrice)
+		log.Info("lp price range", "low", core.LpLowPrice(lp), "current", core.LpCurrentPrice(lp), "high", core.LpHighPrice(lp), "liquidity", lp.Liquidity.String())
+	}
+	return
+}
+
+// cleanLps remove all lps of the account from router
+func (r *Router) cleanLps() (err error) {
+	lps, err := r.rsdk.GetLps()
+	if err != nil {
+		return
+	}
+
+	for {
+		for _, lp := range lps {
+			r.rsdk.RemoveLiquidity(LpToRemoveMsg(lp))
+		}
+
+		lps, err = r.rsdk.GetLps()
+		if err != nil {
+			return
+		}
+		if len(lps) == 0 {
+			log.Info("liqudity is clean", "router", r.address)
+			return
+		}
+
+		log.Warn("liquidity not clean", "router", r.address, "accID", r.rsdk.AccID, "len(lps)", len(lps))
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// runRouter forward msgs of router to process
+func (l *Lp) runRouter(r *Router) {
+	for {
+		select {
+		case msg := <-r.rsdk.SubscribeOrder():
+			select {
+			case l.routerOrder <- routerOrder{router: r, msg: msg}:
+			case <-l.close:
+				return
+			}
+		case msg := <-r.rsdk.SubscribeOrderStatus():
+			select {
+			case l.routerOrderStatus <- msg:
+			case <-l.close:
+				return
+			}
+		case <-r.rsdk.SubscribeReconnect():
+			select {
+			case l.routerReconnect <- r:
+			case <-l.close:
+				return
+			}
+		case <-l.close:
+			return
+		}
+	}
+}
+
+// runRouterTxs forward txs of router on everPay to process until router is resubscribed
+func (l *Lp) runRouterTxs(sub *sdk.SubscribeTx, quit chan struct{}) {
+	for {
+		select {
+		case tx := <-sub.Subscribe():
+			select {
+			case l.routerTx <- tx:
+			case <-quit:
+				return
+			case <-l.close:
+				return
+			}
+		case <-quit:
+			return
+		case <-l.close:
+			return
+		}
+	}
+}
+
+// subscribeRouter subscribe txs of router, the previous subscription is stopped
+func (l *Lp) subscribeRouter(r *Router) {
+	if r.sub != nil {
+		r.sub.Unsubscribe()
+		close(r.subQuit)
+	}
+	r.subscribe()
+	// raw id of everPay txs is global, txs of orders arrived later are after it
+	if r.cursor > l.txCursor {
+		l.txCursor = r.cursor
+	}
+	r.subQuit = make(chan struct{})
+	go l.runRouterTxs(r.sub, r.subQuit)
+}
+
+// AddRouter register lps of pools with another router, all pools if pools is empty
+func (l *Lp) AddRouter(rsdk *RSDK, pools []string) {
+	l.routers = append(l.routers, NewRouter(rsdk, pools))
+}
+
+// SetPools set pools registered on the first router, all pools if pools is empty
+func (l *Lp) SetPools(pools []string) {
+	l.routers[0] = NewRouter(l.rsdk, pools)
+}
+
+// resyncLps update lps changed by order on other routers.
+// lps are reserved by the order until resync finishes, so the same liquidity can not be signed by two routers.
+func (l *Lp) resyncLps(from *Router, paths []coreSchema.Path) {
+	// pending orders are processed before lps are registered
+	if from == nil {
+		return
+	}
+
+	synced := map[string]bool{}
+	for _, path := range paths {
+		lp, ok := l.core.Lps[path.LpID]
+		if !ok || synced[path.LpID] {
+			continue
+		}
+		synced[path.LpID] = true
+
+		for _, r := range l.routers {
+			if r == from || !r.hasPool(lp.PoolID) {
+				continue
+			}
+			if res := r.removeLp(LpToRemoveMsg(*lp)); res.Msg != "ok" {
+				log.Error("resync: remove lp failed", "router", r.address, "lpID", path.LpID, "result", res.Msg, "err", res.Error)
+				continue
+			}
+			if res := r.addLp(LpToAddMsg(*lp)); res.Msg != "ok" {
+				log.Error("resync: add lp failed, lp is removed from router", "router", r.address, "lpID", path.LpID, "result", res.Msg, "err", res.Error)
+				continue
+			}
+			log.Info("lp resynced", "router", r.address, "lpID", path.LpID, "currentSqrtPrice", lp.CurrentSqrtPrice)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package lp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	routerSchema "github.com/permadao/permaswap/router/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouterHasPool(t *testing.T) {
+	r := NewRouter(nil, nil)
+	r.routerPools = map[string]bool{"pool1": true, "pool2": true}
+	assert.True(t, r.hasPool("pool1"))
+	assert.True(t, r.hasPool("pool2"))
+	assert.False(t, r.hasPool("pool3"))
+
+	// subset of pools
+	r = NewRouter(nil, []string{"pool2", "pool3"})
+	r.routerPools = map[string]bool{"pool1": true, "pool2": true}
+	assert.False(t, r.hasPool("pool1"))
+	assert.True(t, r.hasPool("pool2"))
+	// not supported by router
+	assert.False(t, r.hasPool("pool3"))
+}
+
+func TestRouterLpResponse(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// router never responses
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.NoError(t, err)
+	rsdk := &RSDK{
+		wsConn: conn,
+		closed: make(chan struct{}),
+	}
+	r := NewRouter(rsdk, nil)
+
+	timeout := lpResponseTimeout
+	defer func() { lpResponseTimeout = timeout }()
+	lpResponseTimeout = 50 * time.Millisecond
+
+	res := r.addLp(routerSchema.LpMsgAdd{})
+	assert.Equal(t, ERR_ROUTER_TIMEOUT.Error(), res.Error)
+	// late response of the request timed out is not received by next request
+	resCh, err := rsdk.RequestAddLiquidity(routerSchema.LpMsgAdd{})
+	assert.NoError(t, err)
+	rsdk.sendAddResponse(conn, &routerSchema.LpMsgAddResponse{Msg: "late"})
+	rsdk.sendAddResponse(conn, &routerSchema.LpMsgAddResponse{Msg: "ok"})
+	assert.Equal(t, "ok", (<-resCh).Msg)
+
+	// responses of concurrent requests are received in order sent
+	resChs := make([]<-chan *routerSchema.LpMsgRemoveResponse, 3)
+	for i := range resChs {
+		resChs[i], err = rsdk.RequestRemoveLiquidity(routerSchema.LpMsgRemove{})
+		assert.NoError(t, err)
+	}
+	for i := range resChs {
+		rsdk.sendRemoveResponse(conn, &routerSchema.LpMsgRemoveResponse{LpID: strconv.Itoa(i)})
+	}
+	for i, resCh := range resChs {
+		assert.Equal(t, strconv.Itoa(i), (<-resCh).LpID)
+	}
+
+	// request sent on previous connection fails when response of new connection arrives
+	resCh, err = rsdk.RequestAddLiquidity(routerSchema.LpMsgAdd{})
+	assert.NoError(t, err)
+	rsdk.sendAddResponse(&websocket.Conn{}, &routerSchema.LpMsgAddResponse{Msg: "ok"})
+	assert.Equal(t, ERR_ROUTER_DISCONNECTED.Error(), (<-resCh).Error)
+
+	// pending request fails when disconnected
+	removeCh, err := rsdk.RequestRemoveLiquidity(routerSchema.LpMsgRemove{})
+	assert.NoError(t, err)
+	rsdk.failRequests(ERR_ROUTER_DISCONNECTED)
+	assert.Equal(t, ERR_ROUTER_DISCONNECTED.Error(), (<-removeCh).Error)
+
+	// request waiting is failed by close
+	lpResponseTimeout = time.Minute
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(rsdk.closed)
+	}()
+	assert.Equal(t, ERR_ROUTER_CLOSED.Error(), r.removeLp(routerSchema.LpMsgRemove{}).Error)
+}
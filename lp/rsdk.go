@@ -3,6 +3,10 @@ package lp
 import (
 	"encoding/json"
 	"errors"
+	"math/big"
+	"math/rand"
+	"net/url"
+	"sync"
 	"time"
 
 	everSchema "github.com/everVision/everpay-kits/schema"
@@ -11,46 +15,87 @@ import (
 	"github.com/gorilla/websocket"
 	coreSchema "github.com/permadao/permaswap/core/schema"
 	"github.com/permadao/permaswap/router/schema"
+	"github.com/permadao/permaswap/wshub"
 	"gopkg.in/h2non/gentleman.v2"
 )
 
+const (
+	// failover to next endpoint after continuous reconnect failures
+	failoverRetries = 3
+
+	reconnectBackoffMin = 500 * time.Millisecond
+	reconnectBackoffMax = 30 * time.Second
+)
+
+// encoding of msgs sent by routers, it is requested when rsdks connect
+var routerEncoding = wshub.EncodingJSON
+
+// SetEncoding set encoding of msgs sent by routers: json or cbor, it must be set before rsdks are created
+func SetEncoding(encoding string) error {
+	if !wshub.IsEncodingSupported(encoding) {
+		return wshub.ErrUnsupportedEncoding
+	}
+	routerEncoding = encoding
+	return nil
+}
+
 // RSDK is RouterSDK
 type RSDK struct {
 	AccID   string
-	wsURL   string
 	wsConn  *websocket.Conn
-	httpCli *gentleman.Client
 	EverSDK *sdk.SDK
 
-	order                        chan *schema.LpMsgOrder
-	orderStatus                  chan *schema.OrderMsgStatus
-	addResponse                  chan *schema.LpMsgAddResponse
-	addResponseOnceSubscribed    bool
-	removeResponse               chan *schema.LpMsgRemoveResponse
-	removeResponseOnceSubscribed bool
+	// endpoints of routers, the current one is used and others are for failover
+	endpoints []RouterEndpoint
+	current   int
+	httpCli   *gentleman.Client
+	lock      sync.RWMutex
+
+	// protocol version negotiated with router
+	protocolVersion string
+
+	order       chan *schema.LpMsgOrder
+	orderStatus chan *schema.OrderMsgStatus
+
+	// requests waiting responses in order sent, router responds add and remove of a connection in order
+	requestLock    sync.Mutex
+	addRequests    []*addRequest
+	removeRequests []*removeRequest
 
 	reconnect chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
 func NewRSDK(wsURL, httpURL string, everSDK *sdk.SDK) *RSDK {
+	return NewRSDKWithEndpoints([]RouterEndpoint{{WsURL: wsURL, HttpURL: httpURL}}, everSDK)
+}
+
+// NewRSDKWithEndpoints connect to the first available endpoint, and fail over to others when it is down
+func NewRSDKWithEndpoints(endpoints []RouterEndpoint, everSDK *sdk.SDK) *RSDK {
+	if len(endpoints) == 0 {
+		panic(ERR_NO_ROUTER)
+	}
 	r := &RSDK{
-		AccID:   everSDK.AccId,
-		wsURL:   wsURL,
-		httpCli: gentleman.New().URL(httpURL),
-		EverSDK: everSDK,
+		AccID:     everSDK.AccId,
+		EverSDK:   everSDK,
+		endpoints: endpoints,
 
 		order:       make(chan *schema.LpMsgOrder),
 		orderStatus: make(chan *schema.OrderMsgStatus),
 
-		addResponse:                  make(chan *schema.LpMsgAddResponse),
-		addResponseOnceSubscribed:    false,
-		removeResponse:               make(chan *schema.LpMsgRemoveResponse),
-		removeResponseOnceSubscribed: false,
-
 		reconnect: make(chan struct{}),
+		closed:    make(chan struct{}),
 	}
 
-	err := r.connectRouter()
+	var err error
+	for i := range endpoints {
+		r.setEndpoint(i)
+		if err = r.connectRouter(); err == nil {
+			break
+		}
+		log.Warn("failed to connect router", "wsURL", endpoints[i].WsURL, "err", err)
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -60,11 +105,45 @@ func NewRSDK(wsURL, httpURL string, everSDK *sdk.SDK) *RSDK {
 	return r
 }
 
+func (r *RSDK) setEndpoint(i int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.current = i
+	r.httpCli = gentleman.New().URL(r.endpoints[i].HttpURL)
+}
+
+// failover switch to the next endpoint
+func (r *RSDK) failover() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.current = (r.current + 1) % len(r.endpoints)
+	r.httpCli = gentleman.New().URL(r.endpoints[r.current].HttpURL)
+}
+
+// Endpoint return the router endpoint connected
+func (r *RSDK) Endpoint() RouterEndpoint {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.endpoints[r.current]
+}
+
+func (r *RSDK) http() *gentleman.Client {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.httpCli
+}
+
 func (r *RSDK) Close() {
+	r.closeOnce.Do(func() { close(r.closed) })
 	r.wsConn.Close()
 	log.Info("rsdk websocket closed")
 }
 
+// Closed is closed when rsdk is closed
+func (r *RSDK) Closed() <-chan struct{} {
+	return r.closed
+}
+
 func (r *RSDK) SubscribeOrder() <-chan *schema.LpMsgOrder {
 	return r.order
 }
@@ -77,26 +156,102 @@ func (r *RSDK) SubscribeReconnect() <-chan struct{} {
 	return r.reconnect
 }
 
-func (r *RSDK) SubscribeLpAddResponseOnce() <-chan *schema.LpMsgAddResponse {
-	r.addResponseOnceSubscribed = true
-	return r.addResponse
+// addRequest is an add request waiting response, res is buffered so the response is not blocked if request timed out
+type addRequest struct {
+	conn *websocket.Conn
+	res  chan *schema.LpMsgAddResponse
 }
 
-func (r *RSDK) SubscribeLpRemoveResponseOnce() <-chan *schema.LpMsgRemoveResponse {
-	r.removeResponseOnceSubscribed = true
-	return r.removeResponse
+type removeRequest struct {
+	conn *websocket.Conn
+	res  chan *schema.LpMsgRemoveResponse
 }
 
 func (r *RSDK) AddLiquidity(msg schema.LpMsgAdd) error {
-	return r.wsConn.WriteMessage(websocket.TextMessage, msg.Marshal())
+	_, err := r.RequestAddLiquidity(msg)
+	return err
 }
 
 func (r *RSDK) RemoveLiquidity(msg schema.LpMsgRemove) error {
-	return r.wsConn.WriteMessage(websocket.TextMessage, msg.Marshal())
+	_, err := r.RequestRemoveLiquidity(msg)
+	return err
+}
+
+// RequestAddLiquidity send add msg to router and return channel of its response
+func (r *RSDK) RequestAddLiquidity(msg schema.LpMsgAdd) (<-chan *schema.LpMsgAddResponse, error) {
+	r.requestLock.Lock()
+	defer r.requestLock.Unlock()
+	conn := r.wsConn
+	if err := conn.WriteMessage(websocket.TextMessage, msg.Marshal()); err != nil {
+		return nil, err
+	}
+	req := &addRequest{conn: conn, res: make(chan *schema.LpMsgAddResponse, 1)}
+	r.addRequests = append(r.addRequests, req)
+	return req.res, nil
+}
+
+// RequestRemoveLiquidity send remove msg to router and return channel of its response
+func (r *RSDK) RequestRemoveLiquidity(msg schema.LpMsgRemove) (<-chan *schema.LpMsgRemoveResponse, error) {
+	r.requestLock.Lock()
+	defer r.requestLock.Unlock()
+	conn := r.wsConn
+	if err := conn.WriteMessage(websocket.TextMessage, msg.Marshal()); err != nil {
+		return nil, err
+	}
+	req := &removeRequest{conn: conn, res: make(chan *schema.LpMsgRemoveResponse, 1)}
+	r.removeRequests = append(r.removeRequests, req)
+	return req.res, nil
+}
+
+// sendAddResponse send response read from conn to the earliest add request sent on conn.
+// requests sent on previous connections will never be responded, they fail.
+func (r *RSDK) sendAddResponse(conn *websocket.Conn, msg *schema.LpMsgAddResponse) {
+	r.requestLock.Lock()
+	defer r.requestLock.Unlock()
+	for len(r.addRequests) > 0 {
+		req := r.addRequests[0]
+		r.addRequests = r.addRequests[1:]
+		if req.conn == conn {
+			req.res <- msg
+			return
+		}
+		req.res <- &schema.LpMsgAddResponse{Event: schema.LpMsgEventAddResponse, Msg: "failed", Error: ERR_ROUTER_DISCONNECTED.Error()}
+	}
+	log.Warn("add response without request", "lpID", msg.LpID)
+}
+
+// sendRemoveResponse send response read from conn to the earliest remove request sent on conn
+func (r *RSDK) sendRemoveResponse(conn *websocket.Conn, msg *schema.LpMsgRemoveResponse) {
+	r.requestLock.Lock()
+	defer r.requestLock.Unlock()
+	for len(r.removeRequests) > 0 {
+		req := r.removeRequests[0]
+		r.removeRequests = r.removeRequests[1:]
+		if req.conn == conn {
+			req.res <- msg
+			return
+		}
+		req.res <- &schema.LpMsgRemoveResponse{Event: schema.LpMsgEventRemoveResponse, Msg: "failed", Error: ERR_ROUTER_DISCONNECTED.Error()}
+	}
+	log.Warn("remove response without request", "lpID", msg.LpID)
+}
+
+// failRequests fail requests waiting responses, they are lost when connection is down
+func (r *RSDK) failRequests(err error) {
+	r.requestLock.Lock()
+	defer r.requestLock.Unlock()
+	for _, req := range r.addRequests {
+		req.res <- &schema.LpMsgAddResponse{Event: schema.LpMsgEventAddResponse, Msg: "failed", Error: err.Error()}
+	}
+	for _, req := range r.removeRequests {
+		req.res <- &schema.LpMsgRemoveResponse{Event: schema.LpMsgEventRemoveResponse, Msg: "failed", Error: err.Error()}
+	}
+	r.addRequests = nil
+	r.removeRequests = nil
 }
 
 func (r *RSDK) GetInfo() (info schema.InfoRes, err error) {
-	req := r.httpCli.Request()
+	req := r.http().Request()
 	req.Path("/info")
 
 	res, err := req.Send()
@@ -110,7 +265,7 @@ func (r *RSDK) GetInfo() (info schema.InfoRes, err error) {
 }
 
 func (r *RSDK) GetLps() (lps []coreSchema.Lp, err error) {
-	req := r.httpCli.Request()
+	req := r.http().Request()
 	req.Path("/lps")
 	req.AddQuery("accid", r.AccID)
 
@@ -128,6 +283,20 @@ func (r *RSDK) GetLps() (lps []coreSchema.Lp, err error) {
 	return
 }
 
+func (r *RSDK) GetPool(poolID string) (pool schema.PoolRes, err error) {
+	req := r.http().Request()
+	req.Path("/pool/" + poolID)
+
+	res, err := req.Send()
+	if err != nil {
+		return
+	}
+	defer res.Close()
+
+	err = json.Unmarshal(res.Bytes(), &pool)
+	return
+}
+
 func (r *RSDK) SignOrder(msg schema.LpMsgOrder) error {
 	sig, err := r.EverSDK.Sign(msg.Bundle.String())
 	if err != nil {
@@ -162,26 +331,59 @@ func (r *RSDK) GetBalance(tokenTag string) (amount string, err error) {
 	return b.Balance.Amount, nil
 }
 
+// GetBalances return all balances of lp, token tag -> amount
+func (r *RSDK) GetBalances() (map[string]*big.Int, error) {
+	bs, err := r.EverSDK.Cli.Balances(r.EverSDK.AccId)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := map[string]*big.Int{}
+	for _, b := range bs.Balances {
+		amount, ok := new(big.Int).SetString(b.Amount, 10)
+		if !ok {
+			return nil, ERR_INVALID_AMOUNT
+		}
+		balances[b.Tag] = amount
+	}
+	return balances, nil
+}
+
 func (r *RSDK) runMsgUnmarshal() {
+	failures := 0
 	for {
-		_, data, err := r.wsConn.ReadMessage()
+		conn := r.wsConn
+		msgType, data, err := conn.ReadMessage()
 		if err != nil {
-			log.Warn("connection disconnected", "err", err)
-			time.Sleep(2 * time.Second)
+			backoff := reconnectBackoff(failures)
+			log.Warn("connection disconnected", "err", err, "backoff", backoff)
+			r.failRequests(ERR_ROUTER_DISCONNECTED)
+			time.Sleep(backoff)
+
+			if failures >= failoverRetries && len(r.endpoints) > 1 {
+				failures = 0
+				r.failover()
+				log.Warn("fail over to next router", "wsURL", r.Endpoint().WsURL)
+			}
 
 			log.Info("reconnect...")
 			if err = r.connectRouter(); err != nil {
 				log.Error("reconnect failed", "err", err)
-				r.wsConn.Close()
+				failures++
+				if r.wsConn != nil {
+					r.wsConn.Close()
+				}
 			} else {
 				log.Info("reconnect success")
+				failures = 0
 				r.reconnect <- struct{}{}
 			}
 			continue
 		}
+		src := routerMessage(msgType, data)
 
 		msg := &schema.LpMsg{}
-		if err = json.Unmarshal(data, msg); err != nil {
+		if err = src.Unmarshal(msg); err != nil {
 			log.Error("invalid msg from router", "err", err, "msg", string(data))
 			continue
 		}
@@ -189,7 +391,7 @@ func (r *RSDK) runMsgUnmarshal() {
 		switch msg.Event {
 		case schema.LpMsgEventOrder:
 			orderMsg := &schema.LpMsgOrder{}
-			if err = json.Unmarshal(data, orderMsg); err != nil {
+			if err = src.Unmarshal(orderMsg); err != nil {
 				log.Error("invalid order from router", "err", err, "msg", string(data))
 				continue
 			}
@@ -197,7 +399,7 @@ func (r *RSDK) runMsgUnmarshal() {
 
 		case schema.OrderMsgEventStatus:
 			statusMsg := &schema.OrderMsgStatus{}
-			if err = json.Unmarshal(data, statusMsg); err != nil {
+			if err = src.Unmarshal(statusMsg); err != nil {
 				log.Error("invalid status from router", "err", err, "msg", string(data))
 				continue
 			}
@@ -205,7 +407,7 @@ func (r *RSDK) runMsgUnmarshal() {
 
 		case schema.LpMsgEventAddResponse:
 			addResponseMsg := &schema.LpMsgAddResponse{}
-			if err = json.Unmarshal(data, addResponseMsg); err != nil {
+			if err = src.Unmarshal(addResponseMsg); err != nil {
 				log.Error("invalid lp add response from router", "err", err, "msg", string(data))
 				addResponseMsg = &schema.LpMsgAddResponse{
 					Event: schema.LpMsgEventAddResponse,
@@ -213,14 +415,11 @@ func (r *RSDK) runMsgUnmarshal() {
 					Error: "error_invalid_response",
 				}
 			}
-			if r.addResponseOnceSubscribed {
-				r.addResponseOnceSubscribed = false
-				r.addResponse <- addResponseMsg
-			}
+			r.sendAddResponse(conn, addResponseMsg)
 
 		case schema.LpMsgEventRemoveResponse:
 			removeResponseMsg := &schema.LpMsgRemoveResponse{}
-			if err = json.Unmarshal(data, removeResponseMsg); err != nil {
+			if err = src.Unmarshal(removeResponseMsg); err != nil {
 				log.Error("invalid lp add response from router", "err", err, "msg", string(data))
 				removeResponseMsg = &schema.LpMsgRemoveResponse{
 					Event: schema.LpMsgEventRemoveResponse,
@@ -228,10 +427,7 @@ func (r *RSDK) runMsgUnmarshal() {
 					Error: "error_invalid_response",
 				}
 			}
-			if r.removeResponseOnceSubscribed {
-				r.removeResponseOnceSubscribed = false
-				r.removeResponse <- removeResponseMsg
-			}
+			r.sendRemoveResponse(conn, removeResponseMsg)
 
 		default:
 			log.Error("invalid message event", "msg", string(data))
@@ -239,20 +435,38 @@ func (r *RSDK) runMsgUnmarshal() {
 	}
 }
 
+// reconnectBackoff return exponential backoff with jitter after failures, jitter is up to half of backoff
+func reconnectBackoff(failures int) time.Duration {
+	d := reconnectBackoffMax
+	if failures < 16 {
+		if b := reconnectBackoffMin << failures; b < d {
+			d = b
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
 func (r *RSDK) connectRouter() (err error) {
-	wsConn, _, err := websocket.DefaultDialer.Dial(r.wsURL, nil)
+	wsURL, err := protocolURL(r.Endpoint().WsURL, schema.ProtocolVersionLatest, routerEncoding)
 	if err != nil {
 		return
 	}
+	wsConn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return
+	}
+	// requests are sent on the new connection after it is set
+	r.requestLock.Lock()
 	r.wsConn = wsConn
+	r.requestLock.Unlock()
 
 	// auto register
-	_, msg, err := r.wsConn.ReadMessage()
+	msgType, msg, err := r.wsConn.ReadMessage()
 	if err != nil {
 		return
 	}
 	saltMsg := schema.LpMsgSalt{}
-	if err = json.Unmarshal(msg, &saltMsg); err != nil {
+	if err = routerMessage(msgType, msg).Unmarshal(&saltMsg); err != nil {
 		return
 	}
 	salt := saltMsg.Salt
@@ -260,22 +474,66 @@ func (r *RSDK) connectRouter() (err error) {
 	if err != nil {
 		return
 	}
-	err = r.wsConn.WriteMessage(websocket.TextMessage, schema.LpMsgRegister{
+	// routers before protocol v2 do not advertise versions and ignore the version param
+	r.protocolVersion = schema.ProtocolVersionV1
+	for _, v := range saltMsg.ProtocolVersions {
+		if v == schema.ProtocolVersionLatest {
+			r.protocolVersion = v
+		}
+	}
+	regMsg := schema.LpMsgRegister{
 		Address:         r.EverSDK.AccId,
 		Sig:             sig,
 		LpClientName:    LpName,
 		LpClientVersion: LpVersion,
-	}.Marshal())
+	}.Marshal()
+	if r.protocolVersion == schema.ProtocolVersionV2 {
+		regMsg = schema.LpMsgRegisterV2{
+			Address:         r.EverSDK.AccId,
+			Sig:             sig,
+			LpClientName:    LpName,
+			LpClientVersion: LpVersion,
+		}.Marshal()
+	}
+	err = r.wsConn.WriteMessage(websocket.TextMessage, regMsg)
 	if err != nil {
 		return
 	}
-	_, msg, err = r.wsConn.ReadMessage()
+	msgType, msg, err = r.wsConn.ReadMessage()
 	if err != nil {
 		return
 	}
-	if string(msg) != `{"event":"response","msg":"ok"}` {
+	res := schema.LpMsgResponse{}
+	if err = routerMessage(msgType, msg).Unmarshal(&res); err != nil {
+		return
+	}
+	if res != schema.LpMsgOk {
 		err = errors.New(string(msg))
 	}
 
 	return
 }
+
+// protocolURL add protocol version and encoding params to websocket url, json is the default encoding of router
+func protocolURL(wsURL, version, encoding string) (string, error) {
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(schema.ProtocolVersionParam, version)
+	if encoding != wshub.EncodingJSON {
+		q.Set(schema.EncodingParam, encoding)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// routerMessage return msg from router, binary msg is sent by router when connected with encoding=cbor
+func routerMessage(msgType int, data []byte) wshub.Message {
+	encoding := wshub.EncodingJSON
+	if msgType == websocket.BinaryMessage {
+		encoding = wshub.EncodingCBOR
+	}
+	return wshub.Message{Data: data, Encoding: encoding}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/everVision/everpay-kits/sdk"
 	"github.com/gorilla/websocket"
 	"github.com/permadao/permaswap/router"
+	"github.com/permadao/permaswap/wshub"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,3 +28,21 @@ func TestSDKConnect(t *testing.T) {
 	err = testSDK.wsConn.WriteMessage(websocket.TextMessage, []byte{})
 	assert.NoError(t, err)
 }
+
+func TestProtocolURL(t *testing.T) {
+	u, err := protocolURL("wss://router.permaswap.network/wslp", "v2", wshub.EncodingJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, "wss://router.permaswap.network/wslp?version=v2", u)
+
+	u, err = protocolURL("wss://router.permaswap.network/wslp?version=v1", "v2", wshub.EncodingCBOR)
+	assert.NoError(t, err)
+	assert.Equal(t, "wss://router.permaswap.network/wslp?encoding=cbor&version=v2", u)
+}
+
+func TestSetEncoding(t *testing.T) {
+	defer func() { routerEncoding = wshub.EncodingJSON }()
+	assert.Equal(t, wshub.ErrUnsupportedEncoding, SetEncoding("xml"))
+	assert.Equal(t, wshub.EncodingJSON, routerEncoding)
+	assert.NoError(t, SetEncoding(wshub.EncodingCBOR))
+	assert.Equal(t, wshub.EncodingCBOR, routerEncoding)
+}
@@ -1,6 +1,7 @@
 package schema
 
 import (
+	apd "github.com/cockroachdb/apd/v3"
 	everSchema "github.com/everVision/everpay-kits/schema"
 	coreSchema "github.com/permadao/permaswap/core/schema"
 )
@@ -12,6 +13,7 @@ type InfoRes struct {
 	Tokens        map[string]*everSchema.Token `json:"tokens"`
 	Pools         map[string]*coreSchema.Pool  `json:"pools"`
 	Lps           map[string]coreSchema.Lp     `json:"lps"`
+	Paused        bool                         `json:"paused"`
 }
 
 type RemoveLpReq struct {
@@ -29,3 +31,51 @@ type AddLpRes struct {
 	Result string `json:"result"` // "ok" or "failed"
 	Error  string `json:"error"`
 }
+
+// ModifyLpReq replace lp with a new one, empty fields are kept
+type ModifyLpReq struct {
+	LpID             string       `json:"lpID"`
+	LowSqrtPrice     *apd.Decimal `json:"lowSqrtPrice"`
+	CurrentSqrtPrice *apd.Decimal `json:"currentSqrtPrice"`
+	HighSqrtPrice    *apd.Decimal `json:"highSqrtPrice"`
+	Liquidity        string       `json:"liquidity"`
+	PriceDirection   string       `json:"priceDirection"`
+}
+
+type ModifyLpRes struct {
+	LpID    string `json:"lpID"`
+	NewLpID string `json:"newLpID"`
+	Result  string `json:"result"` // "ok" or "failed"
+	Error   string `json:"error"`
+}
+
+type PauseRes struct {
+	Paused bool `json:"paused"`
+}
+
+type PendingOrder struct {
+	OrderHash  string            `json:"orderHash"`
+	UserAddr   string            `json:"userAddr"`
+	Paths      []coreSchema.Path `json:"paths"`
+	Confirming bool              `json:"confirming"`
+}
+
+// LpPnL is token flows of a position from fills, amounts are in the smallest unit.
+// a position is kept when its lps are replaced by modify, rebalance or scale.
+type LpPnL struct {
+	PositionID string   `json:"positionID"` // id of the first lp of position
+	LpIDs      []string `json:"lpIDs"`      // current lps of position
+	PoolID     string   `json:"poolID"`
+	TokenX     string   `json:"tokenX"`
+	TokenY     string   `json:"tokenY"`
+	AmountX    string   `json:"amountX"` // current amounts of lps
+	AmountY    string   `json:"amountY"`
+	InX        string   `json:"inX"`
+	OutX       string   `json:"outX"`
+	InY        string   `json:"inY"`
+	OutY       string   `json:"outY"`
+	FeeX       string   `json:"feeX"`
+	FeeY       string   `json:"feeY"`
+	NetValueY  string   `json:"netValueY"` // net flows valued in tokenY at current price
+	Orders     int      `json:"orders"`
+}
@@ -11,3 +11,34 @@ type Order struct {
 	OrderStatus string     `json:"status"`
 	LpMsgOrder  string     `json:"lpMsgOrde"`
 }
+
+// Fill is a swap of an order through a lp, amounts are in the smallest unit
+type Fill struct {
+	ID        int64      `gorm:"primary_key;auto_increment" json:"id"`
+	CreatedAt *time.Time `gorm:"ASSOCIATION_AUTOCREATE" json:"-"`
+	OrderHash string     `json:"orderHash"`
+	EverHash  string     `gorm:"index:fillindex1" json:"everHash"`
+	LpID      string     `gorm:"index:fillindex2" json:"lpID"`
+	PoolID    string     `gorm:"index:fillindex3" json:"poolID"`
+	TokenIn   string     `json:"tokenIn"`
+	TokenOut  string     `json:"tokenOut"`
+	AmountIn  string     `json:"amountIn"`
+	AmountOut string     `json:"amountOut"`
+	Fee       string     `json:"fee"`                               // fee earned by lp in tokenIn
+	Price     string     `json:"price"`                             // price of tokenX in tokenY with decimals
+	Timestamp int64      `gorm:"index:fillindex4" json:"timestamp"` // unix milliseconds
+}
+
+type FillQuery struct {
+	PoolID string
+	LpID   string
+	Start  int64 // unix milliseconds, inclusive
+	End    int64 // unix milliseconds, exclusive
+}
+
+// Position link a lp to the position it succeeds, lps replaced by modify, rebalance or scale keep position of the first lp.
+// lps without position are positions of their own.
+type Position struct {
+	LpID       string `gorm:"primary_key" json:"lpID"`
+	PositionID string `gorm:"index:positionindex1" json:"positionID"`
+}
@@ -0,0 +1,424 @@
+package lp
+
+import (
+	"errors"
+	"math/big"
+
+	apd "github.com/cockroachdb/apd/v3"
+	"github.com/permadao/permaswap/core"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	routerSchema "github.com/permadao/permaswap/router/schema"
+)
+
+const (
+	StrategyNone       = "none"
+	StrategyFixedWidth = "fixed_width"
+	StrategyLadder     = "ladder"
+)
+
+var (
+	ERR_INVALID_STRATEGY = errors.New("err_invalid_strategy")
+	ERR_NO_MARKET_PRICE  = errors.New("err_no_market_price")
+)
+
+// Strategy decide how to rebalance lps of a pool when price leaves their range.
+// lps are lps of the client in the pool, currentSqrtPrice is the market sqrt price of the pool.
+// lps to add are checked by checkBalance before lps to remove are removed.
+type Strategy interface {
+	Name() string
+	Rebalance(currentSqrtPrice *apd.Decimal, lps []coreSchema.Lp) (toRemove []coreSchema.Lp, toAdd []routerSchema.LpMsgAdd, err error)
+}
+
+func NewStrategy(name string, ladderRungs int, ladderStep string) (Strategy, error) {
+	switch name {
+	case StrategyNone, "":
+		return nil, nil
+	case StrategyFixedWidth:
+		return &FixedWidthStrategy{}, nil
+	case StrategyLadder:
+		return NewLadderStrategy(ladderRungs, ladderStep)
+	default:
+		return nil, ERR_INVALID_STRATEGY
+	}
+}
+
+// FixedWidthStrategy recenter a lp around current price with the same width and liquidity when price leaves its range
+type FixedWidthStrategy struct{}
+
+func (s *FixedWidthStrategy) Name() string {
+	return StrategyFixedWidth
+}
+
+func (s *FixedWidthStrategy) Rebalance(currentSqrtPrice *apd.Decimal, lps []coreSchema.Lp) (toRemove []coreSchema.Lp, toAdd []routerSchema.LpMsgAdd, err error) {
+	c := apd.BaseContext.WithPrecision(core.PRECISION)
+
+	for _, lp := range lps {
+		if currentSqrtPrice.Cmp(lp.LowSqrtPrice) == 1 && currentSqrtPrice.Cmp(lp.HighSqrtPrice) == -1 {
+			continue
+		}
+
+		// low = current / (high/low)**0.5, high = current * (high/low)**0.5
+		ratio := new(apd.Decimal)
+		if _, err = c.Quo(ratio, lp.HighSqrtPrice, lp.LowSqrtPrice); err != nil {
+			return
+		}
+		factor := new(apd.Decimal)
+		if _, err = c.Sqrt(factor, ratio); err != nil {
+			return
+		}
+		low := new(apd.Decimal)
+		if _, err = c.Quo(low, currentSqrtPrice, factor); err != nil {
+			return
+		}
+		high := new(apd.Decimal)
+		if _, err = c.Mul(high, currentSqrtPrice, factor); err != nil {
+			return
+		}
+
+		low.Reduce(low)
+		high.Reduce(high)
+
+		toRemove = append(toRemove, lp)
+		toAdd = append(toAdd, routerSchema.LpMsgAdd{
+			TokenX:           lp.TokenXTag,
+			TokenY:           lp.TokenYTag,
+			FeeRatio:         lp.FeeRatio,
+			LowSqrtPrice:     low,
+			CurrentSqrtPrice: new(apd.Decimal).Set(currentSqrtPrice),
+			HighSqrtPrice:    high,
+			Liquidity:        lp.Liquidity.String(),
+			PriceDirection:   lp.PriceDirection,
+		})
+	}
+	return
+}
+
+// LadderStrategy lay rungs of one-sided lps next to current price.
+// When price moves beyond all lps of the pool, tokens of the lps are all converted to one token,
+// then lps are removed and the token is laid again in rungs starting at current price:
+// tokenY is laid below the price to buy tokenX back, tokenX is laid above the price to sell.
+type LadderStrategy struct {
+	Rungs int
+	Step  *apd.Decimal // sqrt price factor of a rung, high = low * step
+}
+
+func NewLadderStrategy(rungs int, step string) (*LadderStrategy, error) {
+	step_, _, err := apd.NewFromString(step)
+	if err != nil {
+		return nil, err
+	}
+	if rungs < 1 || !core.QuotientGreaterThan(step_, apd.New(1, 0), coreSchema.MinSqrtPriceFactor) {
+		return nil, ERR_INVALID_STRATEGY
+	}
+	return &LadderStrategy{Rungs: rungs, Step: step_}, nil
+}
+
+func (s *LadderStrategy) Name() string {
+	return StrategyLadder
+}
+
+func (s *LadderStrategy) Rebalance(currentSqrtPrice *apd.Decimal, lps []coreSchema.Lp) (toRemove []coreSchema.Lp, toAdd []routerSchema.LpMsgAdd, err error) {
+	if len(lps) == 0 {
+		return
+	}
+
+	lowest, highest := lps[0].LowSqrtPrice, lps[0].HighSqrtPrice
+	for _, lp := range lps {
+		if lp.LowSqrtPrice.Cmp(lowest) == -1 {
+			lowest = lp.LowSqrtPrice
+		}
+		if lp.HighSqrtPrice.Cmp(highest) == 1 {
+			highest = lp.HighSqrtPrice
+		}
+	}
+	// a new ladder starts at current price, so it is not rebalanced again until price moves beyond it
+	isAbove := currentSqrtPrice.Cmp(highest) == 1
+	isBelow := currentSqrtPrice.Cmp(lowest) == -1
+	if !isAbove && !isBelow {
+		return
+	}
+
+	// tokens hold by lps at market price, current price of lp may not be moved to it by orders
+	total := big.NewInt(0)
+	for _, lp := range lps {
+		current := currentSqrtPrice
+		if current.Cmp(lp.LowSqrtPrice) == -1 {
+			current = lp.LowSqrtPrice
+		}
+		if current.Cmp(lp.HighSqrtPrice) == 1 {
+			current = lp.HighSqrtPrice
+		}
+		amountX, amountY, err := core.LiquidityToAmount(lp.Liquidity.String(), lp.LowSqrtPrice, current, lp.HighSqrtPrice, lp.PriceDirection)
+		if err != nil {
+			return nil, nil, err
+		}
+		amount := amountX
+		if isAbove {
+			amount = amountY
+		}
+		a, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			return nil, nil, ERR_INVALID_AMOUNT
+		}
+		total.Add(total, a)
+	}
+	amount := new(big.Int).Div(total, big.NewInt(int64(s.Rungs)))
+	if amount.Sign() != 1 {
+		return
+	}
+
+	c := apd.BaseContext.WithPrecision(core.PRECISION)
+	lp := lps[0]
+	bound := new(apd.Decimal).Set(currentSqrtPrice)
+	for i := 0; i < s.Rungs; i++ {
+		next := new(apd.Decimal)
+		var low, high *apd.Decimal
+		if isAbove {
+			if _, err = c.Quo(next, bound, s.Step); err != nil {
+				return nil, nil, err
+			}
+			low, high = next, bound
+		} else {
+			if _, err = c.Mul(next, bound, s.Step); err != nil {
+				return nil, nil, err
+			}
+			low, high = bound, next
+		}
+		next.Reduce(next)
+
+		var liquidity string
+		var current *apd.Decimal
+		if isAbove {
+			current = high
+			liquidity, err = core.LiquidityFromAmountY(low, current, high, amount.String())
+		} else {
+			current = low
+			liquidity, err = core.LiquidityFromAmountX(low, current, high, amount.String())
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		toAdd = append(toAdd, routerSchema.LpMsgAdd{
+			TokenX:           lp.TokenXTag,
+			TokenY:           lp.TokenYTag,
+			FeeRatio:         lp.FeeRatio,
+			LowSqrtPrice:     low,
+			CurrentSqrtPrice: current,
+			HighSqrtPrice:    high,
+			Liquidity:        liquidity,
+			PriceDirection:   coreSchema.PriceDirectionBoth,
+		})
+		bound = next
+	}
+
+	return lps, toAdd, nil
+}
+
+func (l *Lp) SetStrategy(s Strategy) {
+	l.strategy = s
+}
+
+// rebalance lps of all pools by strategy.
+// market prices are got from router off the process, and lps are rebalanced by marketPricesProc.
+func (l *Lp) rebalance() {
+	if l.strategy == nil || len(l.orders) > 0 || l.rebalancing {
+		return
+	}
+
+	poolLps := l.poolLps()
+	if len(poolLps) == 0 {
+		return
+	}
+
+	l.rebalancing = true
+	go func() {
+		prices := map[string]*apd.Decimal{}
+		for poolID, lps := range poolLps {
+			price, err := l.marketSqrtPrice(poolID, lps)
+			if err != nil {
+				log.Warn("can not get market price", "poolID", poolID, "err", err)
+				continue
+			}
+			prices[poolID] = price
+		}
+
+		select {
+		case l.marketPrices <- prices:
+		case <-l.close:
+		}
+	}()
+}
+
+// marketPricesProc rebalance lps of pools by market prices, pool id -> sqrt price
+func (l *Lp) marketPricesProc(prices map[string]*apd.Decimal) {
+	l.rebalancing = false
+	// orders arrived while getting prices
+	if len(l.orders) > 0 {
+		return
+	}
+
+	for poolID, lps := range l.poolLps() {
+		price, ok := prices[poolID]
+		if !ok {
+			continue
+		}
+
+		toRemove, toAdd, err := l.strategy.Rebalance(price, lps)
+		if err != nil {
+			log.Error("strategy rebalance failed", "strategy", l.strategy.Name(), "poolID", poolID, "err", err)
+			continue
+		}
+		if len(toRemove) == 0 && len(toAdd) == 0 {
+			continue
+		}
+
+		if err := l.checkRebalanceBalance(toRemove, toAdd); err != nil {
+			log.Warn("balance is not enough to rebalance", "strategy", l.strategy.Name(), "poolID", poolID, "err", err)
+			continue
+		}
+
+		log.Info("rebalance lps", "strategy", l.strategy.Name(), "poolID", poolID, "currentSqrtPrice", price, "remove", len(toRemove), "add", len(toAdd))
+		if err := l.rebalancePool(toRemove, toAdd); err != nil {
+			log.Error("rebalance pool failed, lps are restored", "poolID", poolID, "err", err)
+		}
+	}
+}
+
+func (l *Lp) poolLps() map[string][]coreSchema.Lp {
+	poolLps := map[string][]coreSchema.Lp{}
+	for _, lp := range l.core.GetLps(l.rsdk.AccID) {
+		poolLps[lp.PoolID] = append(poolLps[lp.PoolID], lp)
+	}
+	return poolLps
+}
+
+// rebalancePool remove lps and add new lps of a pool.
+// lps to add need balance of lps removed, so if any step failed, added lps are removed and removed lps are added back.
+func (l *Lp) rebalancePool(toRemove []coreSchema.Lp, toAdd []routerSchema.LpMsgAdd) (err error) {
+	removed := []coreSchema.Lp{}
+	added := []string{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, lpID := range added {
+			if res := l.removeLpProc(lpID); res.Result != "ok" {
+				log.Error("rebalance: failed to remove added lp", "lpID", lpID, "result", res.Result, "err", res.Error)
+			}
+		}
+		for _, lp := range removed {
+			msg := LpToAddMsg(lp)
+			if res := l.addLpProc(&msg); res.Result != "ok" {
+				log.Error("rebalance: failed to add removed lp back", "lpID", lp.ID(), "result", res.Result, "err", res.Error)
+			}
+		}
+	}()
+
+	for _, lp := range toRemove {
+		if res := l.removeLpProc(lp.ID()); res.Result != "ok" {
+			log.Error("rebalance: remove lp failed", "lpID", res.LpID, "result", res.Result, "err", res.Error)
+			return errors.New(res.Error)
+		}
+		removed = append(removed, lp)
+	}
+	for i := range toAdd {
+		res := l.addLpProc(&toAdd[i])
+		if res.Result != "ok" {
+			log.Error("rebalance: add lp failed", "result", res.Result, "err", res.Error)
+			return errors.New(res.Error)
+		}
+		added = append(added, res.LpID)
+	}
+
+	// lps replaced one by one keep their positions, otherwise new lps are in position of the first lp
+	for i, lpID := range added {
+		if len(toRemove) == len(toAdd) {
+			l.succeedLp(toRemove[i].ID(), lpID)
+		} else if len(toRemove) > 0 {
+			l.succeedLp(toRemove[0].ID(), lpID)
+		}
+	}
+	return nil
+}
+
+// checkRebalanceBalance check balance of all lps after rebalance
+func (l *Lp) checkRebalanceBalance(toRemove []coreSchema.Lp, toAdd []routerSchema.LpMsgAdd) error {
+	removed := map[string]bool{}
+	for _, lp := range toRemove {
+		removed[lp.ID()] = true
+	}
+
+	lps := []coreSchema.Lp{}
+	for _, lp := range l.core.GetLps(l.rsdk.AccID) {
+		if !removed[lp.ID()] {
+			lps = append(lps, lp)
+		}
+	}
+	for _, msg := range toAdd {
+		pool, err := l.core.FindPool(msg.TokenX, msg.TokenY, msg.FeeRatio)
+		if err != nil {
+			return err
+		}
+		lp, err := core.NewLp(pool.ID(), msg.TokenX, msg.TokenY, l.rsdk.AccID,
+			msg.FeeRatio, msg.LowSqrtPrice, msg.CurrentSqrtPrice, msg.HighSqrtPrice,
+			msg.Liquidity, msg.PriceDirection)
+		if err != nil {
+			return err
+		}
+		lps = append(lps, *lp)
+	}
+
+	return l.checkBalance(lps, false)
+}
+
+// marketSqrtPrice return mid sqrt price of other lps in the pool on router,
+// or current sqrt price of the client's lps if there is no other lp.
+// it requests router, so it is called off the process.
+func (l *Lp) marketSqrtPrice(poolID string, lps []coreSchema.Lp) (*apd.Decimal, error) {
+	res, err := l.rsdk.GetPool(poolID)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := core.NewPool(res.TokenXTag, res.TokenYTag, res.FeeRatio.String())
+	if err != nil {
+		return nil, err
+	}
+	for i := range res.Lps {
+		if res.Lps[i].AccID == l.rsdk.AccID {
+			continue
+		}
+		if err := core.PoolAddLiquidity(pool, &res.Lps[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	prices := []*apd.Decimal{}
+	for _, direction := range []string{coreSchema.PriceDirectionUp, coreSchema.PriceDirectionDown} {
+		if ticks, err := core.GetPoolTicks(pool, direction, nil); err == nil && len(ticks) > 0 {
+			prices = append(prices, ticks[0].SqrtPrice)
+		}
+	}
+
+	switch len(prices) {
+	case 1:
+		return prices[0], nil
+	case 2:
+		c := apd.BaseContext.WithPrecision(core.PRECISION)
+		product := new(apd.Decimal)
+		if _, err := c.Mul(product, prices[0], prices[1]); err != nil {
+			return nil, err
+		}
+		mid := new(apd.Decimal)
+		if _, err := c.Sqrt(mid, product); err != nil {
+			return nil, err
+		}
+		return mid, nil
+	}
+
+	if len(lps) == 0 {
+		return nil, ERR_NO_MARKET_PRICE
+	}
+	return lps[0].CurrentSqrtPrice, nil
+}
@@ -0,0 +1,110 @@
+package lp
+
+import (
+	"testing"
+
+	apd "github.com/cockroachdb/apd/v3"
+	"github.com/permadao/permaswap/core"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStrategyLp(t *testing.T, low, current, high, liquidity string) coreSchema.Lp {
+	fee, _, _ := apd.NewFromString("0.003")
+	low_, _, _ := apd.NewFromString(low)
+	current_, _, _ := apd.NewFromString(current)
+	high_, _, _ := apd.NewFromString(high)
+	tokenX := "ethereum-eth-0x0000000000000000000000000000000000000000"
+	tokenY := "ethereum-usdt-0xd85476c906b5301e8e9eb58d174a6f96b9dfc5ee"
+	lp, err := core.NewLp(core.GetPoolID(tokenX, tokenY, fee), tokenX, tokenY, "0x61EbF673c200646236B2c53465bcA0699455d5FA",
+		fee, low_, current_, high_, liquidity, coreSchema.PriceDirectionBoth)
+	assert.NoError(t, err)
+	return *lp
+}
+
+func TestFixedWidthStrategy(t *testing.T) {
+	s, err := NewStrategy(StrategyFixedWidth, 0, "")
+	assert.NoError(t, err)
+	lp := testStrategyLp(t, "0.5", "1", "2", "100000")
+
+	// price in range
+	price, _, _ := apd.NewFromString("1.5")
+	toRemove, toAdd, err := s.Rebalance(price, []coreSchema.Lp{lp})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(toRemove))
+	assert.Equal(t, 0, len(toAdd))
+
+	// price out of range, recenter with the same width
+	price, _, _ = apd.NewFromString("4")
+	toRemove, toAdd, err = s.Rebalance(price, []coreSchema.Lp{lp})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(toRemove))
+	assert.Equal(t, lp.ID(), toRemove[0].ID())
+	assert.Equal(t, 1, len(toAdd))
+	assert.Equal(t, "2", toAdd[0].LowSqrtPrice.String())
+	assert.Equal(t, "4", toAdd[0].CurrentSqrtPrice.String())
+	assert.Equal(t, "8", toAdd[0].HighSqrtPrice.String())
+	assert.Equal(t, "100000", toAdd[0].Liquidity)
+}
+
+func TestLadderStrategy(t *testing.T) {
+	_, err := NewStrategy(StrategyLadder, 0, "1.01")
+	assert.Equal(t, ERR_INVALID_STRATEGY, err)
+	_, err = NewStrategy(StrategyLadder, 2, "1")
+	assert.Equal(t, ERR_INVALID_STRATEGY, err)
+	_, err = NewStrategy("unknown", 2, "1.01")
+	assert.Equal(t, ERR_INVALID_STRATEGY, err)
+
+	s, err := NewStrategy(StrategyLadder, 2, "2")
+	assert.NoError(t, err)
+
+	// all tokenX is sold, lay tokenY below price
+	lp := testStrategyLp(t, "1", "2", "2", "100000")
+	_, amountY, _ := core.LiquidityToAmount("100000", lp.LowSqrtPrice, lp.CurrentSqrtPrice, lp.HighSqrtPrice, lp.PriceDirection)
+	assert.Equal(t, "100001", amountY)
+
+	price, _, _ := apd.NewFromString("2")
+	toRemove, toAdd, err := s.Rebalance(price, []coreSchema.Lp{lp})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(toRemove))
+	assert.Equal(t, 0, len(toAdd))
+
+	price, _, _ = apd.NewFromString("8")
+	toRemove, toAdd, err = s.Rebalance(price, []coreSchema.Lp{lp})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(toRemove))
+	assert.Equal(t, 2, len(toAdd))
+	assert.Equal(t, "4", toAdd[0].LowSqrtPrice.String())
+	assert.Equal(t, "8", toAdd[0].HighSqrtPrice.String())
+	assert.Equal(t, "8", toAdd[0].CurrentSqrtPrice.String())
+	assert.Equal(t, "2", toAdd[1].LowSqrtPrice.String())
+	assert.Equal(t, "4", toAdd[1].HighSqrtPrice.String())
+	for _, msg := range toAdd {
+		amountX, amountY, err := core.LiquidityToAmount(msg.Liquidity, msg.LowSqrtPrice, msg.CurrentSqrtPrice, msg.HighSqrtPrice, msg.PriceDirection)
+		assert.NoError(t, err)
+		assert.Equal(t, "0", amountX)
+		assert.Equal(t, "50001", amountY)
+	}
+
+	// current price of lp is not moved to market price, tokens are counted at market price
+	lp = testStrategyLp(t, "1", "1.5", "2", "100000")
+	price, _, _ = apd.NewFromString("8")
+	_, toAdd, err = s.Rebalance(price, []coreSchema.Lp{lp})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(toAdd))
+	for _, msg := range toAdd {
+		_, amountY, err := core.LiquidityToAmount(msg.Liquidity, msg.LowSqrtPrice, msg.CurrentSqrtPrice, msg.HighSqrtPrice, msg.PriceDirection)
+		assert.NoError(t, err)
+		assert.Equal(t, "50001", amountY)
+	}
+
+	// all tokenY is bought back, lay tokenX above price
+	lp = testStrategyLp(t, "1", "1", "2", "100000")
+	price, _, _ = apd.NewFromString("0.5")
+	_, toAdd, err = s.Rebalance(price, []coreSchema.Lp{lp})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(toAdd))
+	assert.Equal(t, "0.5", toAdd[0].LowSqrtPrice.String())
+	assert.Equal(t, "0.5", toAdd[0].CurrentSqrtPrice.String())
+	assert.Equal(t, "1", toAdd[0].HighSqrtPrice.String())
+}
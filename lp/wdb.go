@@ -30,7 +30,7 @@ func NewWDB(dbDir string) *WDB {
 }
 
 func (w *WDB) Migrate() {
-	w.db.AutoMigrate(&schema.Order{})
+	w.db.AutoMigrate(&schema.Order{}, &schema.Fill{}, &schema.Position{})
 }
 
 func (w *WDB) CreateOrder(order *schema.Order, tx *gorm.DB) error {
@@ -48,3 +48,72 @@ func (w *WDB) GetOrders(page, count int) (orders []*schema.Order, err error) {
 	err = w.db.Model(&schema.Order{}).Order("id desc").Offset(dbPage * count).Limit(count).Find(&orders).Error
 	return
 }
+
+// SavePosition save position of lp, position of lp replaced is updated
+func (w *WDB) SavePosition(position *schema.Position) error {
+	return w.db.Save(position).Error
+}
+
+// GetPosition return position id of lp, lp is position of its own if not saved
+func (w *WDB) GetPosition(lpID string) (string, error) {
+	positions := []*schema.Position{}
+	if err := w.db.Model(&schema.Position{}).Where("lp_id = ?", lpID).Limit(1).Find(&positions).Error; err != nil {
+		return "", err
+	}
+	if len(positions) == 0 {
+		return lpID, nil
+	}
+	return positions[0].PositionID, nil
+}
+
+// GetPositions return positions of lps, lp id -> position id
+func (w *WDB) GetPositions() (map[string]string, error) {
+	positions := []*schema.Position{}
+	if err := w.db.Model(&schema.Position{}).Find(&positions).Error; err != nil {
+		return nil, err
+	}
+	res := make(map[string]string, len(positions))
+	for _, p := range positions {
+		res[p.LpID] = p.PositionID
+	}
+	return res, nil
+}
+
+func (w *WDB) CreateFills(fills []*schema.Fill, tx *gorm.DB) error {
+	if len(fills) == 0 {
+		return nil
+	}
+	if tx == nil {
+		tx = w.db
+	}
+	return tx.Create(&fills).Error
+}
+
+// GetFills return fills filtered by query, all fills are returned if count is 0
+func (w *WDB) GetFills(query schema.FillQuery, page, count int) (fills []*schema.Fill, err error) {
+	db := w.db.Model(&schema.Fill{})
+	if query.PoolID != "" {
+		db = db.Where("pool_id = ?", query.PoolID)
+	}
+	if query.LpID != "" {
+		db = db.Where("lp_id = ?", query.LpID)
+	}
+	if query.Start > 0 {
+		db = db.Where("timestamp >= ?", query.Start)
+	}
+	if query.End > 0 {
+		db = db.Where("timestamp < ?", query.End)
+	}
+
+	if count > 0 {
+		dbPage := page - 1
+		if dbPage < 0 {
+			dbPage = 0
+		}
+		db = db.Order("id desc").Offset(dbPage * count).Limit(count)
+	} else {
+		db = db.Order("id asc")
+	}
+	err = db.Find(&fills).Error
+	return
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/permadao/permaswap/core"
 	coreSchema "github.com/permadao/permaswap/core/schema"
 	"github.com/permadao/permaswap/router/schema"
+	"github.com/permadao/permaswap/wshub"
 )
 
 func ClosedMiddleware() gin.HandlerFunc {
@@ -43,6 +44,9 @@ func (r *Router) runAPI(port, haloAPIURLPrefix string) {
 	e.GET("/stats", r.getStats)
 	e.GET("/lpreward", r.getLpReward)
 	e.GET("/penalty", r.getPenalty)
+	e.GET("/quote", r.getQuote)
+	e.POST("/quotes", r.batchQuote)
+	e.GET("/spec", r.getSpec)
 
 	if haloAPIURLPrefix != "" {
 		r.haloServer.RegisterRouter(e, haloAPIURLPrefix)
@@ -59,11 +63,36 @@ func (r *Router) runAPI(port, haloAPIURLPrefix string) {
 }
 
 func (r *Router) wsUser(c *gin.Context) {
-	r.userHub.RegisterSession(c.Writer, c.Request)
+	opt, err := sessionOption(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+	r.userHub.RegisterSession(c.Writer, c.Request, opt)
 }
 
 func (r *Router) wsLp(c *gin.Context) {
-	r.lpHub.RegisterSession(c.Writer, c.Request)
+	opt, err := sessionOption(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+	r.lpHub.RegisterSession(c.Writer, c.Request, opt)
+}
+
+// sessionOption return the protocol version and encoding negotiated by client
+// v1 and json for old clients without params
+func sessionOption(c *gin.Context) (opt wshub.SessionOption, err error) {
+	opt.Version = c.DefaultQuery(schema.ProtocolVersionParam, schema.ProtocolVersionV1)
+	if !schema.IsProtocolVersionSupported(opt.Version) {
+		err = WsErrUnsupportedProtocolVersion
+		return
+	}
+	opt.Encoding = c.DefaultQuery(schema.EncodingParam, wshub.EncodingJSON)
+	if !wshub.IsEncodingSupported(opt.Encoding) {
+		err = WsErrUnsupportedEncoding
+	}
+	return
 }
 
 func (r *Router) getInfo(c *gin.Context) {
@@ -89,6 +118,9 @@ func (r *Router) getInfo(c *gin.Context) {
 		TokenList:     tokenList,
 		PoolList:      r.core.Pools,
 		LpClientInfo:  r.LpClientInfo,
+
+		ProtocolVersions: schema.ProtocolVersionsSupported,
+		Encodings:        wshub.EncodingsSupported,
 	})
 }
 
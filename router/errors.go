@@ -11,6 +11,14 @@ func NewWsErr(msg string) WsErr {
 	return WsErr{Event: "error", Msg: msg}
 }
 
+// toWsErr return err as ws msg, errors not from ws are wrapped
+func toWsErr(err error) WsErr {
+	if e, ok := err.(WsErr); ok {
+		return e
+	}
+	return NewWsErr(err.Error())
+}
+
 func (w WsErr) Error() string {
 	by, _ := json.Marshal(w)
 	return string(by)
@@ -33,4 +41,7 @@ var (
 	WsErrInvalidNFTData        = NewWsErr("err_invalid_nft_data")
 	WsErrInvalidLpClient       = NewWsErr("err_invalid_lp_client")
 	WsErrBlackListed           = NewWsErr("err_blacklisted")
+
+	WsErrUnsupportedProtocolVersion = NewWsErr("err_unsupported_protocol_version")
+	WsErrUnsupportedEncoding        = NewWsErr("err_unsupported_encoding")
 )
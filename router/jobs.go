@@ -10,6 +10,9 @@ func (r *Router) runJobs() {
 	r.scheduler.Every(2).Minute().SingletonMode().Do(r.saveLpsSnapshot)
 	r.scheduler.Every(5).Minute().SingletonMode().Do(r.loadNFTWhiteList)
 	r.scheduler.Every(5).Minute().SingletonMode().Do(r.cleanUpExpiredPenalty)
+	if r.haloSDK != nil {
+		r.scheduler.Every(10).Minute().SingletonMode().Do(r.haloTick)
+	}
 	r.scheduler.StartAsync()
 }
 
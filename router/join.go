@@ -31,3 +31,14 @@ func (r *Router) Join() error {
 	log.Info("AutoJoin tx submit success", "tx", tx.EverHash)
 	return nil
 }
+
+// haloTick submit tick tx with prices of router to halo, so scheduled proposals run in quiet periods
+// and prices in oracle of halo are updated
+func (r *Router) haloTick() {
+	tx, err := r.haloSDK.Tick(r.Stats.Price.Prices())
+	if err != nil {
+		log.Error("halo tick tx submit failed", "error", err)
+		return
+	}
+	log.Debug("halo tick tx submit success", "tx", tx.EverHash)
+}
@@ -1,7 +1,6 @@
 package router
 
 import (
-	"encoding/json"
 
 	"github.com/permadao/permaswap/core"
 	"github.com/permadao/permaswap/router/schema"
@@ -23,24 +22,24 @@ func (r *Router) runLpMsgUnmarshal() {
 		src := <-r.lpHub.Subscribe()
 
 		msg := &schema.LpMsg{}
-		if err := json.Unmarshal(src.Data, msg); err != nil {
-			r.lpHub.Publish(src.ID, []byte(WsErrInvalidMsg.Error()))
+		if err := src.Unmarshal(msg); err != nil {
+			r.lpHub.Publish(src.ID, WsErrInvalidMsg)
 			log.Error("invalid message from lp", "err", err, "msg", string(src.Data))
 			continue
 		}
 
 		// authorization verification
 		if msg.Event != schema.LpMsgEventRegister && !r.isLpByID(src.ID) {
-			r.lpHub.Publish(src.ID, []byte(WsErrNoAuthorization.Error()))
+			r.lpHub.Publish(src.ID, WsErrNoAuthorization)
 			log.Error("no auth from lp", "id", src.ID)
 			continue
 		}
 
 		switch msg.Event {
 		case schema.LpMsgEventRegister:
-			regMsg := &schema.LpMsgRegister{}
-			if err := json.Unmarshal(src.Data, regMsg); err != nil {
-				r.lpHub.Publish(src.ID, []byte(WsErrInvalidMsg.Error()))
+			regMsg, err := schema.DecodeLpMsgRegister(src)
+			if err != nil {
+				r.lpHub.Publish(src.ID, WsErrInvalidMsg)
 				log.Error("invalid message from lp", "err", err, "msg", string(src.Data))
 				continue
 			}
@@ -51,8 +50,8 @@ func (r *Router) runLpMsgUnmarshal() {
 
 		case schema.LpMsgEventAdd:
 			addMsg := &schema.LpMsgAdd{}
-			if err := json.Unmarshal(src.Data, addMsg); err != nil {
-				r.lpHub.Publish(src.ID, []byte(WsErrInvalidMsg.Error()))
+			if err := src.Unmarshal(addMsg); err != nil {
+				r.lpHub.Publish(src.ID, WsErrInvalidMsg)
 				log.Error("invalid message from lp", "err", err, "msg", string(src.Data))
 				continue
 			}
@@ -62,8 +61,8 @@ func (r *Router) runLpMsgUnmarshal() {
 
 		case schema.LpMsgEventRemove:
 			removeMsg := &schema.LpMsgRemove{}
-			if err := json.Unmarshal(src.Data, removeMsg); err != nil {
-				r.lpHub.Publish(src.ID, []byte(WsErrInvalidMsg.Error()))
+			if err := src.Unmarshal(removeMsg); err != nil {
+				r.lpHub.Publish(src.ID, WsErrInvalidMsg)
 				log.Error("invalid message from lp", "err", err, "msg", string(src.Data))
 				continue
 			}
@@ -73,8 +72,8 @@ func (r *Router) runLpMsgUnmarshal() {
 
 		case schema.LpMsgEventSign:
 			signMsg := &schema.LpMsgSign{}
-			if err := json.Unmarshal(src.Data, signMsg); err != nil {
-				r.lpHub.Publish(src.ID, []byte(WsErrInvalidMsg.Error()))
+			if err := src.Unmarshal(signMsg); err != nil {
+				r.lpHub.Publish(src.ID, WsErrInvalidMsg)
 				log.Error("invalid message from lp", "err", err, "msg", string(src.Data))
 				continue
 			}
@@ -84,8 +83,8 @@ func (r *Router) runLpMsgUnmarshal() {
 
 		case schema.LpMsgEventReject:
 			rejectMsg := &schema.LpMsgReject{}
-			if err := json.Unmarshal(src.Data, rejectMsg); err != nil {
-				r.lpHub.Publish(src.ID, []byte(WsErrInvalidMsg.Error()))
+			if err := src.Unmarshal(rejectMsg); err != nil {
+				r.lpHub.Publish(src.ID, WsErrInvalidMsg)
 				log.Error("invalid message from lp", "err", err, "msg", string(src.Data))
 				continue
 			}
@@ -94,7 +93,7 @@ func (r *Router) runLpMsgUnmarshal() {
 			r.lpReject <- rejectMsg
 
 		default:
-			r.lpHub.Publish(src.ID, []byte(WsErrInvalidMsg.Error()))
+			r.lpHub.Publish(src.ID, WsErrInvalidMsg)
 			log.Error("invalid message action", "msg", string(src.Data))
 		}
 	}
@@ -114,7 +113,7 @@ func (r *Router) lpInitProc(id string) {
 	salt := uuid.NewString()
 	r.lpSalt[id] = salt
 
-	r.lpHub.Publish(id, schema.LpMsgSalt{Salt: salt}.Marshal())
+	r.lpHub.Publish(id, schema.LpMsgSalt{Salt: salt, ProtocolVersions: schema.ProtocolVersionsSupported}.WithEvent())
 }
 
 func (r *Router) lpRegisterProc(msg *schema.LpMsgRegister) {
@@ -123,19 +122,19 @@ func (r *Router) lpRegisterProc(msg *schema.LpMsgRegister) {
 	lpClienInfo, ok := r.LpClientInfo[msg.LpClientName]
 	if !ok {
 		log.Error("lp client name is invalid")
-		r.lpHub.Publish(msg.ID, []byte(WsErrInvalidLpClient.Error()))
+		r.lpHub.Publish(msg.ID, WsErrInvalidLpClient)
 		return
 	}
 	if semver.Compare(lpClienInfo.Version, msg.LpClientVersion) == 1 {
 		log.Error("lp client version is invalid", "lp name", msg.LpClientName, "lp version", msg.LpClientVersion, "user", msg.Address)
-		r.lpHub.Publish(msg.ID, []byte(WsErrInvalidLpClient.Error()))
+		r.lpHub.Publish(msg.ID, WsErrInvalidLpClient)
 		return
 	}
 
 	// check black list
 	if ok := r.penalty.IsBlackListed(msg.Address); ok {
 		log.Error("lp account is in black list")
-		r.lpHub.Publish(msg.ID, []byte(WsErrBlackListed.Error()))
+		r.lpHub.Publish(msg.ID, WsErrBlackListed)
 		return
 	}
 
@@ -143,7 +142,7 @@ func (r *Router) lpRegisterProc(msg *schema.LpMsgRegister) {
 	salt, ok := r.lpSalt[msg.ID]
 	if !ok {
 		log.Error("salt not found")
-		r.lpHub.Publish(msg.ID, []byte(WsErrNotFoundSalt.Error()))
+		r.lpHub.Publish(msg.ID, WsErrNotFoundSalt)
 		return
 	}
 
@@ -151,20 +150,20 @@ func (r *Router) lpRegisterProc(msg *schema.LpMsgRegister) {
 	accType, accid, err := utils.IDCheck(msg.Address)
 	if err != nil {
 		log.Warn("invalid account", "err", err)
-		r.lpHub.Publish(msg.ID, []byte(WsErrInvalidAddress.Error()))
+		r.lpHub.Publish(msg.ID, WsErrInvalidAddress)
 		return
 	}
 
 	//check is nft holder
 	if r.CheckNFTOrNot() && !r.NFTInfo.Passed(accid) {
 		log.Warn("not a nft owner", "acc.ID", accid)
-		r.lpHub.Publish(msg.ID, []byte(WsErrNotNFTOwner.Error()))
+		r.lpHub.Publish(msg.ID, WsErrNotNFTOwner)
 		return
 	}
 
 	// check duplicate registration
 	if r.isLpByAddr(accid) {
-		r.lpHub.Publish(msg.ID, []byte(WsErrDuplicateRegistration.Error()))
+		r.lpHub.Publish(msg.ID, WsErrDuplicateRegistration)
 		return
 	}
 
@@ -172,14 +171,14 @@ func (r *Router) lpRegisterProc(msg *schema.LpMsgRegister) {
 	err = VerifySig(accType, accid, salt, msg.Sig, int(r.chainID))
 	if err != nil {
 		log.Warn("invalid account", "err", err)
-		r.lpHub.Publish(msg.ID, []byte(WsErrInvalidSignature.Error()))
+		r.lpHub.Publish(msg.ID, WsErrInvalidSignature)
 		return
 	}
 
 	// register
 	r.lpAddrToID[accid] = msg.ID
 	r.lpIDtoAddr[msg.ID] = accid
-	r.lpHub.Publish(msg.ID, schema.LpMsgOk.Marshal())
+	r.lpHub.Publish(msg.ID, schema.LpMsgOk.WithEvent())
 }
 
 func (r *Router) lpUnregisterProc(id string) {
@@ -203,7 +202,7 @@ func (r *Router) lpAddProc(msg *schema.LpMsgAdd) {
 		r.lpHub.Publish(msg.ID, schema.LpMsgAddResponse{
 			Msg:   "failed",
 			Error: err.Error(),
-		}.Marshal())
+		}.WithEvent())
 		return
 	}
 
@@ -214,7 +213,7 @@ func (r *Router) lpAddProc(msg *schema.LpMsgAdd) {
 				LpID:  lpID,
 				Msg:   "failed",
 				Error: WsErrCanNotUpdateLp.Error(),
-			}.Marshal())
+			}.WithEvent())
 			return
 		}
 	}
@@ -225,14 +224,14 @@ func (r *Router) lpAddProc(msg *schema.LpMsgAdd) {
 			LpID:  lpID,
 			Msg:   "failed",
 			Error: err.Error(),
-		}.Marshal())
+		}.WithEvent())
 		return
 	}
 
 	r.lpHub.Publish(msg.ID, schema.LpMsgAddResponse{
 		LpID: lpID,
 		Msg:  "ok",
-	}.Marshal())
+	}.WithEvent())
 
 	// add token tag to cache
 	go func(tagA, tagB string) {
@@ -257,7 +256,7 @@ func (r *Router) lpRemoveProc(msg *schema.LpMsgRemove) {
 		r.lpHub.Publish(msg.ID, schema.LpMsgRemoveResponse{
 			Msg:   "failed",
 			Error: err.Error(),
-		}.Marshal())
+		}.WithEvent())
 		return
 	}
 
@@ -268,7 +267,7 @@ func (r *Router) lpRemoveProc(msg *schema.LpMsgRemove) {
 				LpID:  lpID,
 				Msg:   "failed",
 				Error: WsErrCanNotUpdateLp.Error(),
-			}.Marshal())
+			}.WithEvent())
 			return
 		}
 	}
@@ -279,14 +278,14 @@ func (r *Router) lpRemoveProc(msg *schema.LpMsgRemove) {
 			LpID:  lpID,
 			Msg:   "failed",
 			Error: err.Error(),
-		}.Marshal())
+		}.WithEvent())
 		return
 	}
 
 	r.lpHub.Publish(msg.ID, schema.LpMsgRemoveResponse{
 		LpID: lpID,
 		Msg:  "ok",
-	}.Marshal())
+	}.WithEvent())
 
 	r.pushNewOrder(msg.TokenX, msg.TokenY)
 }
@@ -296,7 +295,7 @@ func (r *Router) lpSignProc(msg *schema.LpMsgSign) {
 	order, ok := r.orders[msg.Bundle.HashHex()]
 	if !ok {
 		log.Warn("not found order")
-		r.lpHub.Publish(msg.ID, []byte(WsErrInvalidOrder.Error()))
+		r.lpHub.Publish(msg.ID, WsErrInvalidOrder)
 		return
 	}
 	order.lpSig <- msg
@@ -307,7 +306,7 @@ func (r *Router) lpRejectProc(msg *schema.LpMsgReject) {
 	order, ok := r.orders[msg.OrderHash]
 	if !ok {
 		log.Warn("not found order")
-		r.lpHub.Publish(msg.ID, []byte(WsErrInvalidOrder.Error()))
+		r.lpHub.Publish(msg.ID, WsErrInvalidOrder)
 		return
 	}
 
@@ -5,9 +5,11 @@ import (
 	"testing"
 	"time"
 
+	apd "github.com/cockroachdb/apd/v3"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/everFinance/goether"
 	"github.com/permadao/permaswap/router/schema"
+	"github.com/permadao/permaswap/wshub"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
@@ -167,3 +169,63 @@ func TestLpAddAndRemove(t *testing.T) {
 func TestLpSign(t *testing.T) {
 	//see TestUserSubmit
 }
+
+func TestDecodeLpMsgRegister(t *testing.T) {
+	v1 := []byte(`{"event":"register","address":"0x1","sig":"0x2","lpClientName":"lp-golang","lpClientVerison":"v0.4.0"}`)
+	v2 := []byte(`{"event":"register","address":"0x1","sig":"0x2","lpClientName":"lp-golang","lpClientVersion":"v0.5.5"}`)
+
+	msg, err := schema.DecodeLpMsgRegister(wshub.Message{Data: v1, Encoding: wshub.EncodingJSON, Version: schema.ProtocolVersionV1})
+	assert.NoError(t, err)
+	assert.Equal(t, "v0.4.0", msg.LpClientVersion)
+	assert.Equal(t, "0x1", msg.Address)
+
+	msg, err = schema.DecodeLpMsgRegister(wshub.Message{Data: v2, Encoding: wshub.EncodingJSON, Version: schema.ProtocolVersionV2})
+	assert.NoError(t, err)
+	assert.Equal(t, "v0.5.5", msg.LpClientVersion)
+	assert.Equal(t, "lp-golang", msg.LpClientName)
+
+	// v2 field is ignored by v1
+	msg, err = schema.DecodeLpMsgRegister(wshub.Message{Data: v2, Encoding: wshub.EncodingJSON, Version: schema.ProtocolVersionV1})
+	assert.NoError(t, err)
+	assert.Equal(t, "", msg.LpClientVersion)
+
+	_, err = schema.DecodeLpMsgRegister(wshub.Message{Data: []byte(`{"event":1}`), Encoding: wshub.EncodingJSON, Version: schema.ProtocolVersionV2})
+	assert.Error(t, err)
+
+	// typed msg in cbor
+	by, err := wshub.Marshal(wshub.EncodingCBOR, schema.LpMsgRegisterV2{Address: "0x1", LpClientVersion: "v0.5.5"}.WithEvent())
+	assert.NoError(t, err)
+	msg, err = schema.DecodeLpMsgRegister(wshub.Message{Data: by, Encoding: wshub.EncodingCBOR, Version: schema.ProtocolVersionV2})
+	assert.NoError(t, err)
+	assert.Equal(t, "v0.5.5", msg.LpClientVersion)
+	assert.Equal(t, schema.LpMsgEventRegister, msg.Event)
+}
+
+func TestLpMsgAddCBOR(t *testing.T) {
+	price, _, _ := apd.NewFromString("1.0488088481701515")
+	feeRatio, _, _ := apd.NewFromString("0.003")
+	add := schema.LpMsgAdd{
+		TokenX:           "ethereum-eth-0x0000000000000000000000000000000000000000",
+		TokenY:           "ethereum-usdc-0xb7a4f3e9097c08da09517b5ab877f7a917224ede",
+		FeeRatio:         feeRatio,
+		CurrentSqrtPrice: price,
+		LowSqrtPrice:     price,
+		Liquidity:        "1000",
+	}.WithEvent()
+	by, err := wshub.Marshal(wshub.EncodingCBOR, add)
+	assert.NoError(t, err)
+
+	res := schema.LpMsgAdd{}
+	assert.NoError(t, wshub.Message{Data: by, Encoding: wshub.EncodingCBOR}.Unmarshal(&res))
+	assert.Equal(t, schema.LpMsgEventAdd, res.Event)
+	assert.Equal(t, "1.0488088481701515", res.CurrentSqrtPrice.String())
+	assert.Equal(t, "0.003", res.FeeRatio.String())
+	assert.Nil(t, res.HighSqrtPrice)
+
+	remove := schema.LpMsgRemove{FeeRatio: feeRatio, LowSqrtPrice: price}.WithEvent()
+	by, err = wshub.Marshal(wshub.EncodingCBOR, remove)
+	assert.NoError(t, err)
+	resRemove := schema.LpMsgRemove{}
+	assert.NoError(t, wshub.Message{Data: by, Encoding: wshub.EncodingCBOR}.Unmarshal(&resRemove))
+	assert.Equal(t, "1.0488088481701515", resRemove.LowSqrtPrice.String())
+}
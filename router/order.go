@@ -328,7 +328,7 @@ func (o *Order) askSig() {
 			UserAddr: o.UserMsg.Address,
 			Bundle:   o.Bundle.Bundle,
 			Paths:    o.UserMsg.Paths,
-		}.Marshal())
+		}.WithEvent())
 	}
 }
 
@@ -400,11 +400,11 @@ func (o *Order) notice() {
 	}
 
 	// notice user
-	o.router.userHub.Publish(o.UserMsg.ID, statusMsg.Marshal())
+	o.router.userHub.Publish(o.UserMsg.ID, statusMsg.WithEvent())
 
 	// notice lps
 	for _, id := range o.lpAddrToID {
-		o.router.lpHub.Publish(id, statusMsg.Marshal())
+		o.router.lpHub.Publish(id, statusMsg.WithEvent())
 	}
 }
 
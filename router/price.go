@@ -2,6 +2,7 @@ package router
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -73,6 +74,18 @@ func (p *Price) GetPrice(tokenTag string) (price float64, ok bool) {
 	return
 }
 
+// Prices return usd prices of tokens in decimal string, token tag -> price
+func (p *Price) Prices() map[string]string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	prices := make(map[string]string, len(p.tokenTagToPrice))
+	for tag, price := range p.tokenTagToPrice {
+		prices[tag] = strconv.FormatFloat(price, 'f', -1, 64)
+	}
+	return prices
+}
+
 func GetTokenPriceByRedstone(tokenSymbol string, currency string, timestamp string) (float64, error) {
 	cli := gentleman.New()
 	cli.URL("https://api.redstone.finance")
@@ -51,6 +51,12 @@ func (r *Router) runProcess() {
 		case poolID := <-r.apiGetPoolReq:
 			r.apiGetPoolRes <- r.getPoolProc(poolID)
 
+		case msg := <-r.apiQuoteReq:
+			r.apiQuoteRes <- r.quoteProc(msg)
+
+		case msgs := <-r.apiBatchQuoteReq:
+			r.apiBatchQuoteRes <- r.batchQuoteProc(msgs)
+
 		//stats
 		case <-r.getAllLpsReq:
 			r.apiGetLpsRes <- r.getAllLpsProc()
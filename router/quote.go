@@ -0,0 +1,198 @@
+package router
+
+import (
+	"math/big"
+	"net/http"
+
+	"github.com/everVision/everpay-kits/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/permadao/permaswap/core"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/permadao/permaswap/router/schema"
+)
+
+const (
+	// quote address is used to build paths when quote without address
+	QuoteAddress = "0x0000000000000000000000000000000000000000"
+
+	// max number of quotes in one batch request
+	MaxBatchQuotes = 100
+)
+
+func newQuoteMsg(req schema.QuoteReq) *schema.UserMsgQuery {
+	address := req.Address
+	if address == "" {
+		address = QuoteAddress
+	}
+	return &schema.UserMsgQuery{
+		Event:    schema.UserMsgEventQuery,
+		Address:  address,
+		TokenIn:  req.TokenIn,
+		TokenOut: req.TokenOut,
+		AmountIn: req.AmountIn,
+	}
+}
+
+// GET /quote?tokenIn=xxx&tokenOut=xxx&amountIn=xxx&address=xxx
+// address is optional
+func (r *Router) getQuote(c *gin.Context) {
+	msg := newQuoteMsg(schema.QuoteReq{
+		Address:  c.Query("address"),
+		TokenIn:  c.Query("tokenIn"),
+		TokenOut: c.Query("tokenOut"),
+		AmountIn: c.Query("amountIn"),
+	})
+	if msg.TokenIn == "" || msg.TokenOut == "" || msg.AmountIn == "" {
+		c.JSON(http.StatusBadRequest, NewWsErr("err_no_param"))
+		return
+	}
+
+	r.apiQuoteReq <- msg
+	res := <-r.apiQuoteRes
+	if res.Error != "" {
+		c.JSON(http.StatusBadRequest, NewWsErr(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// POST /quotes
+// {"quotes":[{"tokenIn":"xxx","tokenOut":"xxx","amountIn":"xxx","address":"xxx"}]}
+// result of each quote is returned in the same order, failed quote has error field.
+func (r *Router) batchQuote(c *gin.Context) {
+	req := schema.BatchQuoteReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, NewWsErr("err_invalid_param"))
+		return
+	}
+	if len(req.Quotes) == 0 || len(req.Quotes) > MaxBatchQuotes {
+		c.JSON(http.StatusBadRequest, NewWsErr("err_invalid_param"))
+		return
+	}
+
+	msgs := make([]*schema.UserMsgQuery, 0, len(req.Quotes))
+	for _, q := range req.Quotes {
+		msgs = append(msgs, newQuoteMsg(q))
+	}
+
+	r.apiBatchQuoteReq <- msgs
+	c.JSON(http.StatusOK, schema.BatchQuoteRes{Quotes: <-r.apiBatchQuoteRes})
+}
+
+// batchQuoteProc run all quotes in one process step, so they are priced against the same core
+func (r *Router) batchQuoteProc(msgs []*schema.UserMsgQuery) []*schema.QuoteRes {
+	res := make([]*schema.QuoteRes, 0, len(msgs))
+	for _, msg := range msgs {
+		res = append(res, r.quoteProc(msg))
+	}
+	return res
+}
+
+// quoteProc run query in router core without any session cache
+func (r *Router) quoteProc(msg *schema.UserMsgQuery) *schema.QuoteRes {
+	res := &schema.QuoteRes{
+		TokenIn:  msg.TokenIn,
+		TokenOut: msg.TokenOut,
+		AmountIn: msg.AmountIn,
+	}
+
+	paths, err := r.core.Quote(*msg)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if len(paths) == 0 {
+		res.Error = core.ERR_NO_PATH.Error()
+		return res
+	}
+
+	price, _, _ := CalPrice(paths, r.tokens, msg.TokenIn, msg.TokenOut, msg.Address)
+	if price == nil {
+		res.Error = WsErrInvalidToken.Msg
+		return res
+	}
+
+	lps, err := QuoteLpsFromPaths(msg.Address, paths, r.core.Lps)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	res.AmountOut = QuoteAmountOut(msg.Address, msg.TokenOut, paths)
+	res.Price = price.Text('f', 10)
+	res.PriceImpact = r.calPriceImpact(msg, price)
+	res.PoolPath = QuotePoolPath(lps)
+	res.Lps = lps
+	res.Paths = paths
+	return res
+}
+
+// QuoteLpsFromPaths return per lp swap detail, in the order of paths
+func QuoteLpsFromPaths(user string, paths []coreSchema.Path, lps map[string]*coreSchema.Lp) ([]schema.QuoteLp, error) {
+	swapInputs, err := core.PathsToSwapInputs(user, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []schema.QuoteLp{}
+	for _, path := range paths {
+		si, ok := swapInputs[path.LpID]
+		if !ok {
+			continue
+		}
+		delete(swapInputs, path.LpID)
+
+		poolID := ""
+		if lp, ok := lps[si.LpID]; ok {
+			poolID = lp.PoolID
+		}
+		res = append(res, schema.QuoteLp{
+			LpID:      si.LpID,
+			PoolID:    poolID,
+			TokenIn:   si.TokenIn,
+			AmountIn:  si.AmountIn.String(),
+			TokenOut:  si.TokenOut,
+			AmountOut: si.AmountOut.String(),
+		})
+	}
+	return res, nil
+}
+
+// QuotePoolPath return the pool ids which the swap goes through
+func QuotePoolPath(lps []schema.QuoteLp) []string {
+	poolPath := []string{}
+	isAdded := map[string]bool{}
+	for _, lp := range lps {
+		if lp.PoolID == "" || isAdded[lp.PoolID] {
+			continue
+		}
+		isAdded[lp.PoolID] = true
+		poolPath = append(poolPath, lp.PoolID)
+	}
+	return poolPath
+}
+
+// QuoteAmountOut return the total amount of tokenOut received by user
+func QuoteAmountOut(user, tokenOut string, paths []coreSchema.Path) string {
+	_, addr, err := utils.IDCheck(user)
+	if err != nil {
+		return "0"
+	}
+
+	amountOut := big.NewInt(0)
+	for _, path := range paths {
+		if path.TokenTag != tokenOut {
+			continue
+		}
+		_, to, err := utils.IDCheck(path.To)
+		if err != nil || to != addr {
+			continue
+		}
+		amount, ok := new(big.Int).SetString(path.Amount, 10)
+		if !ok {
+			continue
+		}
+		amountOut.Add(amountOut, amount)
+	}
+	return amountOut.String()
+}
@@ -0,0 +1,186 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apd "github.com/cockroachdb/apd/v3"
+	everSchema "github.com/everVision/everpay-kits/schema"
+	"github.com/gin-gonic/gin"
+	"github.com/permadao/permaswap/core"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/permadao/permaswap/router/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteLpsFromPaths(t *testing.T) {
+	user := "0x45a9f23ac5af2dcdcb11f1386977d2bfea7dad5b"
+	lp1 := "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+	lp2 := "0x61EbF673c200646236B2c53465bcA0699455d5FA"
+	fee := "0x911F42b0229c15bBB38D648B7Aa7CA480eD977d6"
+	eth := "ethereum-eth-0x0000000000000000000000000000000000000000"
+	usdc := "ethereum-usdc-0xf044320bcc3cd1f6100cd197754c71941469e79c"
+	usdt := "ethereum-usdt-0x923fcb255da521037385457fb549a51f78ef0af4"
+
+	paths := []coreSchema.Path{
+		{LpID: "1", From: user, To: lp1, TokenTag: eth, Amount: "1000"},
+		{LpID: "1", From: lp1, To: user, TokenTag: usdc, Amount: "3000"},
+		{LpID: "2", From: user, To: lp2, TokenTag: usdc, Amount: "3000"},
+		{LpID: "2", From: lp2, To: user, TokenTag: usdt, Amount: "2990"},
+		{LpID: "", From: user, To: fee, TokenTag: eth, Amount: "3"},
+	}
+	lps := map[string]*coreSchema.Lp{
+		"1": {PoolID: "pool-eth-usdc"},
+		"2": {PoolID: "pool-usdc-usdt"},
+	}
+
+	quoteLps, err := QuoteLpsFromPaths(user, paths, lps)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(quoteLps))
+	assert.Equal(t, "1", quoteLps[0].LpID)
+	assert.Equal(t, eth, quoteLps[0].TokenIn)
+	assert.Equal(t, "3000", quoteLps[0].AmountOut)
+	assert.Equal(t, "2", quoteLps[1].LpID)
+	assert.Equal(t, usdt, quoteLps[1].TokenOut)
+
+	assert.Equal(t, []string{"pool-eth-usdc", "pool-usdc-usdt"}, QuotePoolPath(quoteLps))
+	assert.Equal(t, "2990", QuoteAmountOut(user, usdt, paths))
+}
+
+func TestNewQuoteMsg(t *testing.T) {
+	msg := newQuoteMsg(schema.QuoteReq{
+		TokenIn:  "ethereum-eth-0x0000000000000000000000000000000000000000",
+		TokenOut: "ethereum-usdt-0x923fcb255da521037385457fb549a51f78ef0af4",
+		AmountIn: "1000",
+	})
+	assert.Equal(t, QuoteAddress, msg.Address)
+	assert.Equal(t, schema.UserMsgEventQuery, msg.Event)
+
+	msg = newQuoteMsg(schema.QuoteReq{Address: "0x61EbF673c200646236B2c53465bcA0699455d5FA"})
+	assert.Equal(t, "0x61EbF673c200646236B2c53465bcA0699455d5FA", msg.Address)
+}
+
+const (
+	testQuoteEth  = "ethereum-eth-0x0000000000000000000000000000000000000000"
+	testQuoteUsdt = "ethereum-usdt-0xd85476c906b5301e8e9eb58d174a6f96b9dfc5ee"
+)
+
+// testQuoteRouter return router with an eth-usdt lp in core, without db and sessions
+func testQuoteRouter(t *testing.T) *Router {
+	pool, err := core.NewPool(testQuoteEth, testQuoteUsdt, "0.003")
+	assert.NoError(t, err)
+	c := core.New(map[string]*coreSchema.Pool{pool.ID(): pool}, "", "")
+
+	decimal := func(s string) *apd.Decimal {
+		d, _, _ := apd.NewFromString(s)
+		return d
+	}
+	assert.NoError(t, c.AddLiquidity("0x61EbF673c200646236B2c53465bcA0699455d5FA", schema.LpMsgAdd{
+		TokenX:           testQuoteEth,
+		TokenY:           testQuoteUsdt,
+		FeeRatio:         decimal("0.003"),
+		LowSqrtPrice:     decimal("0.000044721359549995793928183473374626"),
+		CurrentSqrtPrice: decimal("0.000054792195750516611345696978280080"),
+		HighSqrtPrice:    decimal("0.000063245553203367586639977870888654"),
+		Liquidity:        "50000000000000000",
+		PriceDirection:   "both",
+	}))
+
+	return &Router{
+		core: c,
+		tokens: map[string]*everSchema.Token{
+			testQuoteEth:  {ID: "0x0000000000000000000000000000000000000000", Symbol: "eth", ChainType: "ethereum", Decimals: 18},
+			testQuoteUsdt: {ID: "0xd85476c906b5301e8e9eb58d174a6f96b9dfc5ee", Symbol: "usdt", ChainType: "ethereum", Decimals: 6},
+		},
+		apiBatchQuoteReq: make(chan []*schema.UserMsgQuery),
+		apiBatchQuoteRes: make(chan []*schema.QuoteRes),
+	}
+}
+
+func TestBatchQuote(t *testing.T) {
+	r := testQuoteRouter(t)
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.POST("/quotes", r.batchQuote)
+	go func() {
+		for msgs := range r.apiBatchQuoteReq {
+			r.apiBatchQuoteRes <- r.batchQuoteProc(msgs)
+		}
+	}()
+	defer close(r.apiBatchQuoteReq)
+
+	post := func(quotes []schema.QuoteReq) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(schema.BatchQuoteReq{Quotes: quotes})
+		req := httptest.NewRequest(http.MethodPost, "/quotes", strings.NewReader(string(body)))
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		return w
+	}
+
+	// size limit
+	assert.Equal(t, http.StatusBadRequest, post(nil).Code)
+	quotes := make([]schema.QuoteReq, MaxBatchQuotes+1)
+	for i := range quotes {
+		quotes[i] = schema.QuoteReq{TokenIn: testQuoteUsdt, TokenOut: testQuoteEth, AmountIn: "1000000"}
+	}
+	assert.Equal(t, http.StatusBadRequest, post(quotes).Code)
+	w := post(quotes[:MaxBatchQuotes])
+	assert.Equal(t, http.StatusOK, w.Code)
+	res := schema.BatchQuoteRes{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, MaxBatchQuotes, len(res.Quotes))
+
+	req := httptest.NewRequest(http.MethodPost, "/quotes", strings.NewReader("invalid"))
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// errors of items are returned in order, other items are not affected
+	w = post([]schema.QuoteReq{
+		{TokenIn: testQuoteUsdt, TokenOut: testQuoteEth, AmountIn: "1000000"},
+		{TokenIn: testQuoteUsdt, TokenOut: testQuoteEth, AmountIn: "0"},
+		{TokenIn: testQuoteUsdt, TokenOut: "ethereum-usdc-0xb7a4f3e9097c08da09517b5ab877f7a917224ede", AmountIn: "1000000"},
+		{TokenIn: testQuoteEth, TokenOut: testQuoteUsdt, AmountIn: "1000000000000"},
+		// priced beyond range of every lp
+		{TokenIn: testQuoteEth, TokenOut: testQuoteUsdt, AmountIn: "1000000000000000000000000"},
+		{TokenIn: testQuoteUsdt, TokenOut: testQuoteEth, AmountIn: "2000000"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	res = schema.BatchQuoteRes{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, 6, len(res.Quotes))
+	assert.Equal(t, "", res.Quotes[0].Error)
+	assert.NotEqual(t, "", res.Quotes[0].AmountOut)
+	assert.Equal(t, core.ERR_INVALID_AMOUNT.Error(), res.Quotes[1].Error)
+	assert.NotEqual(t, "", res.Quotes[2].Error)
+	assert.Equal(t, "", res.Quotes[3].Error)
+	assert.Equal(t, testQuoteEth, res.Quotes[3].TokenIn)
+	assert.Equal(t, core.ERR_OUT_OF_RANGE.Error(), res.Quotes[4].Error)
+	assert.Equal(t, "", res.Quotes[4].AmountOut)
+	assert.Equal(t, "", res.Quotes[5].Error)
+	assert.NotEqual(t, "", res.Quotes[5].AmountOut)
+}
+
+func TestBatchQuoteProcSnapshot(t *testing.T) {
+	r := testQuoteRouter(t)
+	lps := r.core.GetAllLps()
+	single := r.quoteProc(&schema.UserMsgQuery{Address: QuoteAddress, TokenIn: testQuoteUsdt, TokenOut: testQuoteEth, AmountIn: "1000000"})
+	assert.Equal(t, "", single.Error)
+
+	// every item is priced against the same core, quotes do not change lps
+	msgs := []*schema.UserMsgQuery{}
+	for i := 0; i < 3; i++ {
+		msgs = append(msgs, &schema.UserMsgQuery{Address: QuoteAddress, TokenIn: testQuoteUsdt, TokenOut: testQuoteEth, AmountIn: "1000000"})
+	}
+	res := r.batchQuoteProc(msgs)
+	assert.Equal(t, 3, len(res))
+	for _, q := range res {
+		assert.Equal(t, single.AmountOut, q.AmountOut)
+		assert.Equal(t, single.Price, q.Price)
+		assert.Equal(t, single.Paths, q.Paths)
+	}
+	assert.Equal(t, lps, r.core.GetAllLps())
+}
@@ -48,6 +48,12 @@ type Router struct {
 	apiGetPoolReq chan string
 	apiGetPoolRes chan *schema.PoolRes
 
+	apiQuoteReq chan *schema.UserMsgQuery
+	apiQuoteRes chan *schema.QuoteRes
+
+	apiBatchQuoteReq chan []*schema.UserMsgQuery
+	apiBatchQuoteRes chan []*schema.QuoteRes
+
 	// api cache
 	apiTokenTags     map[string]bool
 	apiTokenTagsLock sync.RWMutex
@@ -136,6 +142,8 @@ func New(config *Config, haloConfig *halo.Config, everSDK *sdk.SDK, haloSDK *hal
 	var haloServer *halo.Halo
 	if haloConfig.Genesis != "" {
 		haloServer = halo.New(haloConfig.Genesis, config.Mysql, everSDK)
+		haloServer.SetDryRunToken(haloConfig.DryRunToken)
+		haloServer.SetStateLogs(haloConfig.StateLogs)
 	}
 
 	return &Router{
@@ -157,6 +165,10 @@ func New(config *Config, haloConfig *halo.Config, everSDK *sdk.SDK, haloSDK *hal
 		apiGetLpsRes:         make(chan []coreSchema.Lp),
 		apiGetPoolReq:        make(chan string),
 		apiGetPoolRes:        make(chan *schema.PoolRes),
+		apiQuoteReq:          make(chan *schema.UserMsgQuery),
+		apiQuoteRes:          make(chan *schema.QuoteRes),
+		apiBatchQuoteReq:     make(chan []*schema.UserMsgQuery),
+		apiBatchQuoteRes:     make(chan []*schema.QuoteRes),
 		apiTokenTags:         make(map[string]bool),
 
 		getAllLpsReq: make(chan struct{}),
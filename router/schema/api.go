@@ -11,6 +11,10 @@ type InfoRes struct {
 	TokenList     []string                 `json:"tokenList"`
 	PoolList      map[string]*schema.Pool  `json:"poolList"`
 	LpClientInfo  map[string]*LpClientInfo `json:"lpClientInfo"`
+	// protocol versions supported by router
+	ProtocolVersions []string `json:"protocolVersions"`
+	// websocket msg encodings supported by router
+	Encodings []string `json:"encodings"`
 }
 
 type OrdersRes struct {
@@ -60,3 +64,40 @@ type PenaltyRes struct {
 	FailureRecords     map[string][]FailureRecord `json:"failureRecords"`
 	BlackList          map[string]int64           `json:"blackList"`
 }
+
+type QuoteLp struct {
+	LpID      string `json:"lpID"`
+	PoolID    string `json:"poolID"`
+	TokenIn   string `json:"tokenIn"`
+	AmountIn  string `json:"amountIn"`
+	TokenOut  string `json:"tokenOut"`
+	AmountOut string `json:"amountOut"`
+}
+
+type QuoteRes struct {
+	TokenIn     string        `json:"tokenIn"`
+	TokenOut    string        `json:"tokenOut"`
+	AmountIn    string        `json:"amountIn"`
+	AmountOut   string        `json:"amountOut"`
+	Price       string        `json:"price"`
+	PriceImpact string        `json:"priceImpact"`
+	PoolPath    []string      `json:"poolPath"`
+	Lps         []QuoteLp     `json:"lps"`
+	Paths       []schema.Path `json:"paths"`
+	Error       string        `json:"error,omitempty"`
+}
+
+type QuoteReq struct {
+	Address  string `json:"address" spec:"optional"`
+	TokenIn  string `json:"tokenIn"`
+	TokenOut string `json:"tokenOut"`
+	AmountIn string `json:"amountIn"`
+}
+
+type BatchQuoteReq struct {
+	Quotes []QuoteReq `json:"quotes"`
+}
+
+type BatchQuoteRes struct {
+	Quotes []*QuoteRes `json:"quotes"`
+}
@@ -0,0 +1,119 @@
+package schema
+
+import (
+	apd "github.com/cockroachdb/apd/v3"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// apd decimals have no cbor encoding, msgs with decimals are encoded by cbor with decimal strings
+
+type lpMsgAddCBOR struct {
+	ID    string `cbor:"id"`
+	Event string `cbor:"event"`
+
+	TokenX   string `cbor:"tokenX"`
+	TokenY   string `cbor:"tokenY"`
+	FeeRatio string `cbor:"feeRatio"`
+
+	CurrentSqrtPrice string `cbor:"currentSqrtPrice"`
+	LowSqrtPrice     string `cbor:"lowSqrtPrice"`
+	HighSqrtPrice    string `cbor:"highSqrtPrice"`
+	Liquidity        string `cbor:"liquidity"`
+	PriceDirection   string `cbor:"priceDirection"`
+}
+
+func (l LpMsgAdd) MarshalCBOR() ([]byte, error) {
+	return cbor.Marshal(lpMsgAddCBOR{
+		ID:               l.ID,
+		Event:            l.Event,
+		TokenX:           l.TokenX,
+		TokenY:           l.TokenY,
+		FeeRatio:         decimalString(l.FeeRatio),
+		CurrentSqrtPrice: decimalString(l.CurrentSqrtPrice),
+		LowSqrtPrice:     decimalString(l.LowSqrtPrice),
+		HighSqrtPrice:    decimalString(l.HighSqrtPrice),
+		Liquidity:        l.Liquidity,
+		PriceDirection:   l.PriceDirection,
+	})
+}
+
+func (l *LpMsgAdd) UnmarshalCBOR(data []byte) (err error) {
+	msg := lpMsgAddCBOR{}
+	if err = cbor.Unmarshal(data, &msg); err != nil {
+		return
+	}
+	l.ID, l.Event = msg.ID, msg.Event
+	l.TokenX, l.TokenY = msg.TokenX, msg.TokenY
+	l.Liquidity, l.PriceDirection = msg.Liquidity, msg.PriceDirection
+	if l.FeeRatio, err = parseDecimal(msg.FeeRatio); err != nil {
+		return
+	}
+	if l.CurrentSqrtPrice, err = parseDecimal(msg.CurrentSqrtPrice); err != nil {
+		return
+	}
+	if l.LowSqrtPrice, err = parseDecimal(msg.LowSqrtPrice); err != nil {
+		return
+	}
+	l.HighSqrtPrice, err = parseDecimal(msg.HighSqrtPrice)
+	return
+}
+
+type lpMsgRemoveCBOR struct {
+	ID    string `cbor:"id"`
+	Event string `cbor:"event"`
+
+	TokenX   string `cbor:"tokenX"`
+	TokenY   string `cbor:"tokenY"`
+	FeeRatio string `cbor:"feeRatio"`
+
+	LowSqrtPrice   string `cbor:"lowSqrtPrice"`
+	HighSqrtPrice  string `cbor:"highSqrtPrice"`
+	PriceDirection string `cbor:"priceDirection"`
+}
+
+func (l LpMsgRemove) MarshalCBOR() ([]byte, error) {
+	return cbor.Marshal(lpMsgRemoveCBOR{
+		ID:             l.ID,
+		Event:          l.Event,
+		TokenX:         l.TokenX,
+		TokenY:         l.TokenY,
+		FeeRatio:       decimalString(l.FeeRatio),
+		LowSqrtPrice:   decimalString(l.LowSqrtPrice),
+		HighSqrtPrice:  decimalString(l.HighSqrtPrice),
+		PriceDirection: l.PriceDirection,
+	})
+}
+
+func (l *LpMsgRemove) UnmarshalCBOR(data []byte) (err error) {
+	msg := lpMsgRemoveCBOR{}
+	if err = cbor.Unmarshal(data, &msg); err != nil {
+		return
+	}
+	l.ID, l.Event = msg.ID, msg.Event
+	l.TokenX, l.TokenY = msg.TokenX, msg.TokenY
+	l.PriceDirection = msg.PriceDirection
+	if l.FeeRatio, err = parseDecimal(msg.FeeRatio); err != nil {
+		return
+	}
+	if l.LowSqrtPrice, err = parseDecimal(msg.LowSqrtPrice); err != nil {
+		return
+	}
+	l.HighSqrtPrice, err = parseDecimal(msg.HighSqrtPrice)
+	return
+}
+
+func decimalString(d *apd.Decimal) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
+
+// parseDecimal return nil for empty string, same as null in json
+func parseDecimal(s string) (*apd.Decimal, error) {
+	if s == "" {
+		return nil, nil
+	}
+	d, _, err := apd.NewFromString(s)
+	return d, err
+}
@@ -6,6 +6,7 @@ import (
 	apd "github.com/cockroachdb/apd/v3"
 	coreSchema "github.com/permadao/permaswap/core/schema"
 	everSchema "github.com/everVision/everpay-kits/schema"
+	"github.com/permadao/permaswap/wshub"
 )
 
 type LpClientInfo struct {
@@ -39,8 +40,9 @@ type LpMsg struct {
 	Event string `json:"event"`
 }
 
+// LpMsgRegister is register msg of protocol v1, and it is also used inside router for all versions
 type LpMsgRegister struct {
-	ID              string `json:"id"`
+	ID              string `json:"id" spec:"optional"`
 	Event           string `json:"event"`
 	Address         string `json:"address"`
 	Sig             string `json:"sig"`
@@ -48,14 +50,65 @@ type LpMsgRegister struct {
 	LpClientVersion string `json:"lpClientVerison"`
 }
 
+// WithEvent return msg with event set
+func (l LpMsgRegister) WithEvent() LpMsgRegister {
+	l.Event = LpMsgEventRegister
+	return l
+}
+
 func (l LpMsgRegister) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
+	return by
+}
+
+// LpMsgRegisterV2 is register msg of protocol v2
+type LpMsgRegisterV2 struct {
+	ID              string `json:"id" spec:"optional"`
+	Event           string `json:"event"`
+	Address         string `json:"address"`
+	Sig             string `json:"sig"`
+	LpClientName    string `json:"lpClientName"`
+	LpClientVersion string `json:"lpClientVersion"`
+}
+
+// WithEvent return msg with event set
+func (l LpMsgRegisterV2) WithEvent() LpMsgRegisterV2 {
 	l.Event = LpMsgEventRegister
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l LpMsgRegisterV2) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
+// DecodeLpMsgRegister decode register msg by protocol version
+func DecodeLpMsgRegister(src wshub.Message) (*LpMsgRegister, error) {
+	switch src.Version {
+	case ProtocolVersionV2:
+		msg := &LpMsgRegisterV2{}
+		if err := src.Unmarshal(msg); err != nil {
+			return nil, err
+		}
+		return &LpMsgRegister{
+			ID:              msg.ID,
+			Event:           msg.Event,
+			Address:         msg.Address,
+			Sig:             msg.Sig,
+			LpClientName:    msg.LpClientName,
+			LpClientVersion: msg.LpClientVersion,
+		}, nil
+	default:
+		msg := &LpMsgRegister{}
+		if err := src.Unmarshal(msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+}
+
 type LpMsgAdd struct {
-	ID    string `json:"id"`
+	ID    string `json:"id" spec:"optional"`
 	Event string `json:"event"`
 
 	TokenX   string       `json:"tokenX"`
@@ -69,14 +122,19 @@ type LpMsgAdd struct {
 	PriceDirection   string       `json:"priceDirection"`
 }
 
-func (l LpMsgAdd) Marshal() []byte {
+// WithEvent return msg with event set
+func (l LpMsgAdd) WithEvent() LpMsgAdd {
 	l.Event = LpMsgEventAdd
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l LpMsgAdd) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
 type LpMsgRemove struct {
-	ID    string `json:"id"`
+	ID    string `json:"id" spec:"optional"`
 	Event string `json:"event"`
 
 	TokenX   string       `json:"tokenX"`
@@ -88,35 +146,50 @@ type LpMsgRemove struct {
 	PriceDirection string       `json:"priceDirection"`
 }
 
-func (l LpMsgRemove) Marshal() []byte {
+// WithEvent return msg with event set
+func (l LpMsgRemove) WithEvent() LpMsgRemove {
 	l.Event = LpMsgEventRemove
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l LpMsgRemove) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
 type LpMsgSign struct {
-	ID      string                    `json:"id"`
+	ID      string                    `json:"id" spec:"optional"`
 	Event   string                    `json:"event"`
 	Address string                    `json:"address"`
 	Bundle  everSchema.BundleWithSigs `json:"bundle"`
 }
 
-func (l LpMsgSign) Marshal() []byte {
+// WithEvent return msg with event set
+func (l LpMsgSign) WithEvent() LpMsgSign {
 	l.Event = LpMsgEventSign
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l LpMsgSign) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
 type LpMsgReject struct {
-	ID        string `json:"id"`
+	ID        string `json:"id" spec:"optional"`
 	Event     string `json:"event"`
 	Address   string `json:"address"`
 	OrderHash string `json:"orderHash"`
 }
 
-func (l LpMsgReject) Marshal() []byte {
+// WithEvent return msg with event set
+func (l LpMsgReject) WithEvent() LpMsgReject {
 	l.Event = LpMsgEventReject
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l LpMsgReject) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
@@ -125,20 +198,32 @@ type LpMsgResponse struct {
 	Msg   string `json:"msg"`
 }
 
-func (l LpMsgResponse) Marshal() []byte {
+// WithEvent return msg with event set
+func (l LpMsgResponse) WithEvent() LpMsgResponse {
 	l.Event = LpMsgEventResponse
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l LpMsgResponse) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
 type LpMsgSalt struct {
 	Event string `json:"event"`
 	Salt  string `json:"salt"`
+	// protocol versions supported by router, empty for routers before protocol v2
+	ProtocolVersions []string `json:"protocolVersions,omitempty"`
 }
 
-func (l LpMsgSalt) Marshal() []byte {
+// WithEvent return msg with event set
+func (l LpMsgSalt) WithEvent() LpMsgSalt {
 	l.Event = LpMsgEventSalt
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l LpMsgSalt) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
@@ -149,9 +234,14 @@ type LpMsgOrder struct {
 	Paths    []coreSchema.Path `json:"paths"`
 }
 
-func (l LpMsgOrder) Marshal() []byte {
+// WithEvent return msg with event set
+func (l LpMsgOrder) WithEvent() LpMsgOrder {
 	l.Event = LpMsgEventOrder
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l LpMsgOrder) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
@@ -162,9 +252,14 @@ type LpMsgAddResponse struct {
 	Error string `json:"error"`
 }
 
-func (l LpMsgAddResponse) Marshal() []byte {
+// WithEvent return msg with event set
+func (l LpMsgAddResponse) WithEvent() LpMsgAddResponse {
 	l.Event = LpMsgEventAddResponse
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l LpMsgAddResponse) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
@@ -175,8 +270,13 @@ type LpMsgRemoveResponse struct {
 	Error string `json:"error"`
 }
 
-func (l LpMsgRemoveResponse) Marshal() []byte {
+// WithEvent return msg with event set
+func (l LpMsgRemoveResponse) WithEvent() LpMsgRemoveResponse {
 	l.Event = LpMsgEventRemoveResponse
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l LpMsgRemoveResponse) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
@@ -23,8 +23,13 @@ type OrderMsgStatus struct {
 	Status    string `json:"status"`
 }
 
-func (o OrderMsgStatus) Marshal() []byte {
+// WithEvent return msg with event set
+func (o OrderMsgStatus) WithEvent() OrderMsgStatus {
 	o.Event = OrderMsgEventStatus
-	by, _ := json.Marshal(o)
+	return o
+}
+
+func (o OrderMsgStatus) Marshal() []byte {
+	by, _ := json.Marshal(o.WithEvent())
 	return by
 }
@@ -0,0 +1,33 @@
+package schema
+
+const (
+	// v1: the original protocol, register msg uses misspelled field lpClientVerison
+	ProtocolVersionV1 = "v1"
+	// v2: register msg uses field lpClientVersion
+	ProtocolVersionV2 = "v2"
+
+	ProtocolVersionLatest = ProtocolVersionV2
+
+	// websocket url query param for protocol version, e.g. /wslp?version=v2
+	// v1 is used when the param is missing
+	ProtocolVersionParam = "version"
+
+	// websocket url query param for msg encoding, json or cbor, e.g. /wslp?version=v2&encoding=cbor
+	// json is used when the param is missing
+	EncodingParam = "encoding"
+)
+
+// protocol versions supported by router
+var ProtocolVersionsSupported = []string{
+	ProtocolVersionV1,
+	ProtocolVersionV2,
+}
+
+func IsProtocolVersionSupported(version string) bool {
+	for _, v := range ProtocolVersionsSupported {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
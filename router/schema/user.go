@@ -25,7 +25,7 @@ type UserMsg struct {
 
 // {"event":"query","address":"123","tokenIn":"ethereum-eth-0x0000000000000000000000000000000000000000","tokenOut":"ethereum-usdc-0xb7a4f3e9097c08da09517b5ab877f7a917224ede", "amountIn":"4000000000000000"}
 type UserMsgQuery struct {
-	ID       string `json:"id"`
+	ID       string `json:"id" spec:"optional"`
 	Event    string `json:"event"`
 	Address  string `json:"address"`
 	TokenIn  string `json:"tokenIn"`
@@ -33,14 +33,19 @@ type UserMsgQuery struct {
 	AmountIn string `json:"amountIn"`
 }
 
-func (l UserMsgQuery) Marshal() []byte {
+// WithEvent return msg with event set
+func (l UserMsgQuery) WithEvent() UserMsgQuery {
 	l.Event = UserMsgEventQuery
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l UserMsgQuery) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
 type UserMsgSubmit struct {
-	ID       string                    `json:"id"`
+	ID       string                    `json:"id" spec:"optional"`
 	Event    string                    `json:"event"`
 	Address  string                    `json:"address"`
 	TokenIn  string                    `json:"tokenIn"`
@@ -49,9 +54,14 @@ type UserMsgSubmit struct {
 	Paths    []coreSchema.Path         `json:"paths"`
 }
 
-func (l UserMsgSubmit) Marshal() []byte {
+// WithEvent return msg with event set
+func (l UserMsgSubmit) WithEvent() UserMsgSubmit {
 	l.Event = UserMsgEventSubmit
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l UserMsgSubmit) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
@@ -60,9 +70,14 @@ type UserMsgResponse struct {
 	Msg   string `json:"msg"`
 }
 
-func (l UserMsgResponse) Marshal() []byte {
+// WithEvent return msg with event set
+func (l UserMsgResponse) WithEvent() UserMsgResponse {
 	l.Event = UserMsgEventResponse
-	by, _ := json.Marshal(l)
+	return l
+}
+
+func (l UserMsgResponse) Marshal() []byte {
+	by, _ := json.Marshal(l.WithEvent())
 	return by
 }
 
@@ -77,8 +92,13 @@ type UserMsgOrder struct {
 	Paths       []coreSchema.Path `json:"paths"`
 }
 
-func (u UserMsgOrder) Marshal() []byte {
+// WithEvent return msg with event set
+func (u UserMsgOrder) WithEvent() UserMsgOrder {
 	u.Event = UserMsgEventOrder
-	by, _ := json.Marshal(u)
+	return u
+}
+
+func (u UserMsgOrder) Marshal() []byte {
+	by, _ := json.Marshal(u.WithEvent())
 	return by
 }
@@ -0,0 +1,89 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/permadao/permaswap/router/schema"
+	"github.com/permadao/permaswap/spec"
+)
+
+const SpecVersion = "v1"
+
+// Spec return openapi of router http api and asyncapi of user & lp websocket in protocol version
+func Spec(protocolVersion string) *spec.Spec {
+	o := spec.NewOpenAPI("permaswap router", SpecVersion)
+	o.Add("get", "/info", "router info", nil, nil, schema.InfoRes{})
+	ordersParams := []*spec.Parameter{
+		spec.QueryParam("count", false),
+		spec.QueryParam("page", false),
+		spec.QueryParam("start", false),
+		spec.QueryParam("end", false),
+	}
+	o.Add("get", "/orders", "orders of all users", ordersParams, nil, schema.OrdersRes{})
+	o.Add("get", "/orders/{accid}", "orders of a user",
+		append([]*spec.Parameter{spec.PathParam("accid")}, ordersParams...), nil, schema.OrdersRes{})
+	o.Add("get", "/pool/{poolid}", "pool and lps in pool", []*spec.Parameter{spec.PathParam("poolid")}, nil, schema.PoolRes{})
+	o.Add("get", "/lps", "lps by accid or poolid",
+		[]*spec.Parameter{spec.QueryParam("accid", false), spec.QueryParam("poolid", false)}, nil, schema.LpsRes{})
+	o.Add("get", "/nft", "nft info", nil, nil, schema.NFTRes{})
+	o.Add("get", "/stats", "stats by accid or poolid, total stats without params",
+		[]*spec.Parameter{spec.QueryParam("accid", false), spec.QueryParam("poolid", false)}, nil, schema.PoolStatsRes{})
+	o.Add("get", "/lpreward", "lp rewards by accid or lpid",
+		[]*spec.Parameter{spec.QueryParam("accid", false), spec.QueryParam("lpid", false)}, nil, schema.LpRewardsRes{})
+	o.Add("get", "/penalty", "penalty info", nil, nil, schema.PenaltyRes{})
+	o.Add("get", "/quote", "swap simulation",
+		[]*spec.Parameter{
+			spec.QueryParam("tokenIn", true),
+			spec.QueryParam("tokenOut", true),
+			spec.QueryParam("amountIn", true),
+			spec.QueryParam("address", false),
+		}, nil, schema.QuoteRes{})
+	o.Add("post", "/quotes", "batch swap simulation", nil, schema.BatchQuoteReq{}, schema.BatchQuoteRes{})
+
+	register := spec.NewMessage("LpMsgRegister", schema.LpMsgEventRegister, schema.LpMsgRegister{})
+	if protocolVersion == schema.ProtocolVersionV2 {
+		register = spec.NewMessage("LpMsgRegisterV2", schema.LpMsgEventRegister, schema.LpMsgRegisterV2{})
+	}
+
+	a := spec.NewAsyncAPI("permaswap router", protocolVersion)
+	a.Add("/wsuser", true,
+		spec.NewMessage("UserMsgQuery", schema.UserMsgEventQuery, schema.UserMsgQuery{}),
+		spec.NewMessage("UserMsgSubmit", schema.UserMsgEventSubmit, schema.UserMsgSubmit{}),
+	)
+	a.Add("/wsuser", false,
+		spec.NewMessage("UserMsgOrder", schema.UserMsgEventOrder, schema.UserMsgOrder{}),
+		spec.NewMessage("OrderMsgStatus", schema.OrderMsgEventStatus, schema.OrderMsgStatus{}),
+		spec.NewMessage("WsErr", "error", WsErr{}),
+	)
+	a.Add("/wslp", true,
+		register,
+		spec.NewMessage("LpMsgAdd", schema.LpMsgEventAdd, schema.LpMsgAdd{}),
+		spec.NewMessage("LpMsgRemove", schema.LpMsgEventRemove, schema.LpMsgRemove{}),
+		spec.NewMessage("LpMsgSign", schema.LpMsgEventSign, schema.LpMsgSign{}),
+		spec.NewMessage("LpMsgReject", schema.LpMsgEventReject, schema.LpMsgReject{}),
+	)
+	a.Add("/wslp", false,
+		spec.NewMessage("LpMsgSalt", schema.LpMsgEventSalt, schema.LpMsgSalt{}),
+		spec.NewMessage("LpMsgResponse", schema.LpMsgEventResponse, schema.LpMsgResponse{}),
+		spec.NewMessage("LpMsgOrder", schema.LpMsgEventOrder, schema.LpMsgOrder{}),
+		spec.NewMessage("LpMsgAddResponse", schema.LpMsgEventAddResponse, schema.LpMsgAddResponse{}),
+		spec.NewMessage("LpMsgRemoveResponse", schema.LpMsgEventRemoveResponse, schema.LpMsgRemoveResponse{}),
+		spec.NewMessage("OrderMsgStatus", schema.OrderMsgEventStatus, schema.OrderMsgStatus{}),
+		spec.NewMessage("WsErr", "error", WsErr{}),
+	)
+
+	return &spec.Spec{
+		OpenAPI:  o,
+		AsyncAPI: a,
+	}
+}
+
+func (r *Router) getSpec(c *gin.Context) {
+	version := c.DefaultQuery(schema.ProtocolVersionParam, schema.ProtocolVersionLatest)
+	if !schema.IsProtocolVersionSupported(version) {
+		c.JSON(http.StatusBadRequest, WsErrUnsupportedProtocolVersion)
+		return
+	}
+	c.JSON(http.StatusOK, Spec(version))
+}
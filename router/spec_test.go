@@ -0,0 +1,124 @@
+package router
+
+import (
+	"encoding/json"
+	"testing"
+
+	everSchema "github.com/everVision/everpay-kits/schema"
+	coreSchema "github.com/permadao/permaswap/core/schema"
+	"github.com/permadao/permaswap/router/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecUserMsgs(t *testing.T) {
+	a := Spec(schema.ProtocolVersionLatest).AsyncAPI
+	pub := a.Channels["/wsuser"].Publish
+	sub := a.Channels["/wsuser"].Subscribe
+
+	// msg sent by third-party clients
+	assert.NoError(t, pub.Validate([]byte(`{"event":"query","address":"123","tokenIn":"ethereum-eth-0x0000000000000000000000000000000000000000","tokenOut":"ethereum-usdc-0xb7a4f3e9097c08da09517b5ab877f7a917224ede", "amountIn":"4000000000000000"}`)))
+	assert.Error(t, pub.Validate([]byte(`{"event":"query","amountIn":4000000000000000}`)))
+
+	bundle := everSchema.Bundle{
+		Items: []everSchema.BundleItem{
+			{Tag: "ethereum-eth-0x0000000000000000000000000000000000000000", ChainID: "5", From: "0x1", To: "0x2", Amount: "1"},
+		},
+		Expiration: 1,
+		Salt:       "salt",
+		Version:    everSchema.BundleTxVersionV1,
+	}
+	paths := []coreSchema.Path{{LpID: "0x1", From: "0x1", To: "0x2", TokenTag: "ethereum-eth-0x0000000000000000000000000000000000000000", Amount: "1"}}
+
+	assert.Error(t, pub.Validate([]byte(`{"event":"query","address":"123"}`)))
+	assert.NoError(t, pub.Validate(schema.UserMsgQuery{Address: "0x1", TokenIn: "x", TokenOut: "y", AmountIn: "1"}.Marshal()))
+	assert.NoError(t, pub.Validate(schema.UserMsgSubmit{
+		Address: "0x1",
+		Bundle:  everSchema.BundleWithSigs{Bundle: bundle, Sigs: map[string]string{"0x1": "sig"}},
+		Paths:   paths,
+	}.Marshal()))
+
+	assert.NoError(t, sub.Validate(schema.UserMsgOrder{Bundle: bundle, Paths: paths}.Marshal()))
+	assert.NoError(t, sub.Validate(schema.OrderMsgStatus{OrderHash: "0x3", EverHash: "0x4", Status: schema.OrderStatusSuccess}.Marshal()))
+	assert.NoError(t, sub.Validate([]byte(WsErrInvalidMsg.Error())))
+}
+
+func TestSpecLpMsgs(t *testing.T) {
+	a := Spec(schema.ProtocolVersionV1).AsyncAPI
+	pub := a.Channels["/wslp"].Publish
+	sub := a.Channels["/wslp"].Subscribe
+
+	// msg sent by third-party lp clients
+	assert.NoError(t, pub.Validate([]byte(`{
+		"event": "add",
+		"tokenX": "ethereum-eth-0x0000000000000000000000000000000000000000",
+		"tokenY": "ethereum-usdt-0xd85476c906b5301e8e9eb58d174a6f96b9dfc5ee",
+		"feeRatio": "0.003",
+		"lowSqrtPrice": "0.000044721359549995793928183473374626",
+		"currentSqrtPrice": "0.000054792195750516611345696978280080",
+		"highSqrtPrice": "0.000063245553203367586639977870888654",
+		"liquidity": "50000000000000000",
+		"priceDirection": "both"
+	}`)))
+	assert.NoError(t, pub.Validate([]byte(`{"event":"register","address":"0x1","sig":"0x2","lpClientName":"lp-golang","lpClientVerison":"v0.4.0"}`)))
+
+	// required fields
+	assert.Error(t, pub.Validate([]byte(`{"event":"register"}`)))
+	assert.Error(t, pub.Validate([]byte(`{"event":"add","tokenX":"ethereum-eth-0x0000000000000000000000000000000000000000"}`)))
+	assert.Error(t, sub.Validate([]byte(`{"event":"status","orderHash":"0x1"}`)))
+
+	bundle := everSchema.BundleWithSigs{
+		Bundle: everSchema.Bundle{
+			Items:      []everSchema.BundleItem{{Tag: "ethereum-eth-0x0000000000000000000000000000000000000000", ChainID: "5", From: "0x1", To: "0x2", Amount: "1"}},
+			Expiration: 1,
+			Salt:       "salt",
+			Version:    everSchema.BundleTxVersionV1,
+		},
+		Sigs: map[string]string{"0x1": "sig"},
+	}
+	addMsg := schema.LpMsgAdd{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"tokenX":"x","tokenY":"y","feeRatio":"0.003","lowSqrtPrice":"1","currentSqrtPrice":"2","highSqrtPrice":"3","liquidity":"1","priceDirection":"both"}`), &addMsg))
+	assert.NoError(t, pub.Validate(addMsg.Marshal()))
+	assert.NoError(t, pub.Validate(schema.LpMsgRemove{
+		TokenX: "x", TokenY: "y", FeeRatio: addMsg.FeeRatio, LowSqrtPrice: addMsg.LowSqrtPrice, HighSqrtPrice: addMsg.HighSqrtPrice, PriceDirection: "both",
+	}.Marshal()))
+	assert.NoError(t, pub.Validate(schema.LpMsgRegister{Address: "0x1", Sig: "0x2", LpClientName: "lp-golang", LpClientVersion: "v0.4.0"}.Marshal()))
+	assert.NoError(t, pub.Validate(schema.LpMsgSign{Address: "0x1", Bundle: bundle}.Marshal()))
+	assert.NoError(t, pub.Validate(schema.LpMsgReject{Address: "0x1", OrderHash: "0x3"}.Marshal()))
+
+	assert.NoError(t, sub.Validate(schema.LpMsgSalt{Salt: "salt"}.Marshal()))
+	assert.NoError(t, sub.Validate(schema.LpMsgOk.Marshal()))
+	assert.NoError(t, sub.Validate(schema.LpMsgOrder{
+		UserAddr: "0x1",
+		Bundle:   bundle.Bundle,
+		Paths:    []coreSchema.Path{{LpID: "0x1", From: "0x1", To: "0x2", TokenTag: "ethereum-eth-0x0000000000000000000000000000000000000000", Amount: "1"}},
+	}.Marshal()))
+	assert.NoError(t, sub.Validate(schema.LpMsgAddResponse{LpID: "0x1", Msg: "ok"}.Marshal()))
+	assert.NoError(t, sub.Validate(schema.LpMsgRemoveResponse{LpID: "0x1", Msg: "failed", Error: "err_not_found"}.Marshal()))
+	assert.NoError(t, sub.Validate(schema.OrderMsgStatus{OrderHash: "0x3", EverHash: "0x4", Status: schema.OrderStatusSuccess}.Marshal()))
+	assert.NoError(t, sub.Validate([]byte(WsErrNoAuthorization.Error())))
+
+	// protocol v2
+	pubV2 := Spec(schema.ProtocolVersionV2).AsyncAPI.Channels["/wslp"].Publish
+	assert.NoError(t, pubV2.Validate([]byte(`{"event":"register","address":"0x1","sig":"0x2","lpClientName":"lp-golang","lpClientVersion":"v0.5.5"}`)))
+	assert.NoError(t, pubV2.Validate(schema.LpMsgRegisterV2{Address: "0x1", Sig: "0x2", LpClientName: "lp-golang", LpClientVersion: "v0.5.5"}.Marshal()))
+	assert.Error(t, pubV2.Validate(schema.LpMsgRegister{Address: "0x1", Sig: "0x2", LpClientName: "lp-golang", LpClientVersion: "v0.4.0"}.Marshal()))
+	assert.NoError(t, sub.Validate(schema.LpMsgSalt{Salt: "salt", ProtocolVersions: schema.ProtocolVersionsSupported}.Marshal()))
+}
+
+func TestSpecAPI(t *testing.T) {
+	o := Spec(schema.ProtocolVersionLatest).OpenAPI
+	res := o.Operation("get", "/quote").Responses["200"].Content["application/json"].Schema
+
+	by, _ := json.Marshal(schema.QuoteRes{
+		Lps:   []schema.QuoteLp{{LpID: "0x1"}},
+		Paths: []coreSchema.Path{{LpID: "0x1"}},
+	})
+	assert.NoError(t, res.Validate(by))
+
+	req := o.Operation("post", "/quotes").RequestBody.Content["application/json"].Schema
+	assert.NoError(t, req.Validate([]byte(`{"quotes":[{"tokenIn":"a","tokenOut":"b","amountIn":"1"}]}`)))
+	assert.Error(t, req.Validate([]byte(`{"quotes":[{"tokenIn":"a","tokenOut":"b"}]}`)))
+
+	_, err := json.Marshal(Spec(schema.ProtocolVersionLatest))
+	assert.NoError(t, err)
+}
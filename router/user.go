@@ -1,7 +1,6 @@
 package router
 
 import (
-	"encoding/json"
 	"math/big"
 	"time"
 
@@ -20,8 +19,8 @@ func (r *Router) runUserMsgUnmarshal() {
 		src := <-r.userHub.Subscribe()
 
 		msg := &schema.UserMsg{}
-		if err := json.Unmarshal(src.Data, msg); err != nil {
-			r.userHub.Publish(src.ID, []byte(WsErrInvalidMsg.Error()))
+		if err := src.Unmarshal(msg); err != nil {
+			r.userHub.Publish(src.ID, WsErrInvalidMsg)
 			log.Error("invalid message from user", "err", err, "msg", string(src.Data))
 			continue
 		}
@@ -29,8 +28,8 @@ func (r *Router) runUserMsgUnmarshal() {
 		switch msg.Event {
 		case schema.UserMsgEventQuery:
 			qryMsg := &schema.UserMsgQuery{}
-			if err := json.Unmarshal(src.Data, qryMsg); err != nil {
-				r.userHub.Publish(src.ID, []byte(WsErrInvalidMsg.Error()))
+			if err := src.Unmarshal(qryMsg); err != nil {
+				r.userHub.Publish(src.ID, WsErrInvalidMsg)
 				log.Error("invalid message from user", "err", err, "msg", string(src.Data))
 				continue
 			}
@@ -41,8 +40,8 @@ func (r *Router) runUserMsgUnmarshal() {
 
 		case schema.UserMsgEventSubmit:
 			submitMsg := &schema.UserMsgSubmit{}
-			if err := json.Unmarshal(src.Data, submitMsg); err != nil {
-				r.userHub.Publish(src.ID, []byte(WsErrInvalidMsg.Error()))
+			if err := src.Unmarshal(submitMsg); err != nil {
+				r.userHub.Publish(src.ID, WsErrInvalidMsg)
 				log.Error("invalid message from user", "err", err, "msg", string(src.Data))
 				continue
 			}
@@ -51,7 +50,7 @@ func (r *Router) runUserMsgUnmarshal() {
 			r.userSubmit <- submitMsg
 
 		default:
-			r.userHub.Publish(src.ID, []byte(WsErrInvalidMsg.Error()))
+			r.userHub.Publish(src.ID, WsErrInvalidMsg)
 			log.Error("invalid message action", "msg", string(src.Data))
 		}
 
@@ -61,7 +60,7 @@ func (r *Router) runUserMsgUnmarshal() {
 func (r *Router) userQueryProc(msg *schema.UserMsgQuery) {
 	orderMsg, err := r.queryOrder(msg)
 	if err != nil {
-		r.userHub.Publish(msg.ID, []byte(NewWsErr(err.Error()).Error()))
+		r.userHub.Publish(msg.ID, NewWsErr(err.Error()))
 		return
 	}
 
@@ -76,7 +75,7 @@ func (r *Router) userQueryProc(msg *schema.UserMsgQuery) {
 		}
 	}
 
-	r.userHub.Publish(msg.ID, orderMsg.Marshal())
+	r.userHub.Publish(msg.ID, orderMsg.WithEvent())
 }
 
 func (r *Router) userSubmitProc(msg *schema.UserMsgSubmit) {
@@ -86,7 +85,7 @@ func (r *Router) userSubmitProc(msg *schema.UserMsgSubmit) {
 	// check black list
 	if r.penalty.IsBlackListed(msg.Address) {
 		log.Error("user is blacklisted", "user", msg.Address)
-		r.userHub.Publish(msg.ID, []byte(WsErrBlackListed.Error()))
+		r.userHub.Publish(msg.ID, WsErrBlackListed)
 		return
 	}
 
@@ -94,7 +93,7 @@ func (r *Router) userSubmitProc(msg *schema.UserMsgSubmit) {
 	tokenIn := msg.Paths[0].TokenTag
 	tokenOut := msg.Paths[len(msg.Paths)-2].TokenTag
 	if tokenIn != msg.TokenIn || tokenOut != msg.TokenOut {
-		r.userHub.Publish(msg.ID, []byte(WsErrInvalidToken.Error()))
+		r.userHub.Publish(msg.ID, WsErrInvalidToken)
 		return
 	}
 
@@ -102,19 +101,19 @@ func (r *Router) userSubmitProc(msg *schema.UserMsgSubmit) {
 	// verify bundle tx
 	userAddr, _, err := VerifyBundleByAddr(bundle, msg.Address, r.chainID)
 	if err != nil {
-		r.userHub.Publish(msg.ID, []byte(err.Error()))
+		r.userHub.Publish(msg.ID, toWsErr(err))
 		return
 	}
 
 	//verify paths
 	if err := VerifyBundleAndPaths(bundle.Bundle, msg.Paths, r.tokens); err != nil {
-		r.userHub.Publish(msg.ID, []byte(err.Error()))
+		r.userHub.Publish(msg.ID, toWsErr(err))
 		return
 	}
 
 	// verify price in core
 	if err = r.core.Verify(userAddr, msg.Paths); err != nil {
-		r.userHub.Publish(msg.ID, []byte(NewWsErr(err.Error()).Error()))
+		r.userHub.Publish(msg.ID, NewWsErr(err.Error()))
 		return
 	}
 
@@ -123,7 +122,7 @@ func (r *Router) userSubmitProc(msg *schema.UserMsgSubmit) {
 	for _, item := range bundle.Items {
 		_, lpAcc, err := utils.IDCheck(item.From)
 		if err != nil {
-			r.userHub.Publish(msg.ID, []byte(WsErrInvalidAddress.Error()))
+			r.userHub.Publish(msg.ID, WsErrInvalidAddress)
 			return
 		}
 
@@ -133,7 +132,7 @@ func (r *Router) userSubmitProc(msg *schema.UserMsgSubmit) {
 
 		lpID, ok := r.lpAddrToID[lpAcc]
 		if !ok {
-			r.userHub.Publish(msg.ID, []byte(WsErrNotFoundLp.Error()))
+			r.userHub.Publish(msg.ID, WsErrNotFoundLp)
 			return
 		}
 
@@ -210,7 +209,7 @@ func (r *Router) calPriceImpact(msg *schema.UserMsgQuery, price *big.Float) (pri
 		AmountIn: amountIn,
 	}
 
-	paths, err := r.core.Query(umq)
+	paths, err := r.core.Quote(umq)
 	if err != nil || len(paths) == 0 {
 		log.Error("Failed to get path", "tokenIn", msg.TokenIn, "tokenOut",
 			msg.TokenOut, "err", err, "len(paths)", len(paths))
@@ -284,7 +283,7 @@ func (r *Router) pushNewOrder(tokenTags ...string) {
 				continue
 			}
 
-			r.userHub.Publish(msg.ID, orderMsg.Marshal())
+			r.userHub.Publish(msg.ID, orderMsg.WithEvent())
 		}
 	}
 }
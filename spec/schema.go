@@ -0,0 +1,178 @@
+package spec
+
+import (
+	"encoding"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	bigIntType          = reflect.TypeOf(big.Int{})
+	timeType            = reflect.TypeOf(time.Time{})
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// Types is json schema type, marshal to string if only one type
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+func (t *Types) UnmarshalJSON(by []byte) error {
+	s := ""
+	if err := json.Unmarshal(by, &s); err == nil {
+		*t = Types{s}
+		return nil
+	}
+	ts := []string{}
+	if err := json.Unmarshal(by, &ts); err != nil {
+		return err
+	}
+	*t = Types(ts)
+	return nil
+}
+
+// Schema is a subset of json schema (draft 2020-12), it is also used by openapi 3.1 and asyncapi
+type Schema struct {
+	Type                 Types              `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Const                interface{}        `json:"const,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // bool or *Schema
+}
+
+// Reflect generate json schema from go value by encoding/json rules.
+// fields without omitempty are required, unless they are tagged spec:"optional" which clients may omit.
+func Reflect(v interface{}) *Schema {
+	s := reflectType(reflect.TypeOf(v), map[reflect.Type]bool{})
+	if s == nil {
+		return &Schema{}
+	}
+	return s
+}
+
+func reflectType(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	s := reflectBaseType(t, visiting)
+	if s == nil || len(s.Type) == 0 {
+		return s
+	}
+	if nullable || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		s.Type = append(s.Type, "null")
+	}
+	return s
+}
+
+func reflectBaseType(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	switch {
+	case t == bigIntType:
+		return &Schema{Type: Types{"integer"}}
+	case t == timeType:
+		return &Schema{Type: Types{"string"}, Format: "date-time"}
+	case implements(t, jsonMarshalerType) || implements(t, jsonUnmarshalerType):
+		// custom json format, can not be described
+		return &Schema{}
+	case implements(t, textMarshalerType):
+		return &Schema{Type: Types{"string"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: Types{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: Types{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Types{"number"}}
+	case reflect.String:
+		return &Schema{Type: Types{"string"}}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Types{"string"}, Format: "byte"}
+		}
+		return &Schema{Type: Types{"array"}, Items: reflectType(t.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: Types{"object"}, AdditionalProperties: reflectType(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			// recursive type
+			return &Schema{}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		s := &Schema{
+			Type:                 Types{"object"},
+			Properties:           map[string]*Schema{},
+			AdditionalProperties: false,
+		}
+		reflectFields(t, s, visiting)
+		return s
+	case reflect.Interface:
+		return &Schema{}
+	default:
+		// func, chan ... are not supported by json
+		return nil
+	}
+}
+
+func reflectFields(t reflect.Type, s *Schema, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			// embedded struct, fields are promoted
+			reflectFields(ft, s, visiting)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fs := reflectType(f.Type, visiting)
+		if fs == nil {
+			continue
+		}
+		s.Properties[name] = fs
+		// fields always marshaled by encoding/json are required
+		if !strings.Contains(tag, ",omitempty") && f.Tag.Get("spec") != "optional" {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func implements(t reflect.Type, i reflect.Type) bool {
+	return t.Implements(i) || reflect.PtrTo(t).Implements(i)
+}
@@ -0,0 +1,77 @@
+package spec
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	apd "github.com/cockroachdb/apd/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+type testBase struct {
+	Salt string `json:"salt"`
+}
+
+type testMsg struct {
+	testBase
+	Event    string            `json:"event"`
+	Amount   *big.Int          `json:"amount"`
+	Fee      *apd.Decimal      `json:"fee"`
+	Items    []string          `json:"items"`
+	Sigs     map[string]string `json:"sigs"`
+	Internal string            `json:"-"`
+	Count    int64             `json:"count,omitempty"`
+	ID       string            `json:"id" spec:"optional"`
+	Func     func()            `json:"-"`
+}
+
+func TestReflect(t *testing.T) {
+	s := Reflect(testMsg{})
+	assert.Equal(t, Types{"object"}, s.Type)
+	assert.Equal(t, Types{"string"}, s.Properties["salt"].Type)
+	assert.Equal(t, Types{"integer", "null"}, s.Properties["amount"].Type)
+	assert.Equal(t, Types{"string", "null"}, s.Properties["fee"].Type)
+	assert.Equal(t, Types{"array", "null"}, s.Properties["items"].Type)
+	assert.Equal(t, Types{"object", "null"}, s.Properties["sigs"].Type)
+	assert.Equal(t, Types{"integer"}, s.Properties["count"].Type)
+	_, ok := s.Properties["Internal"]
+	assert.False(t, ok)
+
+	by, err := json.Marshal(s)
+	assert.NoError(t, err)
+	s2 := &Schema{}
+	assert.NoError(t, json.Unmarshal(by, s2))
+	assert.Equal(t, s.Properties["amount"].Type, s2.Properties["amount"].Type)
+}
+
+func TestValidate(t *testing.T) {
+	s := NewMessage("testMsg", "test", testMsg{}).Payload
+
+	fee, _, _ := apd.NewFromString("0.003")
+	by, _ := json.Marshal(testMsg{Event: "test", Amount: big.NewInt(100), Fee: fee, Items: []string{"a"}})
+	assert.NoError(t, s.Validate(by))
+
+	assert.NoError(t, s.Validate([]byte(`{"event":"test","salt":"","amount":null,"fee":null,"items":null,"sigs":null}`)))
+	// fields without omitempty are required, except fields tagged optional
+	assert.ElementsMatch(t, []string{"salt", "event", "amount", "fee", "items", "sigs"}, s.Required)
+	assert.Error(t, s.Validate([]byte(`{"event":"test","amount":null,"fee":null,"items":null,"sigs":null}`)))
+	assert.Error(t, s.Validate([]byte(`{"event":"test"}`)))
+	assert.Error(t, s.Validate([]byte(`{"event":"other"}`)))
+	assert.Error(t, s.Validate([]byte(`{"amount":1}`)))
+	assert.Error(t, s.Validate([]byte(`{"event":"test","amount":"1"}`)))
+	assert.Error(t, s.Validate([]byte(`{"event":"test","amount":1.5}`)))
+	assert.Error(t, s.Validate([]byte(`{"event":"test","items":[1]}`)))
+	assert.Error(t, s.Validate([]byte(`{"event":"test","unknown":1}`)))
+}
+
+func TestChannelValidate(t *testing.T) {
+	a := NewAsyncAPI("test", "v1")
+	a.Add("/ws", true, NewMessage("testMsg", "test", testMsg{}))
+
+	op := a.Channels["/ws"].Publish
+	assert.NoError(t, op.Validate([]byte(`{"event":"test","salt":"123","amount":1,"fee":"0.003","items":[],"sigs":{}}`)))
+	assert.Error(t, op.Validate([]byte(`{"event":"test","salt":"123"}`)))
+	assert.Equal(t, ErrNoMsg, op.Validate([]byte(`{"event":"query"}`)))
+	assert.Equal(t, ErrNoEvent, op.Validate([]byte(`{"salt":"123"}`)))
+}
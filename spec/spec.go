@@ -0,0 +1,206 @@
+package spec
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+const (
+	OpenAPIVersion  = "3.1.0"
+	AsyncAPIVersion = "2.6.0"
+)
+
+var (
+	ErrNoEvent = errors.New("err_no_event")
+	ErrNoMsg   = errors.New("err_no_msg")
+)
+
+// Spec is the protocol specification of a node, served at /spec
+type Spec struct {
+	OpenAPI  *OpenAPI  `json:"openapi"`
+	AsyncAPI *AsyncAPI `json:"asyncapi,omitempty"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenAPI describe http api
+type OpenAPI struct {
+	OpenAPI string                           `json:"openapi"`
+	Info    Info                             `json:"info"`
+	Paths   map[string]map[string]*Operation `json:"paths"` // path -> method -> operation
+}
+
+type Operation struct {
+	Summary     string               `json:"summary,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *Body                `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // path or query
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type Body struct {
+	Content map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+func NewOpenAPI(title, version string) *OpenAPI {
+	return &OpenAPI{
+		OpenAPI: OpenAPIVersion,
+		Info:    Info{Title: title, Version: version},
+		Paths:   make(map[string]map[string]*Operation),
+	}
+}
+
+// Add an operation. path is in openapi format, e.g. /pool/{poolid}. req and res are go values, req can be nil.
+func (o *OpenAPI) Add(method, path, summary string, params []*Parameter, req, res interface{}) {
+	op := &Operation{
+		Summary:    summary,
+		Parameters: params,
+		Responses: map[string]*Response{
+			"200": {
+				Description: "ok",
+				Content:     map[string]*MediaType{"application/json": {Schema: Reflect(res)}},
+			},
+		},
+	}
+	if req != nil {
+		op.RequestBody = &Body{
+			Content: map[string]*MediaType{"application/json": {Schema: Reflect(req)}},
+		}
+	}
+
+	if _, ok := o.Paths[path]; !ok {
+		o.Paths[path] = make(map[string]*Operation)
+	}
+	o.Paths[path][method] = op
+}
+
+// Operation return the operation by path and method, nil if not found
+func (o *OpenAPI) Operation(method, path string) *Operation {
+	return o.Paths[path][method]
+}
+
+func PathParam(name string) *Parameter {
+	return &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: Types{"string"}}}
+}
+
+func QueryParam(name string, required bool) *Parameter {
+	return &Parameter{Name: name, In: "query", Required: required, Schema: &Schema{Type: Types{"string"}}}
+}
+
+// AsyncAPI describe websocket api
+type AsyncAPI struct {
+	AsyncAPI string              `json:"asyncapi"`
+	Info     Info                `json:"info"`
+	Channels map[string]*Channel `json:"channels"`
+}
+
+type Channel struct {
+	// publish: msg from client to node; subscribe: msg from node to client
+	Publish   *ChannelOperation `json:"publish,omitempty"`
+	Subscribe *ChannelOperation `json:"subscribe,omitempty"`
+}
+
+type ChannelOperation struct {
+	Message struct {
+		OneOf []*Message `json:"oneOf"`
+	} `json:"message"`
+}
+
+type Message struct {
+	Name    string  `json:"name"`
+	Event   string  `json:"-"`
+	Payload *Schema `json:"payload"`
+}
+
+// NewMessage generate message schema, the event field of message must be the event
+func NewMessage(name, event string, v interface{}) *Message {
+	payload := Reflect(v)
+	if payload.Properties == nil {
+		payload.Properties = make(map[string]*Schema)
+	}
+	payload.Properties["event"] = &Schema{Type: Types{"string"}, Const: event}
+	required := false
+	for _, name := range payload.Required {
+		if name == "event" {
+			required = true
+		}
+	}
+	if !required {
+		payload.Required = append(payload.Required, "event")
+	}
+
+	return &Message{
+		Name:    name,
+		Event:   event,
+		Payload: payload,
+	}
+}
+
+func NewAsyncAPI(title, version string) *AsyncAPI {
+	return &AsyncAPI{
+		AsyncAPI: AsyncAPIVersion,
+		Info:     Info{Title: title, Version: version},
+		Channels: make(map[string]*Channel),
+	}
+}
+
+// Add messages to channel. isPublish is true for msgs from client.
+func (a *AsyncAPI) Add(channel string, isPublish bool, msgs ...*Message) {
+	ch, ok := a.Channels[channel]
+	if !ok {
+		ch = &Channel{}
+		a.Channels[channel] = ch
+	}
+
+	op := ch.Subscribe
+	if isPublish {
+		op = ch.Publish
+	}
+	if op == nil {
+		op = &ChannelOperation{}
+		if isPublish {
+			ch.Publish = op
+		} else {
+			ch.Subscribe = op
+		}
+	}
+	op.Message.OneOf = append(op.Message.OneOf, msgs...)
+}
+
+// Validate json msg by the event field
+func (op *ChannelOperation) Validate(data []byte) error {
+	msg := struct {
+		Event string `json:"event"`
+	}{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	if msg.Event == "" {
+		return ErrNoEvent
+	}
+
+	for _, m := range op.Message.OneOf {
+		if m.Event == msg.Event {
+			return m.Payload.Validate(data)
+		}
+	}
+	return ErrNoMsg
+}
@@ -0,0 +1,110 @@
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Validate check json data by schema
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	return s.validate(v, "$")
+}
+
+func (s *Schema) validate(v interface{}, path string) error {
+	if s == nil {
+		return nil
+	}
+
+	if s.Const != nil && !reflect.DeepEqual(s.Const, v) {
+		return fmt.Errorf("%s: expect %v, got %v", path, s.Const, v)
+	}
+
+	if len(s.Type) == 0 {
+		return nil
+	}
+	typ := jsonType(v)
+	if !s.hasType(typ) {
+		return fmt.Errorf("%s: expect type %s, got %s", path, strings.Join(s.Type, "|"), typ)
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				return fmt.Errorf("%s: missing required property %s", path, name)
+			}
+		}
+		for k, item := range val {
+			subPath := path + "." + k
+			if ps, ok := s.Properties[k]; ok {
+				if err := ps.validate(item, subPath); err != nil {
+					return err
+				}
+				continue
+			}
+
+			switch ap := s.AdditionalProperties.(type) {
+			case bool:
+				if !ap {
+					return fmt.Errorf("%s: unknown property", subPath)
+				}
+			case *Schema:
+				if err := ap.validate(item, subPath); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) hasType(typ string) bool {
+	for _, t := range s.Type {
+		if t == typ {
+			return true
+		}
+		// integer is a number
+		if t == "number" && typ == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := val.Int64(); err == nil || !strings.ContainsAny(val.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,57 @@
+package wshub
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// msgs are typed structs encoded directly in encoding of session,
+// cbor keys are taken from cbor tags of struct fields, or json tags if no cbor tag
+const (
+	EncodingJSON = "json"
+	EncodingCBOR = "cbor"
+)
+
+var EncodingsSupported = []string{EncodingJSON, EncodingCBOR}
+
+var ErrUnsupportedEncoding = errors.New("err_unsupported_encoding")
+
+var (
+	cborEncMode, _ = cbor.EncOptions{Sort: cbor.SortCanonical}.EncMode()
+	cborDecMode, _ = cbor.DecOptions{}.DecMode()
+)
+
+func IsEncodingSupported(encoding string) bool {
+	for _, e := range EncodingsSupported {
+		if e == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// Marshal encode typed msg in encoding
+func Marshal(encoding string, v interface{}) ([]byte, error) {
+	switch encoding {
+	case EncodingJSON, "":
+		return json.Marshal(v)
+	case EncodingCBOR:
+		return cborEncMode.Marshal(v)
+	default:
+		return nil, ErrUnsupportedEncoding
+	}
+}
+
+// Unmarshal decode msg in encoding to typed v
+func Unmarshal(encoding string, data []byte, v interface{}) error {
+	switch encoding {
+	case EncodingJSON, "":
+		return json.Unmarshal(data, v)
+	case EncodingCBOR:
+		return cborDecMode.Unmarshal(data, v)
+	default:
+		return ErrUnsupportedEncoding
+	}
+}
@@ -0,0 +1,60 @@
+package wshub
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPath struct {
+	LpID   string `json:"lpID"`
+	Amount string `json:"amount"`
+}
+
+type testMsg struct {
+	Event    string     `json:"event"`
+	UserAddr string     `json:"userAddr" cbor:"u"`
+	Amount   *big.Int   `json:"amount"`
+	Nonce    int64      `json:"nonce"`
+	OK       bool       `json:"ok"`
+	Paths    []testPath `json:"paths"`
+	Sigs     []string   `json:"sigs"`
+}
+
+func TestCodec(t *testing.T) {
+	amount, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	msg := testMsg{
+		Event:    "order",
+		UserAddr: "0x1",
+		Amount:   amount,
+		Nonce:    1678934011000,
+		OK:       true,
+		Paths:    []testPath{{LpID: "0x2", Amount: "1"}},
+	}
+
+	js, err := Marshal(EncodingJSON, msg)
+	assert.NoError(t, err)
+	jsMsg, _ := json.Marshal(msg)
+	assert.Equal(t, jsMsg, js)
+
+	by, err := Marshal(EncodingCBOR, msg)
+	assert.NoError(t, err)
+	assert.Less(t, len(by), len(js))
+
+	res := testMsg{}
+	assert.NoError(t, Unmarshal(EncodingCBOR, by, &res))
+	assert.Equal(t, msg, res)
+
+	// cbor tag is used as key, json tag if no cbor tag
+	m := map[string]interface{}{}
+	assert.NoError(t, Unmarshal(EncodingCBOR, by, &m))
+	assert.Equal(t, "0x1", m["u"])
+	assert.Equal(t, "order", m["event"])
+
+	_, err = Marshal("protobuf", msg)
+	assert.Equal(t, ErrUnsupportedEncoding, err)
+	assert.Equal(t, ErrUnsupportedEncoding, Unmarshal("protobuf", by, &res))
+	assert.Error(t, Unmarshal(EncodingCBOR, []byte("invalid"), &res))
+}
@@ -25,13 +25,33 @@ const (
 
 var log = logger.New("wshub")
 
+// Message is msg from session
 type Message struct {
-	ID   string // session id
-	Data []byte
+	ID       string // session id
+	Data     []byte
+	Encoding string // encoding of data, text msg is always json
+	Version  string // protocol version of session
+}
+
+// Unmarshal decode data of msg to typed v
+func (m Message) Unmarshal(v interface{}) error {
+	return Unmarshal(m.Encoding, m.Data, v)
+}
+
+// pubMsg is typed msg to session, encoded by session
+type pubMsg struct {
+	id  string
+	msg interface{}
+}
+
+// SessionOption is negotiated by client when connecting
+type SessionOption struct {
+	Version  string // protocol version
+	Encoding string // msg encoding, json by default
 }
 
 type Hub struct {
-	pub chan Message
+	pub chan pubMsg
 	sub chan Message
 
 	register   chan *Session
@@ -41,7 +61,7 @@ type Hub struct {
 
 func New() *Hub {
 	return &Hub{
-		pub: make(chan Message),
+		pub: make(chan pubMsg),
 		sub: make(chan Message),
 
 		register:   make(chan *Session),
@@ -55,8 +75,8 @@ func (h *Hub) Run(registerFunc, unregisterFunc func(id string)) {
 		for {
 			select {
 			case msg := <-h.pub:
-				if ses, ok := h.sessions[msg.ID]; ok {
-					ses.send <- msg.Data
+				if ses, ok := h.sessions[msg.id]; ok {
+					ses.send <- msg.msg
 				}
 			case ses := <-h.register:
 				h.sessions[ses.id] = ses
@@ -77,7 +97,8 @@ func (h *Hub) Run(registerFunc, unregisterFunc func(id string)) {
 	}(unregisterFunc)
 }
 
-func (h *Hub) RegisterSession(w http.ResponseWriter, r *http.Request) {
+// RegisterSession upgrade http connection to websocket session
+func (h *Hub) RegisterSession(w http.ResponseWriter, r *http.Request, opt SessionOption) {
 	upgrader := websocket.Upgrader{
 		ReadBufferSize:  wsMaxMsgSize,
 		WriteBufferSize: wsMaxMsgSize,
@@ -90,7 +111,7 @@ func (h *Hub) RegisterSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s := newSession(uuid.NewString(), h, conn)
+	s := newSession(uuid.NewString(), h, conn, opt)
 	s.run()
 
 	h.register <- s
@@ -100,8 +121,9 @@ func (h *Hub) Subscribe() <-chan Message {
 	return h.sub
 }
 
-func (h *Hub) Publish(id string, data []byte) {
-	h.pub <- Message{id, data}
+// Publish send typed msg to session, msg is encoded in encoding of session
+func (h *Hub) Publish(id string, msg interface{}) {
+	h.pub <- pubMsg{id: id, msg: msg}
 }
 
 func (h *Hub) CloseSession(sessionID string) {
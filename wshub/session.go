@@ -10,15 +10,16 @@ type Session struct {
 	id   string
 	hub  *Hub
 	conn *websocket.Conn
-	send chan []byte
+	send chan interface{}
+	opt  SessionOption
 }
 
-func newSession(id string, hub *Hub, conn *websocket.Conn) *Session {
+func newSession(id string, hub *Hub, conn *websocket.Conn, opt SessionOption) *Session {
 	conn.SetReadLimit(wsMaxMsgSize)
 	conn.SetReadDeadline(time.Now().Add(wsPongWait))
 	conn.SetPongHandler(func(string) error { conn.SetReadDeadline(time.Now().Add(wsPongWait)); return nil })
 
-	return &Session{id: id, hub: hub, conn: conn, send: make(chan []byte, wsMaxMsgSize)}
+	return &Session{id: id, hub: hub, conn: conn, send: make(chan interface{}, wsMaxMsgSize), opt: opt}
 }
 
 func (s *Session) run() {
@@ -37,12 +38,18 @@ func (s *Session) read() {
 	}()
 
 	for {
-		_, msg, err := s.conn.ReadMessage()
+		msgType, msg, err := s.conn.ReadMessage()
 		if err != nil {
 			break
 		}
 
-		s.hub.sub <- Message{s.id, msg}
+		// text msg is always json
+		encoding := EncodingJSON
+		if msgType == websocket.BinaryMessage {
+			encoding = s.opt.Encoding
+		}
+
+		s.hub.sub <- Message{ID: s.id, Data: msg, Encoding: encoding, Version: s.opt.Version}
 	}
 }
 
@@ -54,14 +61,24 @@ func (s *Session) write() {
 
 	for {
 		select {
-		case message, ok := <-s.send:
+		case msg, ok := <-s.send:
 			s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
 			if !ok {
 				s.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
-			w, err := s.conn.NextWriter(websocket.TextMessage)
+			message, err := Marshal(s.opt.Encoding, msg)
+			if err != nil {
+				log.Error("can not encode msg", "encoding", s.opt.Encoding, "err", err)
+				continue
+			}
+			msgType := websocket.TextMessage
+			if s.opt.Encoding == EncodingCBOR {
+				msgType = websocket.BinaryMessage
+			}
+
+			w, err := s.conn.NextWriter(msgType)
 			if err != nil {
 				return
 			}